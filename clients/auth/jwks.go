@@ -0,0 +1,149 @@
+package clients
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+	"user-service/common/auth"
+)
+
+// jwksCache keeps the keys of a JWKS endpoint in memory. Keys are refreshed
+// once they are older than refreshInterval, or earlier when a token names a
+// key id that is not known yet, which is how a key rotation shows up. Such
+// early refreshes happen at most once per minRefreshInterval so a flood of
+// forged key ids cannot hammer user-service. The endpoint is fetched without
+// holding mu, lookups of known keys go on meanwhile and lookups of unknown
+// ones wait for the fetch.
+type jwksCache struct {
+	url                string
+	client             *http.Client
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	// fetching is closed when the fetch in flight ends, nil when there is none
+	fetching chan struct{}
+}
+
+func newJWKSCache(url string, client *http.Client, refreshInterval, minRefreshInterval time.Duration) *jwksCache {
+	return &jwksCache{
+		url:                url,
+		client:             client,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+		keys:               map[string]*rsa.PublicKey{},
+	}
+}
+
+func (j *jwksCache) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	key, ok := j.keys[kid]
+	if ok && time.Since(j.fetchedAt) <= j.refreshInterval {
+		j.mu.Unlock()
+		return key, nil
+	}
+
+	if j.fetching == nil && time.Since(j.attemptedAt) >= j.minRefreshInterval {
+		return j.refresh(ctx, kid)
+	}
+
+	fetching := j.fetching
+	j.mu.Unlock()
+
+	if !ok && fetching != nil {
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		j.mu.Lock()
+		key, ok = j.keys[kid]
+		j.mu.Unlock()
+	}
+
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+// refresh must be called with mu held, it releases mu while the keys are
+// fetched and returns without it.
+func (j *jwksCache) refresh(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	attemptedAt := time.Now()
+	done := make(chan struct{})
+	j.attemptedAt = attemptedAt
+	j.fetching = done
+	j.mu.Unlock()
+
+	keys, err := j.fetch(ctx)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.fetching = nil
+	close(done)
+
+	// a failed refresh keeps serving the keys we already have
+	if err == nil {
+		j.keys = keys
+		j.fetchedAt = attemptedAt
+	}
+
+	key, ok := j.keys[kid]
+	if !ok {
+		if err != nil {
+			return nil, err
+		}
+		return nil, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+func (j *jwksCache) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJWKSUnavailable, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %d", ErrJWKSUnavailable, res.StatusCode)
+	}
+
+	var jwks auth.JWKS
+	err = json.NewDecoder(res.Body).Decode(&jwks)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrJWKSUnavailable, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Alg != "" && jwk.Alg != auth.AlgorithmRSA {
+			continue
+		}
+
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,54 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"user-service/common/response"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Authenticate is the offline counterpart of middlewares.Authenticate. It
+// fills the request context with the same constants.UserLogin,
+// constants.SessionID and constants.Impersonator values, so handlers read
+// the user the same way in every service.
+func Authenticate(verifier IVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(constants.Authorization)
+		if token == "" {
+			abort(c, http.StatusUnauthorized, errConstant.ErrUnauthorized)
+			return
+		}
+
+		claims, err := verifier.Verify(c.Request.Context(), token)
+		if err != nil {
+			if errors.Is(err, ErrJWKSUnavailable) {
+				abort(c, http.StatusServiceUnavailable, errConstant.ErrInternalServerError)
+				return
+			}
+			abort(c, http.StatusUnauthorized, errConstant.ErrUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), constants.UserLogin, claims.User)
+		ctx = context.WithValue(ctx, constants.SessionID, claims.ID)
+		if claims.Impersonator != nil {
+			ctx = context.WithValue(ctx, constants.Impersonator, claims.Impersonator)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Set(constants.Token, token)
+
+		c.Next()
+	}
+}
+
+func abort(c *gin.Context, code int, err error) {
+	c.JSON(code, response.Response{
+		Status:  constants.Error,
+		Message: err.Error(),
+	})
+	c.Abort()
+}
@@ -0,0 +1,115 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"user-service/common/auth"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	defaultRefreshInterval    = 15 * time.Minute
+	defaultMinRefreshInterval = 30 * time.Second
+	defaultFetchTimeout       = 5 * time.Second
+)
+
+var (
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrUnknownKey      = errors.New("token signed with an unknown key")
+	ErrJWKSUnavailable = errors.New("jwks could not be fetched")
+)
+
+type Config struct {
+	// JWKSURL is the full address of the key set, for example
+	// http://user-service:8001/api/v1/.well-known/jwks.json.
+	JWKSURL string
+	// Issuer and Audience are checked when set.
+	Issuer   string
+	Audience string
+	// RefreshInterval is how long fetched keys are trusted. Zero means
+	// 15 minutes.
+	RefreshInterval time.Duration
+	// MinRefreshInterval limits refreshes caused by unknown key ids. Zero
+	// means 30 seconds.
+	MinRefreshInterval time.Duration
+	// Leeway tolerates clock skew on the time based claims.
+	Leeway     time.Duration
+	HTTPClient *http.Client
+}
+
+// Verifier checks user-service tokens without calling user-service for each
+// one. It only sees what is inside the token, so a session revoked before
+// the token expires still passes. Services that need revocation to apply at
+// once have to ask user-service instead.
+type Verifier struct {
+	jwks   *jwksCache
+	parser *jwt.Parser
+}
+
+type IVerifier interface {
+	Verify(ctx context.Context, token string) (*auth.Claims, error)
+}
+
+func NewVerifier(config Config) IVerifier {
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = defaultRefreshInterval
+	}
+	if config.MinRefreshInterval == 0 {
+		config.MinRefreshInterval = defaultMinRefreshInterval
+	}
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: defaultFetchTimeout}
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{auth.AlgorithmRSA}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(config.Leeway),
+	}
+	if config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(config.Issuer))
+	}
+	if config.Audience != "" {
+		options = append(options, jwt.WithAudience(config.Audience))
+	}
+
+	return &Verifier{
+		jwks:   newJWKSCache(config.JWKSURL, client, config.RefreshInterval, config.MinRefreshInterval),
+		parser: jwt.NewParser(options...),
+	}
+}
+
+// Verify accepts the raw token or an Authorization header value.
+func (v *Verifier) Verify(ctx context.Context, token string) (*auth.Claims, error) {
+	token = strings.TrimSpace(strings.TrimPrefix(token, "Bearer "))
+	if token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &auth.Claims{}
+	parsed, err := v.parser.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, ErrUnknownKey
+		}
+		return v.jwks.key(ctx, kid)
+	})
+	if err != nil {
+		if errors.Is(err, ErrJWKSUnavailable) {
+			return nil, ErrJWKSUnavailable
+		}
+		return nil, ErrInvalidToken
+	}
+
+	if !parsed.Valid || claims.User == nil {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,288 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"user-service/constants"
+	"user-service/domain/dto"
+)
+
+const (
+	defaultTimeout      = 10 * time.Second
+	defaultMaxRetries   = 2
+	defaultRetryBackoff = 100 * time.Millisecond
+	maxRetryBackoff     = 2 * time.Second
+)
+
+type Config struct {
+	// BaseURL is the address of user-service, without the /api/v1 prefix.
+	BaseURL      string
+	ServiceName  string
+	SignatureKey string
+	// Timeout bounds a single attempt. Zero means 10 seconds.
+	Timeout time.Duration
+	// MaxRetries is the number of extra attempts for requests that are safe
+	// to repeat. Zero means 2, a negative value disables retries.
+	MaxRetries int
+	// RetryBackoff is the first delay between attempts, doubled after each
+	// one. Zero means 100 milliseconds.
+	RetryBackoff time.Duration
+	HTTPClient   *http.Client
+}
+
+type UserClient struct {
+	config Config
+	client *http.Client
+}
+
+type IUserClient interface {
+	Login(context.Context, *dto.LoginRequest) (*dto.LoginResponse, error)
+	Register(context.Context, *dto.RegisterRequest) (*dto.UserResponse, error)
+	GetUserByUUID(ctx context.Context, token, uuid string) (*dto.UserResponse, error)
+	BatchLookup(context.Context, *dto.BatchUserRequest) (*dto.BatchUserResponse, error)
+	VerifyToken(ctx context.Context, token string) (*dto.UserResponse, error)
+}
+
+type envelope struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+	Token   *string         `json:"token,omitempty"`
+}
+
+func NewUserClient(config Config) IUserClient {
+	if config.Timeout == 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultMaxRetries
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = defaultRetryBackoff
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+
+	client := config.HTTPClient
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	return &UserClient{
+		config: config,
+		client: client,
+	}
+}
+
+func (u *UserClient) Login(ctx context.Context, req *dto.LoginRequest) (*dto.LoginResponse, error) {
+	var user dto.UserResponse
+
+	// a login attempt is never repeated, it counts against the account
+	resp, err := u.do(ctx, http.MethodPost, "/auth/login", "", req, false, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Token == nil {
+		return nil, ErrUnexpectedResponse
+	}
+
+	return &dto.LoginResponse{User: user, Token: *resp.Token}, nil
+}
+
+func (u *UserClient) Register(ctx context.Context, req *dto.RegisterRequest) (*dto.UserResponse, error) {
+	var user dto.UserResponse
+
+	_, err := u.do(ctx, http.MethodPost, "/auth/register", "", req, false, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (u *UserClient) GetUserByUUID(ctx context.Context, token, uuid string) (*dto.UserResponse, error) {
+	var user dto.UserResponse
+
+	_, err := u.do(ctx, http.MethodGet, "/auth/"+uuid, token, nil, true, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (u *UserClient) BatchLookup(ctx context.Context, req *dto.BatchUserRequest) (*dto.BatchUserResponse, error) {
+	var users dto.BatchUserResponse
+
+	// the lookup only reads, so it is as safe to repeat as a GET
+	_, err := u.do(ctx, http.MethodPost, "/internal/users/batch", "", req, true, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	return &users, nil
+}
+
+// VerifyToken returns the user a token belongs to. It fails with an *Error
+// wrapping ErrUnauthorized when the token is invalid, expired or revoked.
+func (u *UserClient) VerifyToken(ctx context.Context, token string) (*dto.UserResponse, error) {
+	var user dto.UserResponse
+
+	_, err := u.do(ctx, http.MethodGet, "/auth/user", token, nil, true, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (u *UserClient) do(
+	ctx context.Context,
+	method, path, token string,
+	body interface{},
+	retryable bool,
+	data interface{},
+) (*envelope, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	attempts := 1
+	if retryable && u.config.MaxRetries > 0 {
+		attempts += u.config.MaxRetries
+	}
+
+	var (
+		resp *envelope
+		err  error
+	)
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			err = u.wait(ctx, attempt)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var retry bool
+		resp, retry, err = u.attempt(ctx, method, path, token, payload, data)
+		if err == nil || !retry {
+			return resp, err
+		}
+	}
+
+	return nil, err
+}
+
+// attempt sends the request once and reports whether a failure is worth
+// retrying, which is the case for transport errors, 429 and 5xx.
+func (u *UserClient) attempt(
+	ctx context.Context,
+	method, path, token string,
+	payload []byte,
+	data interface{},
+) (*envelope, bool, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, u.config.Timeout)
+	defer cancel()
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(attemptCtx, method, u.config.BaseURL+"/api/v1"+path, body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set(constants.Authorization, "Bearer "+token)
+	}
+	if requestID, ok := ctx.Value(constants.RequestID).(string); ok {
+		req.Header.Set(constants.XRequestID, requestID)
+	}
+	u.sign(req)
+
+	res, err := u.client.Do(req)
+	if err != nil {
+		// the caller gave up, trying again cannot help
+		if ctx.Err() != nil {
+			return nil, false, ctx.Err()
+		}
+		return nil, true, err
+	}
+	defer res.Body.Close()
+
+	var resp envelope
+	err = json.NewDecoder(res.Body).Decode(&resp)
+	if err != nil {
+		if res.StatusCode >= http.StatusBadRequest {
+			return nil, isRetryableStatus(res.StatusCode), newError(res.StatusCode, http.StatusText(res.StatusCode))
+		}
+		return nil, false, fmt.Errorf("%w: %v", ErrUnexpectedResponse, err)
+	}
+
+	if res.StatusCode >= http.StatusBadRequest || resp.Status == constants.Error {
+		return nil, isRetryableStatus(res.StatusCode), newError(res.StatusCode, resp.Message)
+	}
+
+	if data != nil && len(resp.Data) > 0 {
+		err = json.Unmarshal(resp.Data, data)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: %v", ErrUnexpectedResponse, err)
+		}
+	}
+
+	return &resp, false, nil
+}
+
+// sign adds the api key headers user-service expects from other services.
+func (u *UserClient) sign(req *http.Request) {
+	requestAt := strconv.FormatInt(time.Now().Unix(), 10)
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s", u.config.ServiceName, u.config.SignatureKey, requestAt)))
+
+	req.Header.Set(constants.XServiceName, u.config.ServiceName)
+	req.Header.Set(constants.XRequestAt, requestAt)
+	req.Header.Set(constants.XApiKey, hex.EncodeToString(hash[:]))
+}
+
+// wait sleeps before the given retry with exponential backoff and full
+// jitter, returning early when ctx is done.
+func (u *UserClient) wait(ctx context.Context, attempt int) error {
+	backoff := u.config.RetryBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(backoff)) + 1))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,46 @@
+package clients
+
+import (
+	"errors"
+	"fmt"
+	errConstant "user-service/constants/error"
+)
+
+// Error is returned for every response user-service answers with an error
+// status. Err is the matching sentinel from constants/error when the
+// message is one user-service knows, so callers can use errors.Is.
+type Error struct {
+	StatusCode int
+	Message    string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("user-service: %d %s", e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnexpectedResponse is returned when a response body cannot be decoded.
+var ErrUnexpectedResponse = errors.New("unexpected response from user-service")
+
+// knownErrors holds every error the service reports by message, the same
+// ones ErrMapping passes through.
+var knownErrors = func() map[string]error {
+	known := make(map[string]error)
+	for _, err := range errConstant.AllErrors() {
+		known[err.Error()] = err
+	}
+
+	return known
+}()
+
+func newError(statusCode int, message string) *Error {
+	return &Error{
+		StatusCode: statusCode,
+		Message:    message,
+		Err:        knownErrors[message],
+	}
+}
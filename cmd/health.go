@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+	"user-service/common/health"
+	"user-service/config"
+	"user-service/database/migrations"
+	"user-service/database/resolver"
+	userServices "user-service/services/user"
+)
+
+// names of the components /readyz reports, also the keys of
+// health.critical in the config
+const (
+	healthDatabase   = "database"
+	healthMigrations = "migrations"
+	healthKeys       = "keys"
+	healthReplicas   = "replicas"
+	healthCache      = "cache"
+	healthBroker     = "broker"
+	healthRateLimit  = "ratelimit"
+)
+
+// newHealthChecker checks the database, its schema and the signing key,
+// which the service cannot work without, and the optional dependencies
+// that can tell whether they are up. Those only degrade readiness unless
+// health.critical says otherwise.
+func newHealthChecker(db *sql.DB, migrator migrations.IMigrator, replicas resolver.IResolver, optional map[string]any) *health.Checker {
+	checks := []health.Check{
+		{Name: healthDatabase, Critical: true, Check: db.PingContext},
+		{Name: healthMigrations, Critical: true, Check: func(ctx context.Context) error {
+			pending, err := migrator.Pending(ctx)
+			if err != nil {
+				return err
+			}
+			if len(pending) > 0 {
+				return fmt.Errorf("%d migration(s) pending", len(pending))
+			}
+
+			return nil
+		}},
+		{Name: healthKeys, Critical: true, Check: func(context.Context) error {
+			return userServices.CheckSigningKey()
+		}},
+	}
+	if len(config.Config.Database.Replicas) > 0 {
+		checks = append(checks, health.Check{Name: healthReplicas, Check: func(context.Context) error {
+			return replicas.Err()
+		}})
+	}
+
+	for _, name := range []string{healthCache, healthBroker, healthRateLimit} {
+		if pinger, ok := optional[name].(health.Pinger); ok {
+			checks = append(checks, health.Check{Name: name, Check: pinger.Ping})
+		}
+	}
+
+	return health.NewChecker(time.Duration(config.Config.Health.TimeoutSecond)*time.Second,
+		config.Config.Health.Critical, checks...)
+}
@@ -1,25 +1,41 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
+	"user-service/common/broker"
+	"user-service/common/cache"
+	"user-service/common/health"
+	"user-service/common/mailer"
+	"user-service/common/ratelimit"
 	"user-service/common/response"
+	"user-service/common/shutdown"
 	"user-service/config"
 	"user-service/constants"
 	"user-service/controllers"
+	"user-service/database/resolver"
 	"user-service/database/seeders"
-	"user-service/domain/models"
 	"user-service/middlewares"
 	"user-service/repositories"
+	userRepositories "user-service/repositories/user"
 	"user-service/routes"
+	"user-service/rpc"
 	"user-service/services"
+	userServices "user-service/services/user"
 
-	"github.com/didip/tollbooth"
-	"github.com/didip/tollbooth/limiter"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
 )
 
 var command = &cobra.Command{
@@ -29,6 +45,12 @@ var command = &cobra.Command{
 		_ = godotenv.Load()
 		config.Init()
 
+		// tokens signed with a key that does not parse cannot be verified
+		err := userServices.CheckSigningKey()
+		if err != nil {
+			panic(err)
+		}
+
 		db, err := config.InitDatabase()
 		if err != nil {
 			panic(err)
@@ -41,19 +63,75 @@ var command = &cobra.Command{
 
 		time.Local = loc
 
-		err = db.AutoMigrate(&models.Role{}, &models.User{})
+		sqlDB, err := db.DB()
+		if err != nil {
+			panic(err)
+		}
+
+		// the schema is owned by `migrate up`, refuse to run against an
+		// older one instead of failing on the first query that needs it
+		migrator := mustMigrator(sqlDB)
+		pending, err := migrator.Pending(context.Background())
 		if err != nil {
 			panic(err)
 		}
+		if len(pending) > 0 {
+			logrus.Fatalf("database schema is %d migration(s) behind, run `migrate up` first", len(pending))
+		}
 
 		seeders.NewSeederRegistry(db).Run()
 
-		repository := repositories.NewRepositoryRegistry(db)
-		service := services.NewServiceRegistry(repository)
+		replicas, err := config.InitReplicas()
+		if err != nil {
+			panic(err)
+		}
+
+		dbResolver, err := resolver.Register(db, replicas,
+			time.Duration(config.Config.Database.ReplicaHealthCheckSecond)*time.Second)
+		if err != nil {
+			panic(err)
+		}
+
+		cacheBackend, err := cache.NewCache(config.Config.Cache)
+		if err != nil {
+			panic(err)
+		}
+
+		repository := repositories.NewRepositoryRegistry(db, newUserCache(cacheBackend))
+		eventBroker, err := broker.NewBroker(config.Config.Broker, config.Config.AppEnv)
+		if err != nil {
+			panic(err)
+		}
+
+		mails := mailer.NewQueue(mailer.NewMailer(config.Config.Mail), config.Config.Mail)
+		service := services.NewServiceRegistry(repository, mails, eventBroker)
+
+		// from here on a signal has to go through the drain below, so it is
+		// caught before the workers and servers start
+		signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		background := shutdown.NewWorkers()
+		background.Go(dbResolver.Run)
+		background.Go(service.GetOutbox().Run)
+		background.Go(service.GetWebhook().Run)
+
 		controller := controllers.NewControllerRegistry(service)
 
+		rateLimitStore, err := ratelimit.NewStore(config.Config.RateLimit)
+		if err != nil {
+			panic(err)
+		}
+
+		checker := newHealthChecker(sqlDB, migrator, dbResolver, map[string]any{
+			healthCache:     cacheBackend,
+			healthBroker:    eventBroker,
+			healthRateLimit: rateLimitStore,
+		})
+
 		router := gin.Default()
 		router.Use(middlewares.HandlePanic())
+		router.Use(middlewares.RequestMetadata())
 		router.NoRoute(func(c *gin.Context) {
 			c.JSON(http.StatusNotFound, response.Response{
 				Status:  constants.Error,
@@ -68,11 +146,15 @@ var command = &cobra.Command{
 			})
 		})
 
+		router.GET("/healthz", health.Liveness)
+		router.GET("/readyz", checker.Readiness(middlewares.IsService))
+
 		// Handle CORS
 		router.Use(func(c *gin.Context) {
 			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT")
-			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, x-service-name, x-api-key, x-request-at")
+			c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
+			c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, x-service-name, x-api-key, x-request-at, x-request-id, if-match")
+			c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-Id")
 			if c.Request.Method == "OPTIONS" {
 				c.AbortWithStatus(204)
 				return
@@ -80,21 +162,157 @@ var command = &cobra.Command{
 			c.Next()
 		})
 
-		lmt := tollbooth.NewLimiter(config.Config.RateLimiterMaxRequest, &limiter.ExpirableOptions{
-			DefaultExpirationTTL: time.Duration(config.Config.RateLimiterTimeSecond) * time.Second,
-		})
+		rateLimits, err := ratelimit.NewPolicies(rateLimitStore, rateLimitConfig(config.Config))
+		if err != nil {
+			panic(err)
+		}
+		go reloadRateLimits(rateLimits)
 
-		router.Use(middlewares.RateLimiter(lmt))
+		router.Use(middlewares.RateLimit(rateLimits, ratelimit.DefaultPolicy))
 
 		group := router.Group("/api/v1")
-		route := routes.NewRouteRegistry(controller, group)
+		route := routes.NewRouteRegistry(controller, service, group, rateLimits)
 		route.Serve()
 
-		port := fmt.Sprintf(":%d", config.Config.Port)
-		router.Run(port)
+		// servers report here when they stop on their own, which shuts the
+		// rest down like a signal would
+		serverErrors := make(chan error, 2)
+
+		var grpcServer *grpc.Server
+		if config.Config.GRPCPort != 0 {
+			listener, err := net.Listen("tcp", fmt.Sprintf(":%d", config.Config.GRPCPort))
+			if err != nil {
+				panic(err)
+			}
+
+			grpcServer = rpc.NewServer(service, rateLimits)
+			go func() {
+				serverErrors <- grpcServer.Serve(listener)
+			}()
+		}
+
+		httpServer := newHTTPServer(fmt.Sprintf(":%d", config.Config.Port), router, config.Config.Server)
+		go func() {
+			logrus.Infof("listening on %s", httpServer.Addr)
+			err := httpServer.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				serverErrors <- err
+			}
+		}()
+
+		failed := false
+		select {
+		case <-signals.Done():
+			logrus.Info("shutting down")
+		case err := <-serverErrors:
+			logrus.Errorf("server stopped, shutting down: %v", err)
+			failed = true
+		}
+		stop()
+
+		checker.ShutDown()
+		if delay := config.Config.Server.ShutdownDelaySecond; delay > 0 && !failed {
+			logrus.Infof("failing readiness for %ds before closing the listeners", delay)
+			time.Sleep(time.Duration(delay) * time.Second)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(),
+			seconds(config.Config.Server.ShutdownTimeoutSecond, defaultShutdownTimeout))
+		defer cancel()
+
+		// stop taking requests and let the ones in flight finish first,
+		// they may still need every dependency below
+		var servers sync.WaitGroup
+		servers.Add(1)
+		go func() {
+			defer servers.Done()
+			if err := httpServer.Shutdown(ctx); err != nil {
+				logrus.Errorf("http requests still running at the shutdown deadline: %v", err)
+			}
+		}()
+		if grpcServer != nil {
+			servers.Add(1)
+			go func() {
+				defer servers.Done()
+				shutdown.StopGRPC(ctx, grpcServer)
+			}()
+		}
+		servers.Wait()
+
+		if err := background.Stop(ctx); err != nil {
+			logrus.Errorf("background workers still running at the shutdown deadline: %v", err)
+		}
+
+		// mails queued by the requests above are sent before the
+		// dependencies close
+		if err := mails.Close(ctx); err != nil {
+			logrus.Errorf("mails still queued at the shutdown deadline: %v", err)
+		}
+
+		closers := []shutdown.Closer{
+			{Name: "broker", Close: eventBroker.Close},
+			{Name: "rate limit store", Close: rateLimitStore.Close},
+			{Name: "read replicas", Close: dbResolver.Close},
+			{Name: "database", Close: sqlDB.Close},
+		}
+		if cacheBackend != nil {
+			closers = append([]shutdown.Closer{{Name: "cache", Close: cacheBackend.Close}}, closers...)
+		}
+		shutdown.CloseAll(closers)
+
+		logrus.Info("shutdown complete")
+		if failed {
+			os.Exit(1)
+		}
 	},
 }
 
+// newUserCache returns nil when caching is turned off.
+func newUserCache(backend cache.ICache) *userRepositories.UserCache {
+	if backend == nil {
+		return nil
+	}
+
+	return userRepositories.NewUserCache(backend,
+		time.Duration(config.Config.Cache.TTLSecond)*time.Second,
+		time.Duration(config.Config.Cache.NegativeTTLSecond)*time.Second)
+}
+
+// rateLimitConfig fills the default policy in from the older
+// rateLimiterMaxRequest and rateLimiterTimeSecond settings.
+func rateLimitConfig(cfg config.AppConfig) config.RateLimit {
+	rateLimit := cfg.RateLimit
+	if rateLimit.Default.Limit == 0 {
+		rateLimit.Default.Limit = int(cfg.RateLimiterMaxRequest)
+	}
+	if rateLimit.Default.WindowSecond == 0 {
+		rateLimit.Default.WindowSecond = cfg.RateLimiterTimeSecond
+	}
+
+	return rateLimit
+}
+
+// reloadRateLimits reads the configuration again on every SIGHUP and swaps
+// the rate limit policies in. Nothing else is reloaded.
+func reloadRateLimits(policies *ratelimit.Policies) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		cfg, err := config.Load()
+		if err != nil {
+			logrus.Errorf("failed to reload config: %v", err)
+			continue
+		}
+
+		if err := policies.Load(rateLimitConfig(cfg)); err != nil {
+			logrus.Errorf("failed to reload rate limit policies, keeping the current ones: %v", err)
+			continue
+		}
+		logrus.Infof("reloaded %d rate limit policies", len(cfg.RateLimit.Policies)+1)
+	}
+}
+
 func Run() {
 	command.Execute()
 }
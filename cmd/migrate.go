@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"user-service/config"
+	"user-service/database/migrations"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var migrateCommand = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage the database schema",
+}
+
+var migrateUpCommand = &cobra.Command{
+	Use:   "up",
+	Short: "Apply every pending migration",
+	Run: func(c *cobra.Command, args []string) {
+		applied, err := newMigrator().Up(context.Background())
+		if err != nil {
+			logrus.Fatalf("failed to migrate: %v", err)
+		}
+
+		logrus.Infof("%d migration(s) applied", len(applied))
+	},
+}
+
+var migrateDownCommand = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the most recent migrations",
+	Run: func(c *cobra.Command, args []string) {
+		steps, _ := c.Flags().GetInt("steps")
+
+		reverted, err := newMigrator().Down(context.Background(), steps)
+		if err != nil {
+			logrus.Fatalf("failed to revert: %v", err)
+		}
+
+		logrus.Infof("%d migration(s) reverted", len(reverted))
+	},
+}
+
+var migrateStatusCommand = &cobra.Command{
+	Use:   "status",
+	Short: "List migrations and whether they are applied",
+	Run: func(c *cobra.Command, args []string) {
+		statuses, err := newMigrator().Status(context.Background())
+		if err != nil {
+			logrus.Fatalf("failed to read migration status: %v", err)
+		}
+
+		printJSON(statuses)
+	},
+}
+
+var migrateCreateCommand = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an empty up and down migration",
+	Args:  cobra.ExactArgs(1),
+	Run: func(c *cobra.Command, args []string) {
+		dir, _ := c.Flags().GetString("dir")
+
+		paths, err := migrations.Create(dir, args[0])
+		if err != nil {
+			logrus.Fatalf("failed to create migration: %v", err)
+		}
+
+		for _, path := range paths {
+			logrus.Infof("created %s", path)
+		}
+	},
+}
+
+func newMigrator() migrations.IMigrator {
+	_ = godotenv.Load()
+	config.Init()
+
+	db, err := config.InitDatabase()
+	if err != nil {
+		panic(err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		panic(err)
+	}
+
+	return mustMigrator(sqlDB)
+}
+
+func mustMigrator(db *sql.DB) migrations.IMigrator {
+	migrator, err := migrations.NewMigrator(db)
+	if err != nil {
+		panic(err)
+	}
+
+	return migrator
+}
+
+func init() {
+	migrateDownCommand.Flags().Int("steps", 1, "number of migrations to revert")
+	migrateCreateCommand.Flags().String("dir", migrations.DefaultDir, "directory to write the migration files to")
+
+	migrateCommand.AddCommand(migrateUpCommand, migrateDownCommand, migrateStatusCommand, migrateCreateCommand)
+	command.AddCommand(migrateCommand)
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"net/http"
+	"time"
+	"user-service/config"
+)
+
+const (
+	defaultReadTimeout       = 15 * time.Second
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 60 * time.Second
+	defaultShutdownTimeout   = 25 * time.Second
+)
+
+func seconds(value int, fallback time.Duration) time.Duration {
+	if value <= 0 {
+		return fallback
+	}
+	return time.Duration(value) * time.Second
+}
+
+func newHTTPServer(addr string, handler http.Handler, cfg config.Server) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       seconds(cfg.ReadTimeoutSecond, defaultReadTimeout),
+		ReadHeaderTimeout: seconds(cfg.ReadHeaderTimeoutSecond, defaultReadHeaderTimeout),
+		WriteTimeout:      seconds(cfg.WriteTimeoutSecond, defaultWriteTimeout),
+		IdleTimeout:       seconds(cfg.IdleTimeoutSecond, defaultIdleTimeout),
+	}
+}
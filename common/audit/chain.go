@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+	"user-service/domain/models"
+
+	"github.com/google/uuid"
+)
+
+// ChainGlobal links the entries that have no target.
+const ChainGlobal = "global"
+
+var (
+	ErrInvalidSigningKey = errors.New("audit signing key must be a base64 encoded ed25519 seed")
+	ErrInvalidSignature  = errors.New("checkpoint signature is invalid")
+)
+
+// chainPayload is the canonical form of an audit entry that gets hashed.
+// Optional fields are omitted when empty so that adding new ones later does
+// not change the hash of entries written before they existed.
+type chainPayload struct {
+	PrevHash         string          `json:"prevHash"`
+	Chain            string          `json:"chain,omitempty"`
+	UUID             uuid.UUID       `json:"uuid"`
+	Event            string          `json:"event"`
+	ActorUUID        *uuid.UUID      `json:"actorUuid,omitempty"`
+	TargetUUID       *uuid.UUID      `json:"targetUuid,omitempty"`
+	ImpersonatorUUID *uuid.UUID      `json:"impersonatorUuid,omitempty"`
+	IPAddress        string          `json:"ipAddress,omitempty"`
+	UserAgent        string          `json:"userAgent,omitempty"`
+	RequestID        string          `json:"requestId,omitempty"`
+	Metadata         json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt        string          `json:"createdAt"`
+}
+
+type Checkpoint struct {
+	LastID   uint   `json:"lastId"`
+	LastHash string `json:"lastHash"`
+	Count    int64  `json:"count"`
+	// HeadsDigest covers the last hash of every chain up to LastID, so a
+	// chain cut short after the checkpoint is noticed too.
+	HeadsDigest string    `json:"headsDigest,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	PublicKey   string    `json:"publicKey"`
+	Signature   string    `json:"signature"`
+}
+
+// canonicalJSON re-encodes raw so that the same document hashes the same
+// before it is stored and after postgres returns it from a jsonb column.
+func canonicalJSON(raw json.RawMessage) json.RawMessage {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	err := json.Unmarshal(raw, &value)
+	if err != nil {
+		return raw
+	}
+
+	result, err := json.Marshal(value)
+	if err != nil {
+		return raw
+	}
+
+	return result
+}
+
+// Timestamp truncates t to the precision postgres keeps, so the value that
+// is hashed is the value that is read back.
+func Timestamp(t time.Time) time.Time {
+	return t.Truncate(time.Microsecond)
+}
+
+// ChainOf names the chain log is linked into. Every target has a chain of
+// its own, so writers auditing different users never wait on each other.
+func ChainOf(log *models.AuditLog) string {
+	if log.TargetUUID == nil {
+		return ChainGlobal
+	}
+
+	return log.TargetUUID.String()
+}
+
+// HeadsDigest hashes the last hash of every chain, keyed by chain name.
+func HeadsDigest(heads map[string]string) string {
+	chains := make([]string, 0, len(heads))
+	for chain := range heads {
+		chains = append(chains, chain)
+	}
+	sort.Strings(chains)
+
+	hash := sha256.New()
+	for _, chain := range chains {
+		hash.Write([]byte(chain + ":" + heads[chain] + "\n"))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func ComputeHash(prevHash string, log *models.AuditLog) string {
+	payload := chainPayload{
+		PrevHash:         prevHash,
+		Chain:            log.Chain,
+		UUID:             log.UUID,
+		Event:            log.Event,
+		ActorUUID:        log.ActorUUID,
+		TargetUUID:       log.TargetUUID,
+		ImpersonatorUUID: log.ImpersonatorUUID,
+		IPAddress:        log.IPAddress,
+		UserAgent:        log.UserAgent,
+		RequestID:        log.RequestID,
+		Metadata:         canonicalJSON(log.Metadata),
+	}
+	if log.CreatedAt != nil {
+		payload.CreatedAt = log.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	// marshalling a struct of plain fields can not fail
+	raw, _ := json.Marshal(payload)
+	sum := sha256.Sum256(raw)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func signingKey(seed string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil || len(raw) != ed25519.SeedSize {
+		return nil, ErrInvalidSigningKey
+	}
+
+	return ed25519.NewKeyFromSeed(raw), nil
+}
+
+func checkpointMessage(cp *Checkpoint) []byte {
+	raw, _ := json.Marshal(struct {
+		LastID      uint   `json:"lastId"`
+		LastHash    string `json:"lastHash"`
+		Count       int64  `json:"count"`
+		HeadsDigest string `json:"headsDigest,omitempty"`
+		CreatedAt   string `json:"createdAt"`
+	}{
+		LastID:      cp.LastID,
+		LastHash:    cp.LastHash,
+		Count:       cp.Count,
+		HeadsDigest: cp.HeadsDigest,
+		CreatedAt:   cp.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	return raw
+}
+
+// SignCheckpoint signs cp with the ed25519 key derived from seed and embeds
+// the public key so the checkpoint can be verified by anyone holding it.
+func SignCheckpoint(cp *Checkpoint, seed string) error {
+	key, err := signingKey(seed)
+	if err != nil {
+		return err
+	}
+
+	cp.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, checkpointMessage(cp)))
+
+	return nil
+}
+
+// VerifyCheckpoint checks the signature of cp against publicKey. When
+// publicKey is empty the key embedded in the checkpoint is used.
+func VerifyCheckpoint(cp *Checkpoint, publicKey string) error {
+	if publicKey == "" {
+		publicKey = cp.PublicKey
+	}
+
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return ErrInvalidSignature
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(cp.Signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if !ed25519.Verify(key, checkpointMessage(cp), signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
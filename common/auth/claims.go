@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"user-service/domain/dto"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the payload of every token user-service issues. The ID claim
+// holds the session uuid.
+type Claims struct {
+	User         *dto.UserResponse
+	Impersonator *dto.UserResponse `json:",omitempty"`
+	jwt.RegisteredClaims
+}
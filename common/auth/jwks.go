@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+)
+
+const (
+	KeyTypeRSA   = "RSA"
+	KeyUseSig    = "sig"
+	AlgorithmRSA = "RS256"
+)
+
+var (
+	ErrInvalidKey = errors.New("invalid rsa key")
+	ErrKeyNotRSA  = errors.New("jwk is not an rsa signing key")
+)
+
+// JWK is the public half of an RSA signing key as published in a JWKS.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParseRSAPrivateKey reads a PEM encoded PKCS#1 or PKCS#8 RSA private key.
+func ParseRSAPrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	return rsaKey, nil
+}
+
+// ParseRSAPublicKey reads a PEM encoded PKIX or PKCS#1 RSA public key.
+func ParseRSAPublicKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, ErrInvalidKey
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	return rsaKey, nil
+}
+
+// NewJWK describes key under kid. An empty kid is replaced by the RFC 7638
+// thumbprint of the key.
+func NewJWK(key *rsa.PublicKey, kid string) JWK {
+	jwk := JWK{
+		Kty: KeyTypeRSA,
+		Use: KeyUseSig,
+		Alg: AlgorithmRSA,
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+
+	jwk.Kid = kid
+	if jwk.Kid == "" {
+		jwk.Kid = jwk.Thumbprint()
+	}
+
+	return jwk
+}
+
+// Thumbprint is the RFC 7638 SHA-256 thumbprint of the key.
+func (j JWK) Thumbprint() string {
+	// the members have to be in lexical order without whitespace
+	raw, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{E: j.E, Kty: j.Kty, N: j.N})
+
+	hash := sha256.Sum256(raw)
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func (j JWK) PublicKey() (*rsa.PublicKey, error) {
+	if j.Kty != KeyTypeRSA || (j.Use != "" && j.Use != KeyUseSig) {
+		return nil, ErrKeyNotRSA
+	}
+
+	n, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	e, err := base64.RawURLEncoding.DecodeString(j.E)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	key := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}
+	if key.N.Sign() == 0 || key.E == 0 {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
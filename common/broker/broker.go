@@ -0,0 +1,75 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"user-service/config"
+)
+
+const (
+	DriverMemory = "memory"
+	DriverFile   = "file"
+	DriverNATS   = "nats"
+)
+
+// Message is what the outbox relay hands to a broker. ID is stable across
+// retries so consumers and brokers that support it can drop duplicates.
+type Message struct {
+	ID      string
+	Topic   string
+	Key     string
+	Payload []byte
+}
+
+type IBroker interface {
+	Publish(context.Context, *Message) error
+	Close() error
+}
+
+// Factory builds a broker from its configuration.
+type Factory func(config.Broker) (IBroker, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Factory{
+		DriverMemory: func(config.Broker) (IBroker, error) { return NewMemoryBroker(), nil },
+		DriverFile:   func(cfg config.Broker) (IBroker, error) { return NewFileBroker(cfg.FilePath) },
+	}
+)
+
+// Register makes a driver available to NewBroker. Adapters that pull in a
+// client library register themselves from a file behind a build tag.
+func Register(driver string, factory Factory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[driver] = factory
+}
+
+// memoryEnvs are the app environments the memory broker may run in. It
+// keeps events in this process, anywhere else they would be marked as
+// published and lost.
+var memoryEnvs = []string{"local", "test"}
+
+// NewBroker returns the broker for cfg.Driver. The driver has to be set, and
+// the memory driver is refused unless env is one of memoryEnvs.
+func NewBroker(cfg config.Broker, env string) (IBroker, error) {
+	driver := cfg.Driver
+	if driver == "" {
+		return nil, errors.New("no broker driver is configured, set broker.driver")
+	}
+	if driver == DriverMemory && !slices.Contains(memoryEnvs, env) {
+		return nil, fmt.Errorf("broker driver %q is only allowed when appEnv is one of %v", driver, memoryEnvs)
+	}
+
+	driversMu.RLock()
+	factory, ok := drivers[driver]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("broker driver %q is not available in this build", driver)
+	}
+
+	return factory(cfg)
+}
@@ -0,0 +1,86 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defaultFilePath = "events.jsonl"
+
+// FileBroker appends every message as a JSON line to a file, which is
+// enough to watch the event stream of a local run with tail -f.
+type FileBroker struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+type fileRecord struct {
+	ID          string          `json:"id"`
+	Topic       string          `json:"topic"`
+	Key         string          `json:"key,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	PublishedAt time.Time       `json:"publishedAt"`
+}
+
+func NewFileBroker(path string) (*FileBroker, error) {
+	if path == "" {
+		path = defaultFilePath
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		err := os.MkdirAll(dir, 0o755)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileBroker{file: file}, nil
+}
+
+func (b *FileBroker) Publish(_ context.Context, message *Message) error {
+	payload := json.RawMessage(message.Payload)
+	if !json.Valid(payload) {
+		raw, err := json.Marshal(string(message.Payload))
+		if err != nil {
+			return err
+		}
+		payload = raw
+	}
+
+	line, err := json.Marshal(fileRecord{
+		ID:          message.ID,
+		Topic:       message.Topic,
+		Key:         message.Key,
+		Payload:     payload,
+		PublishedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, err = b.file.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+
+	// the relay marks the event published right after this returns
+	return b.file.Sync()
+}
+
+func (b *FileBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.file.Close()
+}
@@ -0,0 +1,44 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryBroker delivers messages to in-process subscribers. It is meant
+// for local runs and tests, nothing survives a restart.
+type MemoryBroker struct {
+	mu          sync.RWMutex
+	subscribers map[string][]func(*Message)
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: make(map[string][]func(*Message))}
+}
+
+// Subscribe calls handler for every message published on topic, or on any
+// topic when topic is empty.
+func (b *MemoryBroker) Subscribe(topic string, handler func(*Message)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[topic] = append(b.subscribers[topic], handler)
+}
+
+func (b *MemoryBroker) Publish(_ context.Context, message *Message) error {
+	b.mu.RLock()
+	handlers := append([]func(*Message){}, b.subscribers[message.Topic]...)
+	if message.Topic != "" {
+		handlers = append(handlers, b.subscribers[""]...)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Close() error {
+	return nil
+}
@@ -0,0 +1,69 @@
+//go:build nats
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"user-service/config"
+
+	"github.com/nats-io/nats.go"
+)
+
+func init() {
+	Register(DriverNATS, func(cfg config.Broker) (IBroker, error) {
+		return NewNATSBroker(cfg)
+	})
+}
+
+// NATSBroker publishes to JetStream. The message ID goes into the
+// Nats-Msg-Id header so the stream drops redeliveries of the same event
+// inside its duplicate window.
+type NATSBroker struct {
+	conn   *nats.Conn
+	js     nats.JetStreamContext
+	prefix string
+}
+
+func NewNATSBroker(cfg config.Broker) (*NATSBroker, error) {
+	url := cfg.URL
+	if url == "" {
+		url = nats.DefaultURL
+	}
+
+	conn, err := nats.Connect(url, nats.Name(config.Config.AppName))
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &NATSBroker{conn: conn, js: js, prefix: cfg.SubjectPrefix}, nil
+}
+
+func (b *NATSBroker) Publish(ctx context.Context, message *Message) error {
+	msg := nats.NewMsg(b.prefix + message.Topic)
+	msg.Data = message.Payload
+	if message.Key != "" {
+		msg.Header.Set("Key", message.Key)
+	}
+
+	_, err := b.js.PublishMsg(msg, nats.MsgId(message.ID), nats.Context(ctx))
+	return err
+}
+
+// Ping checks the connection is up and the server answers.
+func (b *NATSBroker) Ping(ctx context.Context) error {
+	if !b.conn.IsConnected() {
+		return fmt.Errorf("nats connection is %s", b.conn.Status())
+	}
+	return b.conn.FlushWithContext(ctx)
+}
+
+func (b *NATSBroker) Close() error {
+	return b.conn.Drain()
+}
@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"user-service/config"
+)
+
+const (
+	DriverNone   = "none"
+	DriverMemory = "memory"
+	DriverRedis  = "redis"
+)
+
+// ErrMiss is returned by Get when the key is not cached.
+var ErrMiss = errors.New("cache miss")
+
+type ICache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	Close() error
+}
+
+// Factory builds a cache from its configuration.
+type Factory func(config.Cache) (ICache, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Factory{
+		DriverMemory: func(cfg config.Cache) (ICache, error) { return NewLRU(cfg.Size), nil },
+		DriverRedis:  func(cfg config.Cache) (ICache, error) { return NewRedis(cfg.URL) },
+	}
+)
+
+// Register makes a driver available to NewCache.
+func Register(driver string, factory Factory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	drivers[driver] = factory
+}
+
+// NewCache returns the cache for cfg.Driver, the in-process LRU when no
+// driver is configured and nil when caching is turned off.
+func NewCache(cfg config.Cache) (ICache, error) {
+	driver := cfg.Driver
+	if driver == "" {
+		driver = DriverMemory
+	}
+	if driver == DriverNone {
+		return nil, nil
+	}
+
+	driversMu.RLock()
+	factory, ok := drivers[driver]
+	driversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("cache driver %q is not available in this build", driver)
+	}
+
+	return factory(cfg)
+}
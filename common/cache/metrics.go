@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics counts how lookups against one cached resource went.
+type Metrics struct {
+	hits         atomic.Uint64
+	misses       atomic.Uint64
+	negativeHits atomic.Uint64
+	errors       atomic.Uint64
+}
+
+type MetricsSnapshot struct {
+	Hits         uint64 `json:"hits"`
+	Misses       uint64 `json:"misses"`
+	NegativeHits uint64 `json:"negativeHits"`
+	Errors       uint64 `json:"errors"`
+}
+
+var (
+	metricsMu sync.Mutex
+	metrics   = map[string]*Metrics{}
+)
+
+// MetricsFor returns the counters for name, creating them on first use.
+// ServeMetrics reports them under cache.<name>.
+func MetricsFor(name string) *Metrics {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	if m, ok := metrics[name]; ok {
+		return m
+	}
+
+	m := &Metrics{}
+	metrics[name] = m
+	return m
+}
+
+// Snapshots returns the counters of every cached resource by name.
+func Snapshots() map[string]MetricsSnapshot {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+
+	snapshots := make(map[string]MetricsSnapshot, len(metrics))
+	for name, m := range metrics {
+		snapshots[name] = m.Snapshot()
+	}
+
+	return snapshots
+}
+
+// ServeMetrics answers with the cache counters and nothing else, the
+// process's command line and memory stats are none of a caller's business.
+func ServeMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"cache": Snapshots()})
+}
+
+func (m *Metrics) Hit()         { m.hits.Add(1) }
+func (m *Metrics) Miss()        { m.misses.Add(1) }
+func (m *Metrics) NegativeHit() { m.negativeHits.Add(1) }
+func (m *Metrics) Error()       { m.errors.Add(1) }
+
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	return MetricsSnapshot{
+		Hits:         m.hits.Load(),
+		Misses:       m.misses.Load(),
+		NegativeHits: m.negativeHits.Load(),
+		Errors:       m.errors.Load(),
+	}
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/common/redis"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Redis is a cache shared by every instance of the service.
+type Redis struct {
+	client *goredis.Client
+}
+
+// NewRedis connects to rawURL, see redis.NewClient for the accepted forms.
+func NewRedis(rawURL string) (*Redis, error) {
+	client, err := redis.NewClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Redis{client: client}, nil
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, ErrMiss
+	}
+
+	return value, err
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return c.client.Del(ctx, keys...).Err()
+}
+
+func (c *Redis) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+func (c *Redis) Close() error {
+	return c.client.Close()
+}
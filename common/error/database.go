@@ -0,0 +1,21 @@
+package error
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// pgUniqueViolation is the SQLSTATE Postgres reports for a duplicate key.
+const pgUniqueViolation = "23505"
+
+// UniqueViolation returns the name of the unique constraint or index err
+// violated, and false when err is not a unique violation.
+func UniqueViolation(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+		return pgErr.ConstraintName, true
+	}
+
+	return "", false
+}
@@ -14,7 +14,12 @@ type ValidationResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
-var ErrValidator = map[string]string{}
+var ErrValidator = map[string]string{
+	"oneof": "%s must be one of %s",
+	"uuid":  "%s is not a valid uuid",
+	"min":   "%s must be at least %s",
+	"max":   "%s must be at most %s",
+}
 
 func ErrValidationResponse(err error) (validationResponse []ValidationResponse) {
 	var fieldErrors validator.ValidationErrors
@@ -0,0 +1,195 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+
+	StatusOK           = "ok"
+	StatusDegraded     = "degraded"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+
+	defaultTimeout = 2 * time.Second
+
+	// reportTTL is how long /readyz answers from the last report, so a
+	// flood of probes does not turn into a flood of pings
+	reportTTL = time.Second
+)
+
+// Pinger is implemented by dependencies that can tell whether they are
+// reachable, like the Redis backed cache.
+type Pinger interface {
+	Ping(context.Context) error
+}
+
+// Check is one dependency readiness looks at. The service is not ready
+// while a critical check fails, a failing non-critical one only degrades
+// it.
+type Check struct {
+	Name     string
+	Critical bool
+	Check    func(context.Context) error
+}
+
+type Component struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components"`
+}
+
+// Checker runs the checks behind /readyz.
+type Checker struct {
+	checks       []Check
+	timeout      time.Duration
+	shuttingDown atomic.Bool
+
+	mu       sync.Mutex
+	last     Report
+	lastTime time.Time
+}
+
+// NewChecker runs every check with timeout, or two seconds when it is not
+// positive. critical overrides the criticality of checks by name.
+func NewChecker(timeout time.Duration, critical map[string]bool, checks ...Check) *Checker {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	for i := range checks {
+		if value, ok := critical[checks[i].Name]; ok {
+			checks[i].Critical = value
+		}
+	}
+
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// ShutDown makes readiness fail from now on, so the load balancer stops
+// sending traffic while in-flight requests drain.
+func (c *Checker) ShutDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Ready runs the checks concurrently and reports on each of them.
+func (c *Checker) Ready(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Components: make(map[string]Component, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			component := c.run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[check.Name] = component
+			if component.Status == StatusDown {
+				if check.Critical {
+					report.Status = StatusUnavailable
+				} else if report.Status == StatusOK {
+					report.Status = StatusDegraded
+				}
+			}
+		}(check)
+	}
+	wg.Wait()
+
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Component {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Check(ctx)
+	component := Component{
+		Status:    StatusUp,
+		Critical:  check.Critical,
+		LatencyMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		component.Status = StatusDown
+		component.Error = err.Error()
+	}
+
+	return component
+}
+
+// Liveness answers as long as the process serves requests. It checks no
+// dependency, restarting the pod would not bring a database back.
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+}
+
+// Readiness reports every component, with 503 while the service is
+// unavailable or shutting down. The errors of the components name hosts
+// and drivers, only callers showErrors accepts see them.
+func (c *Checker) Readiness(showErrors func(*gin.Context) bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report := c.recentReport(ctx.Request.Context())
+
+		code := http.StatusOK
+		if report.Status == StatusUnavailable || report.Status == StatusShuttingDown {
+			code = http.StatusServiceUnavailable
+		}
+
+		if !showErrors(ctx) {
+			report = report.withoutErrors()
+		}
+
+		ctx.JSON(code, report)
+	}
+}
+
+// recentReport runs the checks at most once per reportTTL. Callers that
+// arrive while they run wait for the same report.
+func (c *Checker) recentReport(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.lastTime.IsZero() || time.Since(c.lastTime) >= reportTTL {
+		c.last = c.Ready(ctx)
+		c.lastTime = time.Now()
+	}
+
+	// shutting down shows at once
+	report := c.last
+	if c.shuttingDown.Load() {
+		report.Status = StatusShuttingDown
+	}
+
+	return report
+}
+
+func (r Report) withoutErrors() Report {
+	components := make(map[string]Component, len(r.Components))
+	for name, component := range r.Components {
+		component.Error = ""
+		components[name] = component
+	}
+
+	return Report{Status: r.Status, Components: components}
+}
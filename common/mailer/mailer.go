@@ -0,0 +1,114 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"user-service/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+type IMailer interface {
+	Send(context.Context, *Message) error
+}
+
+type SMTPMailer struct {
+	config config.Mail
+}
+
+type LogMailer struct{}
+
+// NewMailer returns an SMTP mailer, or a mailer that only logs messages
+// when no SMTP host is configured so local runs need no mail server.
+func NewMailer(cfg config.Mail) IMailer {
+	if cfg.Host == "" {
+		return &LogMailer{}
+	}
+
+	return &SMTPMailer{config: cfg}
+}
+
+// Send delivers message over one SMTP connection, upgraded with STARTTLS
+// when the server offers it. The connection is given up at the deadline of
+// ctx.
+func (m *SMTPMailer) Send(ctx context.Context, message *Message) error {
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("From: %s\r\n", m.config.From))
+	body.WriteString(fmt.Sprintf("To: %s\r\n", strings.Join(message.To, ", ")))
+	body.WriteString(fmt.Sprintf("Subject: %s\r\n", message.Subject))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	body.WriteString(message.Body)
+
+	address := fmt.Sprintf("%s:%d", m.config.Host, m.config.Port)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: m.config.Host})
+		if err != nil {
+			return err
+		}
+	}
+
+	if m.config.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = client.Mail(m.config.From)
+	if err != nil {
+		return err
+	}
+	for _, to := range message.To {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write([]byte(body.String()))
+	if err != nil {
+		return err
+	}
+	err = writer.Close()
+	if err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+func (m *LogMailer) Send(_ context.Context, message *Message) error {
+	logrus.Infof("mail to %s: %s\n%s", strings.Join(message.To, ", "), message.Subject, message.Body)
+	return nil
+}
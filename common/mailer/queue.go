@@ -0,0 +1,118 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	"user-service/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultQueueSize     = 100
+	defaultWorkers       = 2
+	defaultTimeoutSecond = 30
+)
+
+var (
+	ErrQueueFull   = errors.New("mail queue is full")
+	ErrQueueClosed = errors.New("mail queue is closed")
+)
+
+// Queue hands messages to a fixed number of workers, so sending never holds
+// up the request that asked for it and a slow mail server can not pile up
+// goroutines. A full queue drops the message instead of waiting.
+type Queue struct {
+	mailer   IMailer
+	messages chan *Message
+	timeout  time.Duration
+	workers  sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewQueue(mailer IMailer, cfg config.Mail) *Queue {
+	size := cfg.QueueSize
+	if size == 0 {
+		size = defaultQueueSize
+	}
+	workers := cfg.Workers
+	if workers == 0 {
+		workers = defaultWorkers
+	}
+	timeout := cfg.TimeoutSecond
+	if timeout == 0 {
+		timeout = defaultTimeoutSecond
+	}
+
+	queue := &Queue{
+		mailer:   mailer,
+		messages: make(chan *Message, size),
+		timeout:  time.Duration(timeout) * time.Second,
+	}
+
+	queue.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go queue.run()
+	}
+
+	return queue
+}
+
+// Send queues message and returns straight away. The context of the caller
+// is not used, the message outlives the request.
+func (q *Queue) Send(_ context.Context, message *Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.messages <- message:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *Queue) run() {
+	defer q.workers.Done()
+
+	for message := range q.messages {
+		ctx, cancel := context.WithTimeout(context.Background(), q.timeout)
+		err := q.mailer.Send(ctx, message)
+		cancel()
+		if err != nil {
+			logrus.Errorf("failed to send %q mail: %v", message.Subject, err)
+		}
+	}
+}
+
+// Close stops taking messages and waits for the queued ones to be sent, or
+// for ctx.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.messages)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	AlgorithmSlidingWindow = "sliding_window"
+	AlgorithmTokenBucket   = "token_bucket"
+
+	casAttempts = 5
+)
+
+// Rule is how many requests a key may make per window. Burst only applies
+// to the token bucket.
+type Rule struct {
+	Limit  int
+	Window time.Duration
+	Burst  int
+}
+
+// Result is the outcome of one request against a limiter, in the terms of
+// the RateLimit response headers.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the whole budget is available again.
+	Reset time.Duration
+	// RetryAfter is the time until a denied request may succeed.
+	RetryAfter time.Duration
+}
+
+type ILimiter interface {
+	Allow(ctx context.Context, key string) (*Result, error)
+}
+
+// NewLimiter returns the limiter for algorithm, the sliding window when it
+// is empty.
+func NewLimiter(store IStore, algorithm string, rule Rule) (ILimiter, error) {
+	if rule.Limit <= 0 || rule.Window <= 0 {
+		return nil, fmt.Errorf("rate limit needs a positive limit and window, got %d per %s", rule.Limit, rule.Window)
+	}
+
+	switch algorithm {
+	case "", AlgorithmSlidingWindow:
+		return &SlidingWindow{store: store, rule: rule}, nil
+	case AlgorithmTokenBucket:
+		if rule.Burst <= 0 {
+			rule.Burst = rule.Limit
+		}
+		return &TokenBucket{store: store, rule: rule}, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit algorithm %q", algorithm)
+	}
+}
+
+// SlidingWindow counts requests in fixed windows and weighs the previous
+// window by how much of it still overlaps the last Window. Denied requests
+// are counted too, so a client that keeps hammering stays limited.
+type SlidingWindow struct {
+	store IStore
+	rule  Rule
+}
+
+func (l *SlidingWindow) Allow(ctx context.Context, key string) (*Result, error) {
+	now := time.Now()
+	window := l.rule.Window
+	index := now.UnixNano() / int64(window)
+	elapsed := time.Duration(now.UnixNano() % int64(window))
+
+	current, err := l.store.Increment(ctx, key+":"+strconv.FormatInt(index, 10), 2*window)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := l.store.Get(ctx, key+":"+strconv.FormatInt(index-1, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	overlap := 1 - float64(elapsed)/float64(window)
+	estimate := float64(previous)*overlap + float64(current)
+	limit := float64(l.rule.Limit)
+
+	result := &Result{
+		Allowed:   estimate <= limit,
+		Limit:     l.rule.Limit,
+		Remaining: max(0, l.rule.Limit-int(math.Ceil(estimate))),
+		Reset:     window - elapsed,
+	}
+	if !result.Allowed {
+		result.RetryAfter = l.retryAfter(float64(previous), float64(current), elapsed)
+	}
+
+	return result, nil
+}
+
+// retryAfter is when the estimate drops back to the limit without further
+// requests, either as the previous window fades out or, when the current
+// one alone is over the limit, as it fades out in turn.
+func (l *SlidingWindow) retryAfter(previous, current float64, elapsed time.Duration) time.Duration {
+	window := float64(l.rule.Window)
+	limit := float64(l.rule.Limit)
+
+	if current <= limit && previous > 0 {
+		overlap := (limit - current) / previous
+		return time.Duration((1-overlap)*window) - elapsed
+	}
+
+	return l.rule.Window - elapsed + time.Duration((1-limit/current)*window)
+}
+
+// TokenBucket refills Limit tokens per Window up to Burst. It keeps a single
+// value per key, the time the bucket will be full again, and updates it
+// with a compare and swap (the generic cell rate algorithm). Instances
+// sharing a store should keep their clocks in sync.
+type TokenBucket struct {
+	store IStore
+	rule  Rule
+}
+
+// ErrContention is returned when a token bucket key kept changing under a
+// limiter through all its attempts.
+var ErrContention = errors.New("rate limit key is too contended")
+
+func (l *TokenBucket) Allow(ctx context.Context, key string) (*Result, error) {
+	interval := l.rule.Window / time.Duration(l.rule.Limit)
+	tolerance := interval * time.Duration(l.rule.Burst)
+
+	for attempt := 0; attempt < casAttempts; attempt++ {
+		now := time.Now().UnixNano()
+
+		stored, err := l.store.Get(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+
+		full := max(stored, now)
+		next := full + int64(interval)
+		wait := time.Duration(next - now)
+
+		if wait > tolerance {
+			return &Result{
+				Limit:      l.rule.Burst,
+				Reset:      time.Duration(full - now),
+				RetryAfter: wait - tolerance,
+			}, nil
+		}
+
+		swapped, err := l.store.CompareAndSwap(ctx, key, stored, next, wait)
+		if err != nil {
+			return nil, err
+		}
+		if swapped {
+			return &Result{
+				Allowed:   true,
+				Limit:     l.rule.Burst,
+				Remaining: int((tolerance - wait) / interval),
+				Reset:     wait,
+			}, nil
+		}
+	}
+
+	return nil, ErrContention
+}
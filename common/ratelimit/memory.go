@@ -0,0 +1,98 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const memorySweepInterval = time.Minute
+
+type counter struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// MemoryStore keeps counters in process memory. Every instance has its own
+// budget, so it suits a single instance and tests.
+type MemoryStore struct {
+	mu        sync.Mutex
+	counters  map[string]counter
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{counters: map[string]counter{}, lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Increment(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	current := s.get(key, now)
+	current.value++
+	current.expiresAt = now.Add(ttl)
+	s.counters[key] = current
+
+	return current.value, nil
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.get(key, time.Now()).value, nil
+}
+
+func (s *MemoryStore) CompareAndSwap(_ context.Context, key string, old, next int64, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	if s.get(key, now).value != old {
+		return false, nil
+	}
+
+	s.counters[key] = counter{value: next, expiresAt: now.Add(ttl)}
+	return true, nil
+}
+
+// Len returns the number of counters held, expired ones included until the
+// next sweep.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.counters)
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) get(key string, now time.Time) counter {
+	current, ok := s.counters[key]
+	if !ok || !now.Before(current.expiresAt) {
+		return counter{}
+	}
+	return current
+}
+
+// sweep drops expired counters now and then, so clients that went away do
+// not keep their keys forever.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+
+	for key, current := range s.counters {
+		if !now.Before(current.expiresAt) {
+			delete(s.counters, key)
+		}
+	}
+	s.lastSweep = now
+}
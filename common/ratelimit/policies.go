@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sync/atomic"
+	"user-service/config"
+)
+
+// DefaultPolicy is the name of the policy every request counts against.
+const DefaultPolicy = "default"
+
+// Policies holds the configured policies by name. Load swaps them all at
+// once, so a request never sees half of a reload.
+type Policies struct {
+	store   IStore
+	current atomic.Pointer[map[string]*Policy]
+}
+
+func NewPolicies(store IStore, cfg config.RateLimit) (*Policies, error) {
+	policies := &Policies{store: store}
+	if err := policies.Load(cfg); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// Load replaces the policies with the ones cfg describes. When any of them
+// is invalid the previous ones stay in place. The store is not part of a
+// reload, changing it takes a restart.
+func (p *Policies) Load(cfg config.RateLimit) error {
+	if _, ok := cfg.Policies[DefaultPolicy]; ok {
+		return fmt.Errorf("rate limit policy name %q is reserved, use rateLimit.default", DefaultPolicy)
+	}
+
+	named := make(map[string]*Policy, len(cfg.Policies)+1)
+
+	policy, err := NewPolicy(DefaultPolicy, p.store, cfg.Default)
+	if err != nil {
+		return err
+	}
+	named[DefaultPolicy] = policy
+
+	for name, policyConfig := range cfg.Policies {
+		policy, err := NewPolicy(name, p.store, policyConfig)
+		if err != nil {
+			return err
+		}
+		named[name] = policy
+	}
+
+	p.current.Store(&named)
+	return nil
+}
+
+// Get returns the policy called name. A route may name a policy the
+// configuration does not define, it is then only held to the default.
+func (p *Policies) Get(name string) (*Policy, bool) {
+	policy, ok := (*p.current.Load())[name]
+	return policy, ok
+}
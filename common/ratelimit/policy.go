@@ -0,0 +1,191 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"user-service/config"
+	"user-service/constants"
+)
+
+const (
+	KeyIP      = "ip"
+	KeyUser    = "user"
+	KeyService = "service"
+	KeyRoute   = "route"
+
+	keyPrefix = "user-service:ratelimit:"
+)
+
+// Subject is what is known about a request when it is limited. User and
+// Service are only set once their credentials checked out.
+type Subject struct {
+	IP      string
+	User    string
+	Service string
+	Route   string
+}
+
+// Policy is a named limit with the parts of a request that share a
+// budget and the clients it does not apply to.
+type Policy struct {
+	Name           string
+	Rule           Rule
+	KeyBy          []string
+	ExemptServices []string
+	exemptIPs      []netip.Prefix
+	limiter        ILimiter
+}
+
+// NewPolicy builds the policy cfg describes on store.
+func NewPolicy(name string, store IStore, cfg config.RateLimitPolicy) (*Policy, error) {
+	keyBy := cfg.KeyBy
+	if len(keyBy) == 0 {
+		keyBy = []string{KeyIP}
+	}
+	for _, part := range keyBy {
+		switch part {
+		case KeyIP, KeyUser, KeyService, KeyRoute:
+		default:
+			return nil, fmt.Errorf("rate limit policy %s: unknown key %q", name, part)
+		}
+	}
+
+	rule := Rule{
+		Limit:  cfg.Limit,
+		Window: time.Duration(cfg.WindowSecond) * time.Second,
+		Burst:  cfg.Burst,
+	}
+
+	limiter, err := NewLimiter(store, cfg.Algorithm, rule)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit policy %s: %w", name, err)
+	}
+
+	exemptIPs := make([]netip.Prefix, 0, len(cfg.ExemptIPs))
+	for _, value := range cfg.ExemptIPs {
+		prefix, err := parsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit policy %s: exempt ip %q: %w", name, value, err)
+		}
+		exemptIPs = append(exemptIPs, prefix)
+	}
+
+	return &Policy{
+		Name:           name,
+		Rule:           rule,
+		KeyBy:          keyBy,
+		ExemptServices: cfg.ExemptServices,
+		exemptIPs:      exemptIPs,
+		limiter:        limiter,
+	}, nil
+}
+
+// parsePrefix accepts a CIDR range or a single address.
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		return netip.ParsePrefix(value)
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Needs reports whether the policy looks at part of the subject, to spare
+// resolving the user or service when it does not.
+func (p *Policy) Needs(part string) bool {
+	if part == KeyService && len(p.ExemptServices) > 0 {
+		return true
+	}
+	return slices.Contains(p.KeyBy, part)
+}
+
+// Exempt reports whether subject is one of the clients the policy lets
+// through unlimited.
+func (p *Policy) Exempt(subject Subject) bool {
+	if subject.Service != "" && slices.Contains(p.ExemptServices, subject.Service) {
+		return true
+	}
+
+	if len(p.exemptIPs) == 0 {
+		return false
+	}
+
+	addr, err := netip.ParseAddr(subject.IP)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range p.exemptIPs {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Policy) Allow(ctx context.Context, subject Subject) (*Result, error) {
+	return p.limiter.Allow(ctx, p.Key(subject))
+}
+
+// Key names the budget subject draws from. user and service fall back to
+// the ip for anonymous requests, so they never share one global budget.
+func (p *Policy) Key(subject Subject) string {
+	parts := make([]string, 0, len(p.KeyBy))
+	for _, part := range p.KeyBy {
+		switch part {
+		case KeyUser:
+			parts = append(parts, identity(part, subject.User, subject.IP))
+		case KeyService:
+			parts = append(parts, identity(part, subject.Service, subject.IP))
+		case KeyRoute:
+			parts = append(parts, "route="+subject.Route)
+		default:
+			parts = append(parts, "ip="+subject.IP)
+		}
+	}
+
+	return keyPrefix + p.Name + ":" + strings.Join(parts, ",")
+}
+
+func identity(part, value, ip string) string {
+	if value == "" {
+		return "ip=" + ip
+	}
+	return part + "=" + value
+}
+
+// Header is the RateLimit-Policy value, the limit and its window in
+// seconds.
+func (p *Policy) Header() string {
+	return fmt.Sprintf("%d;w=%d", p.Rule.Limit, int(p.Rule.Window.Seconds()))
+}
+
+// Headers are the RateLimit-* response headers for result, with
+// Retry-After when the request was denied.
+func (p *Policy) Headers(result *Result) map[string]string {
+	headers := map[string]string{
+		constants.RateLimitLimit:     strconv.Itoa(result.Limit),
+		constants.RateLimitRemaining: strconv.Itoa(result.Remaining),
+		constants.RateLimitReset:     strconv.Itoa(seconds(result.Reset)),
+		constants.RateLimitPolicy:    p.Header(),
+	}
+	if !result.Allowed {
+		headers[constants.RetryAfter] = strconv.Itoa(max(1, seconds(result.RetryAfter)))
+	}
+
+	return headers
+}
+
+func seconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/common/redis"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps counters in Redis, so every instance pointing at the
+// same server shares one budget.
+type RedisStore struct {
+	client *goredis.Client
+}
+
+// NewRedisStore connects to rawURL, see redis.NewClient for the accepted
+// forms.
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	client, err := redis.NewClient(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisStore{client: client}, nil
+}
+
+func (s *RedisStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var count *goredis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		count = pipe.Incr(ctx, key)
+		pipe.PExpire(ctx, key, atLeastMillisecond(ttl))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count.Val(), nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, key string) (int64, error) {
+	value, err := s.client.Get(ctx, key).Int64()
+	if errors.Is(err, goredis.Nil) {
+		return 0, nil
+	}
+
+	return value, err
+}
+
+// CompareAndSwap watches key, so the write is dropped when another
+// instance changed it between the read and EXEC.
+func (s *RedisStore) CompareAndSwap(ctx context.Context, key string, old, next int64, ttl time.Duration) (bool, error) {
+	swapped := false
+	err := s.client.Watch(ctx, func(tx *goredis.Tx) error {
+		current, err := tx.Get(ctx, key).Int64()
+		if err != nil && !errors.Is(err, goredis.Nil) {
+			return err
+		}
+		if current != old {
+			return nil
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+			pipe.Set(ctx, key, next, atLeastMillisecond(ttl))
+			return nil
+		})
+		swapped = err == nil
+		return err
+	}, key)
+	if errors.Is(err, goredis.TxFailedErr) {
+		return false, nil
+	}
+
+	return swapped, err
+}
+
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+// atLeastMillisecond keeps ttl from rounding down to zero, which PX and
+// PEXPIRE reject.
+func atLeastMillisecond(ttl time.Duration) time.Duration {
+	if ttl < time.Millisecond {
+		return time.Millisecond
+	}
+	return ttl
+}
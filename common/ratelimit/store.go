@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"user-service/config"
+)
+
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// IStore holds the counters behind the limiters. Every operation is atomic
+// so instances sharing a store never lose an update.
+type IStore interface {
+	// Increment adds one to key and returns the new value. ttl is applied
+	// from the increment on.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Get returns the value of key, 0 when it is not set.
+	Get(ctx context.Context, key string) (int64, error)
+	// CompareAndSwap sets key to next, expiring after ttl, only when it
+	// still holds old, 0 standing for not set.
+	CompareAndSwap(ctx context.Context, key string, old, next int64, ttl time.Duration) (bool, error)
+	Close() error
+}
+
+// StoreFactory builds a store from its configuration.
+type StoreFactory func(config.RateLimit) (IStore, error)
+
+var (
+	storesMu sync.RWMutex
+	stores   = map[string]StoreFactory{
+		StoreMemory: func(config.RateLimit) (IStore, error) { return NewMemoryStore(), nil },
+		StoreRedis:  func(cfg config.RateLimit) (IStore, error) { return NewRedisStore(cfg.URL) },
+	}
+)
+
+// RegisterStore makes a store available to NewStore.
+func RegisterStore(name string, factory StoreFactory) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+	stores[name] = factory
+}
+
+// NewStore returns the store for cfg.Store, the in-memory one when none is
+// configured.
+func NewStore(cfg config.RateLimit) (IStore, error) {
+	name := cfg.Store
+	if name == "" {
+		name = StoreMemory
+	}
+
+	storesMu.RLock()
+	factory, ok := stores[name]
+	storesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("rate limit store %q is not available in this build", name)
+	}
+
+	return factory(cfg)
+}
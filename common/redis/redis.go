@@ -0,0 +1,37 @@
+// Package redis opens the go-redis client the cache and the rate limiter
+// share the connection settings of.
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// NewClient connects to rawURL, either redis://[:password@]host:port[/db]
+// or a bare host:port, and checks the server answers.
+func NewClient(rawURL string) (*goredis.Client, error) {
+	options := &goredis.Options{Addr: rawURL}
+	if strings.Contains(rawURL, "://") {
+		var err error
+		options, err = goredis.ParseURL(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse redis url: %w", err)
+		}
+	}
+
+	if options.Addr == "" {
+		return nil, errors.New("redis address is empty")
+	}
+
+	client := goredis.NewClient(options)
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
@@ -0,0 +1,90 @@
+// Package shutdown holds the pieces the serve command stops the service
+// with, in the order it needs them.
+package shutdown
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// Workers runs the background loops of the service so they can be stopped
+// together on shutdown.
+type Workers struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWorkers() *Workers {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Workers{ctx: ctx, cancel: cancel}
+}
+
+func (w *Workers) Go(run func(context.Context)) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		run(w.ctx)
+	}()
+}
+
+// Stop cancels the workers and waits for them to return, or for ctx.
+func (w *Workers) Stop(ctx context.Context) error {
+	w.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// GRPCServer is the part of *grpc.Server StopGRPC needs.
+type GRPCServer interface {
+	GracefulStop()
+	Stop()
+}
+
+var _ GRPCServer = (*grpc.Server)(nil)
+
+// StopGRPC lets in-flight calls finish and cuts the rest off once ctx is
+// done.
+func StopGRPC(ctx context.Context, server GRPCServer) {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logrus.Warn("grpc calls still running at the shutdown deadline, stopping them")
+		server.Stop()
+	}
+}
+
+// Closer is something released last on shutdown, in the order given.
+type Closer struct {
+	Name  string
+	Close func() error
+}
+
+// CloseAll closes every closer, one failing does not keep the rest open.
+func CloseAll(closers []Closer) {
+	for _, item := range closers {
+		if err := item.Close(); err != nil {
+			logrus.Errorf("failed to close %s: %v", item.Name, err)
+		}
+	}
+}
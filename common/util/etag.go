@@ -0,0 +1,42 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	errConstant "user-service/constants/error"
+)
+
+func FormatETag(version uint) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// ParseIfMatch reads the expected version out of an If-Match header. The
+// wildcard returns zero, meaning any stored version is accepted. If-Match
+// compares strongly (RFC 9110 13.1.1), so weak tags never match, and
+// neither does a tag this service could not have issued.
+func ParseIfMatch(header string) (uint, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0, errConstant.ErrPreconditionRequired
+	}
+
+	if header == "*" {
+		return 0, nil
+	}
+
+	// a user resource has a single current tag, the first one of ours wins
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") || len(tag) < 2 || !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+
+		version, err := strconv.ParseUint(tag[1:len(tag)-1], 10, 64)
+		if err == nil && version != 0 {
+			return uint(version), nil
+		}
+	}
+
+	return 0, errConstant.ErrConflict
+}
@@ -0,0 +1,74 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var ErrInvalidMergePatch = errors.New("request body is not a valid merge patch document")
+
+type MergePatchError struct {
+	Field   string
+	Message string
+}
+
+func (e *MergePatchError) Error() string {
+	return e.Message
+}
+
+// BindMergePatch decodes a JSON Merge Patch (RFC 7396) document into dest,
+// which must point to a struct whose patchable fields are pointers. Only the
+// json names listed in allowed are accepted and a null value is rejected,
+// since none of the fields can be removed. It returns the struct field names
+// present in the document so they can be validated one by one.
+func BindMergePatch(body []byte, dest interface{}, allowed []string) ([]string, error) {
+	var document map[string]json.RawMessage
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	err := decoder.Decode(&document)
+	if err != nil || document == nil {
+		return nil, ErrInvalidMergePatch
+	}
+
+	value := reflect.ValueOf(dest).Elem()
+	fieldByJSON := make(map[string]int)
+	for i := 0; i < value.NumField(); i++ {
+		name := strings.Split(value.Type().Field(i).Tag.Get("json"), ",")[0]
+		if name != "" && name != "-" {
+			fieldByJSON[name] = i
+		}
+	}
+
+	isAllowed := make(map[string]bool)
+	for _, name := range allowed {
+		isAllowed[name] = true
+	}
+
+	fields := make([]string, 0, len(document))
+	for name, raw := range document {
+		index, ok := fieldByJSON[name]
+		if !ok || !isAllowed[name] {
+			return nil, &MergePatchError{Field: name, Message: fmt.Sprintf("%s is not a known field", name)}
+		}
+
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			return nil, &MergePatchError{Field: name, Message: fmt.Sprintf("%s can not be removed", name)}
+		}
+
+		field := value.Field(index)
+		target := reflect.New(field.Type())
+		err = json.Unmarshal(raw, target.Interface())
+		if err != nil {
+			return nil, &MergePatchError{Field: name, Message: fmt.Sprintf("%s has an invalid type", name)}
+		}
+
+		field.Set(target.Elem())
+		fields = append(fields, value.Type().Field(index).Name)
+	}
+
+	return fields, nil
+}
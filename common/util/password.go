@@ -0,0 +1,34 @@
+package util
+
+import (
+	"unicode"
+	errConstant "user-service/constants/error"
+)
+
+const defaultPasswordMinLength = 8
+
+// ValidatePasswordPolicy requires at least minLength characters with an
+// upper case letter, a lower case letter and a digit.
+func ValidatePasswordPolicy(password string, minLength int) error {
+	if minLength == 0 {
+		minLength = defaultPasswordMinLength
+	}
+
+	var hasUpper, hasLower, hasDigit bool
+	for _, char := range password {
+		switch {
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		}
+	}
+
+	if len([]rune(password)) < minLength || !hasUpper || !hasLower || !hasDigit {
+		return errConstant.ErrPasswordPolicy
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package util
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// GenerateToken returns a random url-safe token together with the SHA-256
+// hash that should be stored in its place.
+func GenerateToken() (token string, hash string, err error) {
+	raw := make([]byte, 32)
+	_, err = rand.Read(raw)
+	if err != nil {
+		return "", "", err
+	}
+
+	token = hex.EncodeToString(raw)
+	return token, HashToken(token), nil
+}
+
+// HashToken hashes a token received from a client for lookup.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the X-Webhook-Signature value for body, in the form
+// t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">. Binding the
+// timestamp into the MAC lets receivers reject replayed requests.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", unix, mac(secret, unix, body))
+}
+
+// Verify checks a signature produced by Sign and that it is not older than
+// tolerance. A zero tolerance skips the age check.
+func Verify(secret, signature string, body []byte, tolerance time.Duration) error {
+	var unix, digest string
+	for _, part := range strings.Split(signature, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			digest = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || digest == "" {
+		return ErrInvalidSignature
+	}
+
+	if tolerance > 0 && time.Since(time.Unix(seconds, 0)) > tolerance {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(digest), []byte(mac(secret, unix, body))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func mac(secret, unix string, body []byte) string {
+	hash := hmac.New(sha256.New, []byte(secret))
+	hash.Write([]byte(unix))
+	hash.Write([]byte("."))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
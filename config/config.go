@@ -11,6 +11,7 @@ var Config AppConfig
 
 type AppConfig struct {
 	Port                  int      `json:"port"`
+	GRPCPort              int      `json:"grpcPort"`
 	AppName               string   `json:"appName"`
 	AppEnv                string   `json:"appEnv"`
 	SignatureKey          string   `json:"signatureKey"`
@@ -19,6 +20,139 @@ type AppConfig struct {
 	RateLimiterTimeSecond int      `json:"rateLimiterTimeSecond"`
 	JwtSecretKey          string   `json:"jwtSecretKey"`
 	JwtExpirationTime     int      `json:"jwtExpirationTime"`
+	JwtPrivateKey         string   `json:"jwtPrivateKey"`
+	JwtKeyID              string   `json:"jwtKeyId"`
+	AuditSigningKey       string   `json:"auditSigningKey"`
+
+	// JwtPreviousPublicKey is the public half of the key jwtPrivateKey
+	// replaced. Tokens it signed stay valid and it stays in the JWKS under
+	// JwtPreviousKeyID, clear it once they have expired.
+	JwtPreviousPublicKey string `json:"jwtPreviousPublicKey"`
+	JwtPreviousKeyID     string `json:"jwtPreviousKeyId"`
+	// JwtAcceptHS256 keeps accepting tokens signed with jwtSecretKey after
+	// an RSA key is configured, while the tokens issued before the switch
+	// expire. Turn it off once they have.
+	JwtAcceptHS256 bool `json:"jwtAcceptHs256"`
+
+	ImpersonationExpirationTime int                 `json:"impersonationExpirationTime"`
+	UserPermissions             map[string][]string `json:"userPermissions"`
+	PasswordMinLength           int                 `json:"passwordMinLength"`
+	Mail                        Mail                `json:"mail"`
+	AppURL                      string              `json:"appUrl"`
+	EmailChangeExpirationTime   int                 `json:"emailChangeExpirationTime"`
+	InternalServices            []string            `json:"internalServices"`
+	BatchLookupMaxSize          int                 `json:"batchLookupMaxSize"`
+	Broker                      Broker              `json:"broker"`
+	Outbox                      Outbox              `json:"outbox"`
+	Webhook                     Webhook             `json:"webhook"`
+	Cache                       Cache               `json:"cache"`
+	RateLimit                   RateLimit           `json:"rateLimit"`
+	Server                      Server              `json:"server"`
+	Health                      Health              `json:"health"`
+
+	// APIKeyMaxSkewSecond is how far the x-request-at of a service api key
+	// may be from now, five minutes when unset.
+	APIKeyMaxSkewSecond int `json:"apiKeyMaxSkewSecond"`
+}
+
+// Health configures the /readyz checks.
+type Health struct {
+	// TimeoutSecond bounds each check, two seconds when unset.
+	TimeoutSecond int `json:"timeoutSecond"`
+	// Critical overrides whether a failing component makes the service
+	// unready, by component name.
+	Critical map[string]bool `json:"critical"`
+}
+
+// Server holds the timeouts of the HTTP server and how long a shutdown may
+// take. Unset values use the defaults in cmd.
+type Server struct {
+	ReadTimeoutSecond       int `json:"readTimeoutSecond"`
+	ReadHeaderTimeoutSecond int `json:"readHeaderTimeoutSecond"`
+	WriteTimeoutSecond      int `json:"writeTimeoutSecond"`
+	IdleTimeoutSecond       int `json:"idleTimeoutSecond"`
+	// ShutdownTimeoutSecond bounds draining requests and stopping the
+	// workers. Keep it below the termination grace period of the pod.
+	ShutdownTimeoutSecond int `json:"shutdownTimeoutSecond"`
+	// ShutdownDelaySecond keeps serving after SIGTERM with /readyz failing,
+	// so load balancers stop routing here before the listener closes.
+	ShutdownDelaySecond int `json:"shutdownDelaySecond"`
+}
+
+type Broker struct {
+	Driver        string `json:"driver"`
+	FilePath      string `json:"filePath"`
+	URL           string `json:"url"`
+	SubjectPrefix string `json:"subjectPrefix"`
+}
+
+type Outbox struct {
+	RelayIntervalSecond int `json:"relayIntervalSecond"`
+	BatchSize           int `json:"batchSize"`
+}
+
+type Webhook struct {
+	DeliveryIntervalSecond int `json:"deliveryIntervalSecond"`
+	BatchSize              int `json:"batchSize"`
+	MaxAttempts            int `json:"maxAttempts"`
+	TimeoutSecond          int `json:"timeoutSecond"`
+	// AllowPrivateTargets lets webhooks reach loopback, link-local and
+	// private addresses. Only turn it on for local development.
+	AllowPrivateTargets bool `json:"allowPrivateTargets"`
+}
+
+type Cache struct {
+	// Driver is memory, redis or none. Use redis when more than one
+	// instance runs, the memory cache only sees its own invalidations.
+	Driver            string `json:"driver"`
+	URL               string `json:"url"`
+	Size              int    `json:"size"`
+	TTLSecond         int    `json:"ttlSecond"`
+	NegativeTTLSecond int    `json:"negativeTtlSecond"`
+}
+
+type RateLimit struct {
+	// Store is memory or redis. With redis every instance draws from the
+	// same budget.
+	Store string `json:"store"`
+	URL   string `json:"url"`
+	// Default applies to every request. Its limit and window fall back to
+	// rateLimiterMaxRequest and rateLimiterTimeSecond.
+	Default RateLimitPolicy `json:"default"`
+	// Policies are the named limits routes apply on top of the default.
+	// They are read again on SIGHUP.
+	Policies map[string]RateLimitPolicy `json:"policies"`
+}
+
+type RateLimitPolicy struct {
+	// Algorithm is sliding_window or token_bucket.
+	Algorithm    string `json:"algorithm"`
+	Limit        int    `json:"limit"`
+	WindowSecond int    `json:"windowSecond"`
+	// Burst is how many requests a full token bucket allows at once,
+	// limit when unset.
+	Burst int `json:"burst"`
+	// KeyBy picks what shares a budget, any of ip, user, service and
+	// route. A request without a user or service falls back to its ip.
+	KeyBy []string `json:"keyBy"`
+	// ExemptServices are service clients, checked by their api key, the
+	// policy does not apply to.
+	ExemptServices []string `json:"exemptServices"`
+	// ExemptIPs are addresses or CIDR ranges the policy does not apply to.
+	ExemptIPs []string `json:"exemptIps"`
+}
+
+type Mail struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+	// QueueSize bounds the mails waiting to be sent, further mails are
+	// dropped. Workers send them, each mail within TimeoutSecond.
+	QueueSize     int `json:"queueSize"`
+	Workers       int `json:"workers"`
+	TimeoutSecond int `json:"timeoutSecond"`
 }
 
 type Database struct {
@@ -31,16 +165,34 @@ type Database struct {
 	MaxLifetimeConnection int    `json:"maxLifetimeConnection"`
 	MaxIdleConnection     int    `json:"maxIdleConnection"`
 	MaxIdleTime           int    `json:"maxIdleTime"`
+	// Replicas are postgres:// DSNs of read replicas. Lookups are spread
+	// over the ones passing their health check.
+	Replicas                 []string `json:"replicas"`
+	ReplicaHealthCheckSecond int      `json:"replicaHealthCheckSecond"`
 }
 
 func Init() {
-	err := util.BindFromJSON(&Config, "config.json", ".")
+	cfg, err := Load()
+	if err != nil {
+		panic(err)
+	}
+
+	Config = cfg
+}
+
+// Load reads the configuration from config.json, or from consul when there
+// is no such file, without touching Config.
+func Load() (AppConfig, error) {
+	var cfg AppConfig
+
+	err := util.BindFromJSON(&cfg, "config.json", ".")
 	if err != nil {
 		logrus.Infof("failed to bind config: %v", err)
-		err = util.BindFromConsul(&Config, os.Getenv("CONSUL_HTTP_URL"), os.Getenv("CONSUL_HTTP_PATH"))
+		err = util.BindFromConsul(&cfg, os.Getenv("CONSUL_HTTP_URL"), os.Getenv("CONSUL_HTTP_PATH"))
 		if err != nil {
-			panic(err)
+			return AppConfig{}, err
 		}
-
 	}
+
+	return cfg, nil
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/url"
 	"time"
+	"user-service/database/resolver"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
@@ -41,3 +42,37 @@ func InitDatabase() (*gorm.DB, error) {
 	return db, nil
 
 }
+
+// InitReplicas opens the configured read replicas. They are not pinged here,
+// a replica that is down at startup is kept out of rotation by the resolver
+// until it answers.
+func InitReplicas() ([]resolver.Replica, error) {
+	config := Config
+	replicas := make([]resolver.Replica, 0, len(config.Database.Replicas))
+	for i, dsn := range config.Database.Replicas {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{DisableAutomaticPing: true})
+		if err != nil {
+			logrus.Errorf("failed to open replica %d: %v", i, err)
+			return nil, err
+		}
+
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+
+		sqlDB.SetMaxOpenConns(config.Database.MaxOpenConnection)
+		sqlDB.SetMaxIdleConns(config.Database.MaxIdleConnection)
+		sqlDB.SetConnMaxLifetime(time.Duration(config.Database.MaxLifetimeConnection) * time.Second)
+		sqlDB.SetConnMaxIdleTime(time.Duration(config.Database.MaxIdleConnection) * time.Second)
+
+		name := fmt.Sprintf("replica-%d", i)
+		if parsed, err := url.Parse(dsn); err == nil && parsed.Host != "" {
+			name = parsed.Host
+		}
+
+		replicas = append(replicas, resolver.Replica{Name: name, DB: db})
+	}
+
+	return replicas, nil
+}
@@ -0,0 +1,20 @@
+package constants
+
+const (
+	AuditEventRegister          = "user.registered"
+	AuditEventLoginSuccess      = "auth.login_succeeded"
+	AuditEventLoginFailure      = "auth.login_failed"
+	AuditEventProfileUpdate     = "user.profile_updated"
+	AuditEventRoleChange        = "user.role_changed"
+	AuditEventStatusChange      = "user.status_changed"
+	AuditEventAdminAction       = "admin.action"
+	AuditEventImpersonation     = "admin.impersonation_started"
+	AuditEventImpersonationStop = "admin.impersonation_stopped"
+	AuditEventPasswordChange    = "user.password_changed"
+
+	AuditEventEmailChangeRequest = "user.email_change_requested"
+	AuditEventEmailChange        = "user.email_changed"
+	AuditEventEmailChangeCancel  = "user.email_change_cancelled"
+)
+
+const AuditRedacted = "[REDACTED]"
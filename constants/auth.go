@@ -3,4 +3,15 @@ package constants
 const (
 	UserLogin = "user_login"
 	Token     = "token"
+	RequestID = "request_id"
+	ClientIP  = "client_ip"
+	UserAgent = "user_agent"
+
+	Impersonator = "impersonator"
+	SessionID    = "session_id"
+	ServiceName  = "service_name"
+	AdminAction  = "admin_action"
+
+	// ReadPrimary makes the reads of a request skip the replicas.
+	ReadPrimary = "read_primary"
 )
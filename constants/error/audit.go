@@ -0,0 +1,15 @@
+package error
+
+import "errors"
+
+var (
+	ErrAuditLogImmutable = errors.New("audit log is immutable")
+	ErrAuditLogNotFound  = errors.New("audit log not found")
+	ErrAuditChainBroken  = errors.New("audit chain is broken")
+)
+
+var AuditErrors = []error{
+	ErrAuditLogImmutable,
+	ErrAuditLogNotFound,
+	ErrAuditChainBroken,
+}
@@ -0,0 +1,15 @@
+package error
+
+import "errors"
+
+var (
+	ErrEmailChangeNotFound = errors.New("email change request not found")
+	ErrEmailChangeExpired  = errors.New("email change request has expired")
+	ErrEmailChangeResolved = errors.New("email change request has already been resolved")
+)
+
+var EmailChangeErrors = []error{
+	ErrEmailChangeNotFound,
+	ErrEmailChangeExpired,
+	ErrEmailChangeResolved,
+}
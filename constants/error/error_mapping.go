@@ -1,10 +1,20 @@
 package error
 
-func ErrMapping(err error) bool {
+// AllErrors returns every error whose message is shown to callers as it is.
+func AllErrors() []error {
 	allErrors := make([]error, 0)
-	allErrors = append(append(GeneralErrors[:], UserErrors[:]...))
+	allErrors = append(allErrors, GeneralErrors...)
+	allErrors = append(allErrors, UserErrors...)
+	allErrors = append(allErrors, AuditErrors...)
+	allErrors = append(allErrors, SessionErrors...)
+	allErrors = append(allErrors, EmailChangeErrors...)
+	allErrors = append(allErrors, WebhookErrors...)
+
+	return allErrors
+}
 
-	for _, item := range allErrors {
+func ErrMapping(err error) bool {
+	for _, item := range AllErrors() {
 		if err.Error() == item.Error() {
 			return true
 		}
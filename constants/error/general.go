@@ -3,12 +3,15 @@ package error
 import "errors"
 
 var (
-	ErrInternalServerError = errors.New("internal server error")
-	ErrSqlError            = errors.New("database server failed to execute query")
-	ErrTooManyRequest      = errors.New("too many request")
-	ErrUnauthorized        = errors.New("unauthorized")
-	ErrInvalidToken        = errors.New("invalid token")
-	ErrForbiden            = errors.New("forbiden")
+	ErrInternalServerError  = errors.New("internal server error")
+	ErrSqlError             = errors.New("database server failed to execute query")
+	ErrTooManyRequest       = errors.New("too many request")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrInvalidToken         = errors.New("invalid token")
+	ErrForbiden             = errors.New("forbiden")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrConflict             = errors.New("resource has been modified by another request")
+	ErrPreconditionRequired = errors.New("if-match header is required")
 )
 
 var GeneralErrors = []error{
@@ -18,4 +21,7 @@ var GeneralErrors = []error{
 	ErrUnauthorized,
 	ErrInvalidToken,
 	ErrForbiden,
+	ErrUnsupportedMediaType,
+	ErrConflict,
+	ErrPreconditionRequired,
 }
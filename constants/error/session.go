@@ -0,0 +1,15 @@
+package error
+
+import "errors"
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session has been revoked")
+	ErrSessionExpired  = errors.New("session has expired")
+)
+
+var SessionErrors = []error{
+	ErrSessionNotFound,
+	ErrSessionRevoked,
+	ErrSessionExpired,
+}
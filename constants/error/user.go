@@ -3,11 +3,25 @@ package error
 import "errors"
 
 var (
-	ErrUserNotFound         = errors.New("user not found")
-	ErrPasswordIncorrect    = errors.New("password incorrect")
-	ErrUsernameExist        = errors.New("username already exist")
-	ErrEmailExist           = errors.New("email already exist")
-	ErrPasswordDoesNotMatch = errors.New("password does not match")
+	ErrUserNotFound            = errors.New("user not found")
+	ErrPasswordIncorrect       = errors.New("password incorrect")
+	ErrUsernameExist           = errors.New("username already exist")
+	ErrEmailExist              = errors.New("email already exist")
+	ErrPhoneNumberExist        = errors.New("phone number already exist")
+	ErrPasswordDoesNotMatch    = errors.New("password does not match")
+	ErrUserNotActive           = errors.New("user is not active")
+	ErrUserSuspended           = errors.New("user is suspended")
+	ErrUserBanned              = errors.New("user is banned")
+	ErrInvalidStatusExpiry     = errors.New("invalid status expiry")
+	ErrChangeOwnStatus         = errors.New("cannot change your own status")
+	ErrChangeOwnRole           = errors.New("cannot change your own role")
+	ErrRoleNotFound            = errors.New("role not found")
+	ErrImpersonationNotAllowed = errors.New("impersonation not allowed")
+	ErrImpersonateAdmin        = errors.New("not allowed to impersonate an admin")
+	ErrNotImpersonating        = errors.New("token is not an impersonation")
+	ErrPasswordPolicy          = errors.New("password must meet the minimum length and contain upper case, lower case letters and a digit")
+	ErrPasswordReused          = errors.New("new password must be different from the current password")
+	ErrBatchTooLarge           = errors.New("too many uuids in a single batch")
 )
 
 var UserErrors = []error{
@@ -15,5 +29,19 @@ var UserErrors = []error{
 	ErrPasswordIncorrect,
 	ErrUsernameExist,
 	ErrEmailExist,
+	ErrPhoneNumberExist,
 	ErrPasswordDoesNotMatch,
+	ErrUserNotActive,
+	ErrUserSuspended,
+	ErrUserBanned,
+	ErrInvalidStatusExpiry,
+	ErrChangeOwnStatus,
+	ErrChangeOwnRole,
+	ErrRoleNotFound,
+	ErrImpersonationNotAllowed,
+	ErrImpersonateAdmin,
+	ErrNotImpersonating,
+	ErrPasswordPolicy,
+	ErrPasswordReused,
+	ErrBatchTooLarge,
 }
@@ -0,0 +1,19 @@
+package error
+
+import "errors"
+
+var (
+	ErrWebhookNotFound         = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrWebhookInactive         = errors.New("webhook is not active")
+	ErrWebhookURLNotAllowed    = errors.New("webhook url must be a public http or https address")
+	// ErrWebhookLeaseLost stays inside the worker, it is never shown.
+	ErrWebhookLeaseLost = errors.New("webhook delivery lease lost")
+)
+
+var WebhookErrors = []error{
+	ErrWebhookNotFound,
+	ErrWebhookDeliveryNotFound,
+	ErrWebhookInactive,
+	ErrWebhookURLNotAllowed,
+}
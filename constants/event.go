@@ -0,0 +1,8 @@
+package constants
+
+const (
+	EventUserRegistered    = "user.registered"
+	EventUserUpdated       = "user.updated"
+	EventUserStatusChanged = "user.status_changed"
+	EventUserDeleted       = "user.deleted"
+)
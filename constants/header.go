@@ -6,5 +6,27 @@ var (
 	XServiceName  = textproto.CanonicalMIMEHeaderKey("x-service-name")
 	XApiKey       = textproto.CanonicalMIMEHeaderKey("x-api-key")
 	XRequestAt    = textproto.CanonicalMIMEHeaderKey("x-request-at")
+	XRequestID    = textproto.CanonicalMIMEHeaderKey("x-request-id")
 	Authorization = textproto.CanonicalMIMEHeaderKey("Authorization")
+	ETag          = textproto.CanonicalMIMEHeaderKey("ETag")
+	IfMatch       = textproto.CanonicalMIMEHeaderKey("If-Match")
+	XReadPrimary  = textproto.CanonicalMIMEHeaderKey("x-read-primary")
+
+	XUserUUID         = textproto.CanonicalMIMEHeaderKey("x-user-uuid")
+	XUserRole         = textproto.CanonicalMIMEHeaderKey("x-user-role")
+	XUserEmail        = textproto.CanonicalMIMEHeaderKey("x-user-email")
+	XImpersonatorUUID = textproto.CanonicalMIMEHeaderKey("x-impersonator-uuid")
+	XRequiredRoles    = textproto.CanonicalMIMEHeaderKey("x-required-roles")
+
+	XWebhookEvent     = textproto.CanonicalMIMEHeaderKey("x-webhook-event")
+	XWebhookDelivery  = textproto.CanonicalMIMEHeaderKey("x-webhook-delivery")
+	XWebhookSignature = textproto.CanonicalMIMEHeaderKey("x-webhook-signature")
+
+	RateLimitLimit     = textproto.CanonicalMIMEHeaderKey("ratelimit-limit")
+	RateLimitRemaining = textproto.CanonicalMIMEHeaderKey("ratelimit-remaining")
+	RateLimitReset     = textproto.CanonicalMIMEHeaderKey("ratelimit-reset")
+	RateLimitPolicy    = textproto.CanonicalMIMEHeaderKey("ratelimit-policy")
+	RetryAfter         = textproto.CanonicalMIMEHeaderKey("retry-after")
 )
+
+const ContentTypeMergePatch = "application/merge-patch+json"
@@ -0,0 +1,20 @@
+package constants
+
+const (
+	PermissionImpersonateAdmin = "impersonate:admin"
+)
+
+const (
+	ScopeProfileRead  = "profile:read"
+	ScopeProfileWrite = "profile:write"
+	ScopeUsersRead    = "users:read"
+	ScopeUsersWrite   = "users:write"
+	ScopeAuditRead    = "audit:read"
+)
+
+// RoleScopes are the scopes a token carries for the role of its user, on
+// top of the permissions granted to the user in config.
+var RoleScopes = map[string][]string{
+	AdminRole: {ScopeProfileRead, ScopeProfileWrite, ScopeUsersRead, ScopeUsersWrite, ScopeAuditRead},
+	UserRole:  {ScopeProfileRead, ScopeProfileWrite},
+}
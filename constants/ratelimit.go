@@ -0,0 +1,10 @@
+package constants
+
+// Rate limit policies routes apply on top of the default one. A policy
+// missing from rateLimit.policies leaves its routes at the default.
+const (
+	RateLimitLogin    = "login"
+	RateLimitRegister = "register"
+	RateLimitPassword = "password"
+	RateLimitEmail    = "email"
+)
@@ -4,3 +4,8 @@ const (
 	Admin = 1
 	User  = 2
 )
+
+const (
+	AdminRole = "admin"
+	UserRole  = "user"
+)
@@ -0,0 +1,9 @@
+package constants
+
+const (
+	UserStatusPending   = "pending"
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
+	UserStatusDeleted   = "deleted"
+)
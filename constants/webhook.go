@@ -0,0 +1,10 @@
+package constants
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+
+	// WebhookAllEvents subscribes an endpoint to every event type.
+	WebhookAllEvents = "*"
+)
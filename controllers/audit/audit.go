@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"net/http"
+	errWrap "user-service/common/error"
+	"user-service/common/response"
+	"user-service/domain/dto"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type AuditController struct {
+	services services.IServiceRegistery
+}
+
+type IAuditController interface {
+	FindAll(*gin.Context)
+}
+
+func NewAuditController(services services.IServiceRegistery) IAuditController {
+	return &AuditController{
+		services: services,
+	}
+}
+
+func (a *AuditController) FindAll(ctx *gin.Context) {
+	request := &dto.AuditFilterRequest{}
+
+	// bind data from query string
+	err := ctx.ShouldBindQuery(request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	// validate the data
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Error:   err,
+			Gin:     ctx,
+		})
+		return
+	}
+
+	logs, err := a.services.GetAudit().FindAll(ctx.Request.Context(), request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: logs,
+		Gin:  ctx,
+	})
+}
@@ -1,7 +1,9 @@
 package controllers
 
 import (
+	auditControllers "user-service/controllers/audit"
 	controllers "user-service/controllers/user"
+	webhookControllers "user-service/controllers/webhook"
 	"user-service/services"
 )
 
@@ -11,6 +13,8 @@ type Registry struct {
 
 type IControllerRegistry interface {
 	GetUserController() controllers.IUserController
+	GetAuditController() auditControllers.IAuditController
+	GetWebhookController() webhookControllers.IWebhookController
 }
 
 func NewControllerRegistry(service services.IServiceRegistery) IControllerRegistry {
@@ -20,3 +24,11 @@ func NewControllerRegistry(service services.IServiceRegistery) IControllerRegist
 func (u *Registry) GetUserController() controllers.IUserController {
 	return controllers.NewUserController(u.service)
 }
+
+func (u *Registry) GetAuditController() auditControllers.IAuditController {
+	return auditControllers.NewAuditController(u.service)
+}
+
+func (u *Registry) GetWebhookController() webhookControllers.IWebhookController {
+	return webhookControllers.NewWebhookController(u.service)
+}
@@ -1,9 +1,14 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 	errWrap "user-service/common/error"
 	"user-service/common/response"
+	"user-service/common/util"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
 	"user-service/domain/dto"
 	"user-service/services"
 
@@ -19,8 +24,21 @@ type IUserController interface {
 	Login(*gin.Context)
 	Register(*gin.Context)
 	Update(*gin.Context)
+	Patch(*gin.Context)
 	GetUserLogin(*gin.Context)
 	GetUserByUUID(*gin.Context)
+	UpdateStatus(*gin.Context)
+	UpdateRole(*gin.Context)
+	Impersonate(*gin.Context)
+	StopImpersonation(*gin.Context)
+	ChangePassword(*gin.Context)
+	ConfirmEmailChange(*gin.Context)
+	CancelEmailChange(*gin.Context)
+	BatchLookup(*gin.Context)
+	JWKS(*gin.Context)
+	Introspect(*gin.Context)
+	Verify(*gin.Context)
+	Delete(*gin.Context)
 }
 
 func NewUserController(services services.IServiceRegistery) IUserController {
@@ -60,7 +78,7 @@ func (u *UserController) Register(ctx *gin.Context) {
 	}
 
 	// pass data to login service
-	user, err := u.services.GetUser().Register(ctx, request)
+	user, err := u.services.GetUser().Register(ctx.Request.Context(), request)
 	if err != nil {
 		response.HttpResponse(response.ParamHTTPResp{
 			Code:  http.StatusBadRequest,
@@ -109,7 +127,7 @@ func (u *UserController) Login(ctx *gin.Context) {
 	}
 
 	// pass data to login service
-	user, err := u.services.GetUser().Login(ctx, request)
+	user, err := u.services.GetUser().Login(ctx.Request.Context(), request)
 	if err != nil {
 		response.HttpResponse(response.ParamHTTPResp{
 			Code:  http.StatusBadRequest,
@@ -132,8 +150,18 @@ func (u *UserController) Update(ctx *gin.Context) {
 	request := &dto.UpdateRequest{}
 	uuid := ctx.Param("uuid")
 
+	version, err := util.ParseIfMatch(ctx.GetHeader(constants.IfMatch))
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  updateErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
 	// bind data to json
-	err := ctx.ShouldBindJSON(request)
+	err = ctx.ShouldBindJSON(request)
 	if err != nil {
 		response.HttpResponse(response.ParamHTTPResp{
 			Code:  http.StatusBadRequest,
@@ -142,6 +170,7 @@ func (u *UserController) Update(ctx *gin.Context) {
 		})
 		return
 	}
+	request.Version = version
 
 	// validate the data
 	validate := validator.New()
@@ -160,10 +189,10 @@ func (u *UserController) Update(ctx *gin.Context) {
 	}
 
 	// pass data to login service
-	user, err := u.services.GetUser().Update(ctx, request, uuid)
+	user, err := u.services.GetUser().Update(ctx.Request.Context(), request, uuid)
 	if err != nil {
 		response.HttpResponse(response.ParamHTTPResp{
-			Code:  http.StatusBadRequest,
+			Code:  updateErrorCode(err),
 			Error: err,
 			Gin:   ctx,
 		})
@@ -171,6 +200,104 @@ func (u *UserController) Update(ctx *gin.Context) {
 	}
 
 	// return success response
+	ctx.Header(constants.ETag, util.FormatETag(user.Version))
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: user,
+		Gin:  ctx,
+	})
+}
+
+func (u *UserController) Patch(ctx *gin.Context) {
+	request := &dto.UpdateRequest{}
+	uuid := ctx.Param("uuid")
+
+	version, err := util.ParseIfMatch(ctx.GetHeader(constants.IfMatch))
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  updateErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+	request.Version = version
+
+	contentType := ctx.ContentType()
+	if contentType != constants.ContentTypeMergePatch && contentType != gin.MIMEJSON {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusUnsupportedMediaType,
+			Error: errConstant.ErrUnsupportedMediaType,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	body, err := ctx.GetRawData()
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	// bind merge patch document, only the fields present are set
+	fields, err := util.BindMergePatch(body, request, dto.PatchUserFields)
+	if err != nil {
+		var patchErr *util.MergePatchError
+		if errors.As(err, &patchErr) {
+			errMessage := http.StatusText(http.StatusUnprocessableEntity)
+			response.HttpResponse(response.ParamHTTPResp{
+				Code:    http.StatusUnprocessableEntity,
+				Message: &errMessage,
+				Data:    []errWrap.ValidationResponse{{Field: patchErr.Field, Message: patchErr.Message}},
+				Error:   err,
+				Gin:     ctx,
+			})
+			return
+		}
+
+		message := err.Error()
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusBadRequest,
+			Message: &message,
+			Error:   err,
+			Gin:     ctx,
+		})
+		return
+	}
+
+	// validate only the fields that are present
+	if len(fields) > 0 {
+		validate := validator.New()
+		err = validate.StructPartial(request, fields...)
+		if err != nil {
+			errMessage := http.StatusText(http.StatusUnprocessableEntity)
+			errResponse := errWrap.ErrValidationResponse(err)
+			response.HttpResponse(response.ParamHTTPResp{
+				Code:    http.StatusUnprocessableEntity,
+				Message: &errMessage,
+				Data:    errResponse,
+				Error:   err,
+				Gin:     ctx,
+			})
+			return
+		}
+	}
+
+	user, err := u.services.GetUser().Update(ctx.Request.Context(), request, uuid)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  updateErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	ctx.Header(constants.ETag, util.FormatETag(user.Version))
 	response.HttpResponse(response.ParamHTTPResp{
 		Code: http.StatusOK,
 		Data: user,
@@ -207,9 +334,498 @@ func (u *UserController) GetUserByUUID(ctx *gin.Context) {
 		return
 	}
 
+	ctx.Header(constants.ETag, util.FormatETag(user.Version))
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: user,
+		Gin:  ctx,
+	})
+}
+
+func (u *UserController) UpdateStatus(ctx *gin.Context) {
+	request := &dto.UpdateStatusRequest{}
+
+	// bind data to json
+	err := ctx.ShouldBindJSON(request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	// validate the data
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Error:   err,
+			Gin:     ctx,
+		})
+		return
+	}
+
+	status, err := u.services.GetUser().UpdateStatus(ctx.Request.Context(), request, ctx.Param("uuid"))
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: status,
+		Gin:  ctx,
+	})
+}
+
+func (u *UserController) Delete(ctx *gin.Context) {
+	request := &dto.UpdateStatusRequest{}
+
+	// reason is optional when deleting
+	if ctx.Request.ContentLength > 0 {
+		err := ctx.ShouldBindJSON(request)
+		if err != nil {
+			response.HttpResponse(response.ParamHTTPResp{
+				Code:  http.StatusBadRequest,
+				Error: err,
+				Gin:   ctx,
+			})
+			return
+		}
+	}
+
+	request.Status = constants.UserStatusDeleted
+	request.ExpiresAt = nil
+
+	status, err := u.services.GetUser().UpdateStatus(ctx.Request.Context(), request, ctx.Param("uuid"))
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: status,
+		Gin:  ctx,
+	})
+}
+
+func (u *UserController) UpdateRole(ctx *gin.Context) {
+	request := &dto.UpdateRoleRequest{}
+
+	// bind data to json
+	err := ctx.ShouldBindJSON(request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	// validate the data
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Error:   err,
+			Gin:     ctx,
+		})
+		return
+	}
+
+	user, err := u.services.GetUser().UpdateRole(ctx.Request.Context(), request, ctx.Param("uuid"))
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
 	response.HttpResponse(response.ParamHTTPResp{
 		Code: http.StatusOK,
 		Data: user,
 		Gin:  ctx,
 	})
 }
+
+func (u *UserController) Impersonate(ctx *gin.Context) {
+	result, err := u.services.GetUser().Impersonate(ctx.Request.Context(), ctx.Param("uuid"))
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, errConstant.ErrImpersonateAdmin) || errors.Is(err, errConstant.ErrImpersonationNotAllowed) ||
+			errors.Is(err, errConstant.ErrForbiden) {
+			code = http.StatusForbidden
+		}
+
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  code,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code:  http.StatusOK,
+		Data:  result,
+		Token: &result.Token,
+		Gin:   ctx,
+	})
+}
+
+func (u *UserController) StopImpersonation(ctx *gin.Context) {
+	err := u.services.GetUser().StopImpersonation(ctx.Request.Context())
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Gin:  ctx,
+	})
+}
+
+func updateErrorCode(err error) int {
+	switch {
+	case errors.Is(err, errConstant.ErrForbiden),
+		errors.Is(err, errConstant.ErrImpersonationNotAllowed):
+		return http.StatusForbidden
+	case errors.Is(err, errConstant.ErrConflict):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, errConstant.ErrPreconditionRequired):
+		return http.StatusPreconditionRequired
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (u *UserController) ChangePassword(ctx *gin.Context) {
+	request := &dto.ChangePasswordRequest{}
+
+	// bind data to json
+	err := ctx.ShouldBindJSON(request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	// validate the data
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Error:   err,
+			Gin:     ctx,
+		})
+		return
+	}
+
+	err = u.services.GetUser().ChangePassword(ctx.Request.Context(), request)
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, errConstant.ErrImpersonationNotAllowed) {
+			code = http.StatusForbidden
+		}
+
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  code,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Gin:  ctx,
+	})
+}
+
+func bindEmailChangeToken(ctx *gin.Context) (*dto.EmailChangeTokenRequest, bool) {
+	request := &dto.EmailChangeTokenRequest{}
+
+	// bind data to json
+	err := ctx.ShouldBindJSON(request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return nil, false
+	}
+
+	// validate the data
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Error:   err,
+			Gin:     ctx,
+		})
+		return nil, false
+	}
+
+	return request, true
+}
+
+func emailChangeErrorCode(err error) int {
+	switch {
+	case errors.Is(err, errConstant.ErrEmailChangeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errConstant.ErrEmailChangeExpired):
+		return http.StatusGone
+	case errors.Is(err, errConstant.ErrEmailChangeResolved),
+		errors.Is(err, errConstant.ErrEmailExist):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (u *UserController) ConfirmEmailChange(ctx *gin.Context) {
+	request, ok := bindEmailChangeToken(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := u.services.GetUser().ConfirmEmailChange(ctx.Request.Context(), request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  emailChangeErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: user,
+		Gin:  ctx,
+	})
+}
+
+func (u *UserController) CancelEmailChange(ctx *gin.Context) {
+	request, ok := bindEmailChangeToken(ctx)
+	if !ok {
+		return
+	}
+
+	err := u.services.GetUser().CancelEmailChange(ctx.Request.Context(), request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  emailChangeErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Gin:  ctx,
+	})
+}
+
+func (u *UserController) BatchLookup(ctx *gin.Context) {
+	request := &dto.BatchUserRequest{}
+
+	// bind data to json
+	err := ctx.ShouldBindJSON(request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	// validate the data
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Error:   err,
+			Gin:     ctx,
+		})
+		return
+	}
+
+	users, err := u.services.GetUser().FindByUUIDs(ctx.Request.Context(), request)
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, errConstant.ErrBatchTooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  code,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: users,
+		Gin:  ctx,
+	})
+}
+
+// JWKS publishes the token signing keys in the standard JWKS format rather
+// than the usual response envelope so off-the-shelf verifiers can read it.
+func (u *UserController) JWKS(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, u.services.GetUser().JWKS())
+}
+
+// Introspect answers in the RFC 7662 format instead of the response
+// envelope, so generic OAuth clients can consume it.
+func (u *UserController) Introspect(ctx *gin.Context) {
+	request := &dto.IntrospectRequest{}
+
+	// the RFC sends a form, json is accepted as well
+	err := ctx.ShouldBind(request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	// validate the data
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		errMessage := http.StatusText(http.StatusUnprocessableEntity)
+		errResponse := errWrap.ErrValidationResponse(err)
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:    http.StatusUnprocessableEntity,
+			Message: &errMessage,
+			Data:    errResponse,
+			Error:   err,
+			Gin:     ctx,
+		})
+		return
+	}
+
+	result, err := u.services.GetUser().Introspect(ctx.Request.Context(), request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusInternalServerError,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, result)
+}
+
+// Verify is the target of nginx auth_request and Traefik ForwardAuth. The
+// proxy may pass a comma separated X-Required-Roles header to require one of
+// those roles for the path it is guarding.
+func (u *UserController) Verify(ctx *gin.Context) {
+	userLogin := ctx.Request.Context().Value(constants.UserLogin).(*dto.UserResponse)
+
+	// the claims keep the role and email the token was issued with, answer
+	// with the current ones. The status check above left the user in the cache
+	user, err := u.services.GetUser().GetUserByUUID(ctx.Request.Context(), userLogin.UUID.String())
+	if err != nil {
+		code := http.StatusUnauthorized
+		if errors.Is(err, errConstant.ErrSqlError) {
+			code = http.StatusInternalServerError
+		}
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  code,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	if required := ctx.GetHeader(constants.XRequiredRoles); required != "" {
+		allowed := false
+		for _, role := range strings.Split(required, ",") {
+			if strings.EqualFold(strings.TrimSpace(role), user.Role) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			response.HttpResponse(response.ParamHTTPResp{
+				Code:  http.StatusForbidden,
+				Error: errConstant.ErrForbiden,
+				Gin:   ctx,
+			})
+			return
+		}
+	}
+
+	ctx.Header(constants.XUserUUID, user.UUID.String())
+	ctx.Header(constants.XUserRole, user.Role)
+	ctx.Header(constants.XUserEmail, user.Email)
+	if impersonator, ok := ctx.Request.Context().Value(constants.Impersonator).(*dto.UserResponse); ok {
+		ctx.Header(constants.XImpersonatorUUID, impersonator.UUID.String())
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Gin:  ctx,
+	})
+}
@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	errWrap "user-service/common/error"
+	"user-service/common/response"
+	errConstant "user-service/constants/error"
+	"user-service/domain/dto"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+type WebhookController struct {
+	services services.IServiceRegistery
+}
+
+type IWebhookController interface {
+	Create(*gin.Context)
+	FindAll(*gin.Context)
+	FindByUUID(*gin.Context)
+	Update(*gin.Context)
+	Delete(*gin.Context)
+	FindDeliveries(*gin.Context)
+	Replay(*gin.Context)
+}
+
+func NewWebhookController(services services.IServiceRegistery) IWebhookController {
+	return &WebhookController{
+		services: services,
+	}
+}
+
+func webhookErrorCode(err error) int {
+	switch {
+	case errors.Is(err, errConstant.ErrWebhookNotFound),
+		errors.Is(err, errConstant.ErrWebhookDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errConstant.ErrWebhookInactive):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func validationFailed(ctx *gin.Context, err error) {
+	errMessage := http.StatusText(http.StatusUnprocessableEntity)
+	errResponse := errWrap.ErrValidationResponse(err)
+	response.HttpResponse(response.ParamHTTPResp{
+		Code:    http.StatusUnprocessableEntity,
+		Message: &errMessage,
+		Data:    errResponse,
+		Error:   err,
+		Gin:     ctx,
+	})
+}
+
+func bindWebhookRequest(ctx *gin.Context) (*dto.WebhookRequest, bool) {
+	request := &dto.WebhookRequest{}
+
+	// bind data to json
+	err := ctx.ShouldBindJSON(request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return nil, false
+	}
+
+	// validate the data
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		validationFailed(ctx, err)
+		return nil, false
+	}
+
+	return request, true
+}
+
+func (w *WebhookController) Create(ctx *gin.Context) {
+	request, ok := bindWebhookRequest(ctx)
+	if !ok {
+		return
+	}
+
+	webhook, err := w.services.GetWebhook().Create(ctx.Request.Context(), request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  webhookErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusCreated,
+		Data: webhook,
+		Gin:  ctx,
+	})
+}
+
+func (w *WebhookController) FindAll(ctx *gin.Context) {
+	webhooks, err := w.services.GetWebhook().FindAll(ctx.Request.Context())
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  webhookErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: webhooks,
+		Gin:  ctx,
+	})
+}
+
+func (w *WebhookController) FindByUUID(ctx *gin.Context) {
+	webhook, err := w.services.GetWebhook().FindByUUID(ctx.Request.Context(), ctx.Param("uuid"))
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  webhookErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: webhook,
+		Gin:  ctx,
+	})
+}
+
+func (w *WebhookController) Update(ctx *gin.Context) {
+	request, ok := bindWebhookRequest(ctx)
+	if !ok {
+		return
+	}
+
+	webhook, err := w.services.GetWebhook().Update(ctx.Request.Context(), request, ctx.Param("uuid"))
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  webhookErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: webhook,
+		Gin:  ctx,
+	})
+}
+
+func (w *WebhookController) Delete(ctx *gin.Context) {
+	err := w.services.GetWebhook().Delete(ctx.Request.Context(), ctx.Param("uuid"))
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  webhookErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Gin:  ctx,
+	})
+}
+
+func (w *WebhookController) FindDeliveries(ctx *gin.Context) {
+	request := &dto.WebhookDeliveryFilterRequest{}
+
+	// bind data from query string
+	err := ctx.ShouldBindQuery(request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  http.StatusBadRequest,
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	// validate the data
+	validate := validator.New()
+	err = validate.Struct(request)
+	if err != nil {
+		validationFailed(ctx, err)
+		return
+	}
+
+	deliveries, err := w.services.GetWebhook().FindDeliveries(ctx.Request.Context(), ctx.Param("uuid"), request)
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  webhookErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusOK,
+		Data: deliveries,
+		Gin:  ctx,
+	})
+}
+
+func (w *WebhookController) Replay(ctx *gin.Context) {
+	delivery, err := w.services.GetWebhook().Replay(ctx.Request.Context(), ctx.Param("uuid"), ctx.Param("deliveryUuid"))
+	if err != nil {
+		response.HttpResponse(response.ParamHTTPResp{
+			Code:  webhookErrorCode(err),
+			Error: err,
+			Gin:   ctx,
+		})
+		return
+	}
+
+	response.HttpResponse(response.ParamHTTPResp{
+		Code: http.StatusAccepted,
+		Data: delivery,
+		Gin:  ctx,
+	})
+}
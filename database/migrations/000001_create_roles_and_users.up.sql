@@ -0,0 +1,45 @@
+-- Matches the schema AutoMigrate created, so databases set up before
+-- versioned migrations can adopt them.
+CREATE TABLE IF NOT EXISTS roles (
+    id         bigserial PRIMARY KEY,
+    code       text NOT NULL,
+    name       text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id                bigserial PRIMARY KEY,
+    uuid              uuid NOT NULL,
+    name              text NOT NULL,
+    username          text NOT NULL,
+    password          text NOT NULL,
+    email             text NOT NULL,
+    phone_number      text NOT NULL,
+    role_id           bigint NOT NULL,
+    status            varchar(20) NOT NULL DEFAULT 'active',
+    status_reason     text,
+    status_changed_by uuid,
+    status_changed_at timestamptz,
+    status_expires_at timestamptz,
+    version           bigint NOT NULL DEFAULT 1,
+    created_at        timestamptz,
+    updated_at        timestamptz,
+    deleted_at        timestamptz,
+    CONSTRAINT fk_users_role FOREIGN KEY (role_id) REFERENCES roles (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- Databases AutoMigrate set up before statuses, optimistic locking and soft
+-- deletes existed have a users table without these columns, which CREATE
+-- TABLE IF NOT EXISTS leaves alone. The defaults fill the existing rows in:
+-- every user is active and at its first version.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS status            varchar(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS status_reason     text,
+    ADD COLUMN IF NOT EXISTS status_changed_by uuid,
+    ADD COLUMN IF NOT EXISTS status_changed_at timestamptz,
+    ADD COLUMN IF NOT EXISTS status_expires_at timestamptz,
+    ADD COLUMN IF NOT EXISTS version           bigint NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS deleted_at        timestamptz;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
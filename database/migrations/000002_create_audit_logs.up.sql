@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id                bigserial PRIMARY KEY,
+    uuid              uuid NOT NULL,
+    event             varchar(50) NOT NULL,
+    actor_uuid        uuid,
+    target_uuid       uuid,
+    impersonator_uuid uuid,
+    ip_address        varchar(45),
+    user_agent        text,
+    request_id        varchar(64),
+    metadata          jsonb,
+    prev_hash         varchar(64),
+    hash              varchar(64),
+    created_at        timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_event ON audit_logs (event);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_uuid ON audit_logs (actor_uuid);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target_uuid ON audit_logs (target_uuid);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_impersonator_uuid ON audit_logs (impersonator_uuid);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_request_id ON audit_logs (request_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_hash ON audit_logs (hash);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id                bigserial PRIMARY KEY,
+    uuid              uuid NOT NULL,
+    user_id           bigint NOT NULL,
+    impersonator_uuid uuid,
+    ip_address        varchar(45),
+    user_agent        text,
+    expires_at        timestamptz NOT NULL,
+    revoked_at        timestamptz,
+    created_at        timestamptz,
+    updated_at        timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_uuid ON sessions (uuid);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
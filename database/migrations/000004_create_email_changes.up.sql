@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS email_changes (
+    id                 bigserial PRIMARY KEY,
+    uuid               uuid NOT NULL,
+    user_id            bigint NOT NULL,
+    old_email          varchar(255) NOT NULL,
+    new_email          varchar(255) NOT NULL,
+    confirm_token_hash varchar(64) NOT NULL,
+    cancel_token_hash  varchar(64) NOT NULL,
+    expires_at         timestamptz NOT NULL,
+    confirmed_at       timestamptz,
+    cancelled_at       timestamptz,
+    created_at         timestamptz,
+    updated_at         timestamptz,
+    CONSTRAINT fk_email_changes_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_changes_uuid ON email_changes (uuid);
+CREATE INDEX IF NOT EXISTS idx_email_changes_user_id ON email_changes (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_changes_confirm_token_hash ON email_changes (confirm_token_hash);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_changes_cancel_token_hash ON email_changes (cancel_token_hash);
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              bigserial PRIMARY KEY,
+    uuid            uuid NOT NULL,
+    topic           varchar(100) NOT NULL,
+    aggregate_uuid  uuid NOT NULL,
+    payload         jsonb NOT NULL,
+    attempts        bigint NOT NULL DEFAULT 0,
+    last_error      text,
+    next_attempt_at timestamptz NOT NULL,
+    published_at    timestamptz,
+    created_at      timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_uuid ON outbox_events (uuid);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_aggregate_uuid ON outbox_events (aggregate_uuid);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_next_attempt_at ON outbox_events (next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published_at ON outbox_events (published_at);
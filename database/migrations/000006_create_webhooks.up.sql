@@ -0,0 +1,39 @@
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id          bigserial PRIMARY KEY,
+    uuid        uuid NOT NULL,
+    url         varchar(2048) NOT NULL,
+    description varchar(255),
+    events      jsonb NOT NULL,
+    secret      varchar(128) NOT NULL,
+    active      boolean NOT NULL DEFAULT true,
+    created_by  uuid,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_subscriptions_uuid ON webhook_subscriptions (uuid);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               bigserial PRIMARY KEY,
+    uuid             uuid NOT NULL,
+    subscription_id  bigint NOT NULL,
+    event_uuid       uuid NOT NULL,
+    event            varchar(100) NOT NULL,
+    payload          jsonb NOT NULL,
+    status           varchar(20) NOT NULL,
+    attempts         bigint NOT NULL DEFAULT 0,
+    last_status_code bigint,
+    last_error       text,
+    next_attempt_at  timestamptz NOT NULL,
+    delivered_at     timestamptz,
+    created_at       timestamptz,
+    updated_at       timestamptz,
+    CONSTRAINT fk_webhook_deliveries_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_uuid ON webhook_deliveries (uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_event ON webhook_deliveries (subscription_id, event_uuid);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_users_phone_number;
+DROP INDEX IF EXISTS idx_users_email_lower;
+DROP INDEX IF EXISTS idx_users_username_lower;
+
+ALTER TABLE users
+    ALTER COLUMN name TYPE text,
+    ALTER COLUMN username TYPE text,
+    ALTER COLUMN password TYPE text,
+    ALTER COLUMN email TYPE text,
+    ALTER COLUMN phone_number TYPE text;
+
+ALTER TABLE roles
+    ALTER COLUMN code TYPE text,
+    ALTER COLUMN name TYPE text;
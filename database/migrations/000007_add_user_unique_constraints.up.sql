@@ -0,0 +1,48 @@
+-- AutoMigrate ignored the size of these columns because their tags lacked
+-- "type:", so they were created as text and may hold longer values than the
+-- API accepts now. The migration fails, and nothing of it is kept, while a
+-- value does not fit. The error names the column and the ids of the rows,
+-- shorten their values and run migrate up again.
+DO $$
+DECLARE
+    target   record;
+    too_long bigint;
+    ids      text;
+    problems text[] := '{}';
+BEGIN
+    FOR target IN
+        SELECT * FROM (VALUES
+            ('roles', 'code', 15),
+            ('roles', 'name', 20),
+            ('users', 'name', 100),
+            ('users', 'username', 20),
+            ('users', 'password', 250),
+            ('users', 'email', 100),
+            ('users', 'phone_number', 100)
+        ) AS sizes (table_name, column_name, size)
+    LOOP
+        EXECUTE format('SELECT count(*), string_agg(id::text, '', '') FILTER (WHERE rank <= 10) FROM '
+            '(SELECT id, row_number() OVER (ORDER BY id) AS rank FROM %I WHERE length(%I) > %s) AS long_values',
+            target.table_name, target.column_name, target.size) INTO too_long, ids;
+
+        IF too_long > 0 THEN
+            problems := problems || format('%s.%s has %s value(s) longer than %s characters (id %s)',
+                target.table_name, target.column_name, too_long, target.size, ids);
+        ELSE
+            EXECUTE format('ALTER TABLE %I ALTER COLUMN %I TYPE varchar(%s)',
+                target.table_name, target.column_name, target.size);
+        END IF;
+    END LOOP;
+
+    IF cardinality(problems) > 0 THEN
+        RAISE EXCEPTION 'values do not fit the column sizes: %', array_to_string(problems, '; ')
+            USING HINT = 'shorten the values and run migrate up again';
+    END IF;
+END $$;
+
+-- Creating the indexes fails while duplicates exist. Find them with
+--   SELECT lower(email), count(*) FROM users WHERE deleted_at IS NULL GROUP BY 1 HAVING count(*) > 1;
+-- and resolve them before migrating. Deleted users release their values.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower ON users (lower(username)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (lower(email)) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone_number ON users (phone_number) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_audit_logs_chain_id;
+
+ALTER TABLE audit_logs DROP COLUMN IF EXISTS chain;
@@ -0,0 +1,6 @@
+-- every audited entity gets a hash chain of its own so writers for
+-- different users stop queueing on one lock. Entries written before keep
+-- the empty chain and stay linked as they were.
+ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS chain varchar(64) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_chain_id ON audit_logs (chain, id);
@@ -0,0 +1,2 @@
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS leased_until;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS lease_id;
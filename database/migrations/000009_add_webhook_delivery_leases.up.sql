@@ -0,0 +1,5 @@
+-- the worker leases the deliveries it is about to send and posts them
+-- outside any transaction. A delivery whose lease ran out, because the
+-- worker died, is due again.
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS lease_id uuid;
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS leased_until timestamptz;
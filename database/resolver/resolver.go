@@ -0,0 +1,208 @@
+package resolver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"user-service/constants"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultHealthCheckInterval = 10 * time.Second
+	healthCheckTimeout         = 2 * time.Second
+)
+
+// Resolver sends the reads of the primary gorm.DB to its replicas. Writes,
+// reads inside a transaction, locking reads and reads whose context asks
+// for the primary stay on the primary.
+type Resolver struct {
+	primary  gorm.ConnPool
+	replicas []*replica
+	next     atomic.Uint64
+	interval time.Duration
+}
+
+type replica struct {
+	name    string
+	pool    *sql.DB
+	healthy atomic.Bool
+}
+
+// Replica is a read-only copy of the primary. Name labels it in logs and
+// must not contain credentials.
+type Replica struct {
+	Name string
+	DB   *gorm.DB
+}
+
+type IResolver interface {
+	CheckHealth(context.Context)
+	Run(context.Context)
+	Err() error
+	Close() error
+}
+
+// WithPrimary returns a context whose reads go to the primary, for paths
+// that read what they just wrote and cannot wait for replication.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, constants.ReadPrimary, true)
+}
+
+// PrimaryRequested reports whether ctx was marked by WithPrimary.
+func PrimaryRequested(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+
+	force, _ := ctx.Value(constants.ReadPrimary).(bool)
+	return force
+}
+
+// Register installs the resolver on db. A replica only receives reads once
+// it passed a health check, the first of which runs before Register
+// returns.
+func Register(db *gorm.DB, replicas []Replica, interval time.Duration) (IResolver, error) {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+
+	r := &Resolver{primary: db.ConnPool, interval: interval}
+	for _, item := range replicas {
+		pool, err := item.DB.DB()
+		if err != nil {
+			return nil, err
+		}
+		r.replicas = append(r.replicas, &replica{name: item.Name, pool: pool})
+	}
+
+	if len(r.replicas) == 0 {
+		return r, nil
+	}
+
+	r.CheckHealth(context.Background())
+
+	err := db.Callback().Query().Before("gorm:query").Register("resolver:query", r.route)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Callback().Row().Before("gorm:row").Register("resolver:row", r.route)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Resolver) route(db *gorm.DB) {
+	statement := db.Statement
+	if statement.ConnPool != r.primary || PrimaryRequested(statement.Context) {
+		return
+	}
+
+	// SELECT ... FOR UPDATE has to lock the rows on the primary
+	if _, ok := statement.Clauses["FOR"]; ok {
+		return
+	}
+
+	if pool := r.pick(); pool != nil {
+		statement.ConnPool = pool
+	}
+}
+
+// pick returns the next healthy replica in turn, or nil when none is.
+func (r *Resolver) pick() *sql.DB {
+	count := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < count; i++ {
+		candidate := r.replicas[(start+i)%count]
+		if candidate.healthy.Load() {
+			return candidate.pool
+		}
+	}
+
+	return nil
+}
+
+// CheckHealth pings every replica and takes the ones that fail out of the
+// rotation until they answer again.
+func (r *Resolver) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, item := range r.replicas {
+		wg.Add(1)
+		go func(item *replica) {
+			defer wg.Done()
+
+			pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			err := item.pool.PingContext(pingCtx)
+			healthy := err == nil
+			if item.healthy.Swap(healthy) != healthy {
+				if healthy {
+					logrus.Infof("replica %s is healthy, routing reads to it", item.name)
+				} else {
+					logrus.Warnf("replica %s failed its health check, reads go elsewhere: %v", item.name, err)
+				}
+			}
+		}(item)
+	}
+	wg.Wait()
+}
+
+// Run checks the replicas periodically until ctx is cancelled.
+func (r *Resolver) Run(ctx context.Context) {
+	if len(r.replicas) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.CheckHealth(ctx)
+		}
+	}
+}
+
+// Err names the replicas that failed their last health check, nil when
+// every one passed.
+func (r *Resolver) Err() error {
+	var down []string
+	for _, item := range r.replicas {
+		if !item.healthy.Load() {
+			down = append(down, item.name)
+		}
+	}
+	if len(down) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("unhealthy replicas: %s", strings.Join(down, ", "))
+}
+
+// Close takes every replica out of rotation and closes its pool. Reads
+// still running finish first, later ones go to the primary.
+func (r *Resolver) Close() error {
+	var errs []error
+	for _, item := range r.replicas {
+		item.healthy.Store(false)
+		if err := item.pool.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close replica %s: %w", item.name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditFilterRequest struct {
+	Event            string     `form:"event"`
+	ActorUUID        string     `form:"actorUuid" validate:"omitempty,uuid"`
+	TargetUUID       string     `form:"targetUuid" validate:"omitempty,uuid"`
+	ImpersonatorUUID string     `form:"impersonatorUuid" validate:"omitempty,uuid"`
+	RequestID        string     `form:"requestId"`
+	From             *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To               *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page             int        `form:"page" validate:"omitempty,min=1"`
+	Limit            int        `form:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+type AuditLogResponse struct {
+	UUID             uuid.UUID       `json:"uuid"`
+	Event            string          `json:"event"`
+	ActorUUID        *uuid.UUID      `json:"actorUuid,omitempty"`
+	TargetUUID       *uuid.UUID      `json:"targetUuid,omitempty"`
+	ImpersonatorUUID *uuid.UUID      `json:"impersonatorUuid,omitempty"`
+	IPAddress        string          `json:"ipAddress"`
+	UserAgent        string          `json:"userAgent"`
+	RequestID        string          `json:"requestId"`
+	Metadata         json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt        *time.Time      `json:"createdAt"`
+}
+
+type AuditListResponse struct {
+	Items []AuditLogResponse `json:"items"`
+	Page  int                `json:"page"`
+	Limit int                `json:"limit"`
+	Total int64              `json:"total"`
+}
+
+type AuditVerifyResult struct {
+	Valid      bool   `json:"valid"`
+	Checked    int64  `json:"checked"`
+	Unchained  int64  `json:"unchained"`
+	LastID     uint   `json:"lastId"`
+	LastHash   string `json:"lastHash"`
+	BrokenAtID uint   `json:"brokenAtId,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserEvent is the payload published for every user lifecycle event.
+// Consumers should drop events whose ID they already handled, since
+// delivery is at least once.
+type UserEvent struct {
+	ID         uuid.UUID     `json:"id"`
+	Type       string        `json:"type"`
+	OccurredAt time.Time     `json:"occurredAt"`
+	RequestID  string        `json:"requestId,omitempty"`
+	Data       UserEventData `json:"data"`
+}
+
+type UserEventData struct {
+	UUID        uuid.UUID              `json:"uuid"`
+	Name        string                 `json:"name"`
+	Username    string                 `json:"username"`
+	Email       string                 `json:"email"`
+	Role        string                 `json:"role,omitempty"`
+	PhoneNumber string                 `json:"phoneNumber"`
+	Status      string                 `json:"status"`
+	Changes     map[string]FieldChange `json:"changes,omitempty"`
+}
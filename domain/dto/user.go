@@ -1,6 +1,10 @@
 package dto
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type LoginRequest struct {
 	Username string `json:"username" validate:"required"`
@@ -14,6 +18,10 @@ type UserResponse struct {
 	Email       string    `json:"email"`
 	Role        string    `json:"role"`
 	PhoneNumber string    `json:"phoneNumber"`
+	Version     uint      `json:"-"`
+	// PendingEmail is only filled in by Update while a change of email
+	// waits for the new address to confirm it.
+	PendingEmail string `json:"pendingEmail,omitempty"`
 }
 
 type LoginResponse struct {
@@ -22,12 +30,12 @@ type LoginResponse struct {
 }
 
 type RegisterRequest struct {
-	Name            string `json:"name" validate:"required"`
-	Username        string `json:"username" validate:"required"`
-	Email           string `json:"email" validate:"required"`
+	Name            string `json:"name" validate:"required,max=100"`
+	Username        string `json:"username" validate:"required,max=20"`
+	Email           string `json:"email" validate:"required,max=100"`
 	Password        string `json:"password" validate:"required"`
 	ConfirmPassword string `json:"confirmPassword" validate:"required"`
-	PhoneNumber     string `json:"phoneNumber" validate:"required"`
+	PhoneNumber     string `json:"phoneNumber" validate:"required,max=100"`
 	RoleID          uint
 }
 
@@ -36,11 +44,94 @@ type RegisterResponse struct {
 }
 
 type UpdateRequest struct {
-	Name            string  `json:"name" validate:"required"`
-	Username        string  `json:"username" validate:"required"`
-	Email           string  `json:"email" validate:"required"`
-	Password        *string `json:"password,omitempty"`
-	ConfirmPassword *string `json:"confirmPassword,omitempty"`
-	PhoneNumber     string  `json:"phoneNumber" validate:"required"`
-	RoleID          uint
+	// required only refuses a nil pointer, min=1 refuses an empty value
+	Name        *string `json:"name" validate:"required,min=1,max=100"`
+	Username    *string `json:"username" validate:"required,min=1,max=20"`
+	Email       *string `json:"email" validate:"required,email,max=100"`
+	PhoneNumber *string `json:"phoneNumber" validate:"required,min=1,max=100"`
+	RoleID      uint
+	Version     uint `json:"-"`
+}
+
+// PatchUserFields are the fields of UpdateRequest a merge patch may carry.
+var PatchUserFields = []string{"name", "username", "email", "phoneNumber"}
+
+type UpdateStatusRequest struct {
+	Status    string     `json:"status" validate:"required,oneof=pending active suspended banned deleted"`
+	Reason    *string    `json:"reason,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	ChangedBy uuid.UUID  `json:"-"`
+}
+
+type UserStatusResponse struct {
+	UUID      uuid.UUID  `json:"uuid"`
+	Status    string     `json:"status"`
+	Reason    *string    `json:"reason,omitempty"`
+	ChangedBy *uuid.UUID `json:"changedBy,omitempty"`
+	ChangedAt *time.Time `json:"changedAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin user"`
+}
+
+type ImpersonateResponse struct {
+	User         UserResponse `json:"user"`
+	Impersonator UserResponse `json:"impersonator"`
+	Token        string       `json:"token"`
+	ExpiresAt    time.Time    `json:"expiresAt"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" validate:"required"`
+	NewPassword     string `json:"newPassword" validate:"required"`
+	ConfirmPassword string `json:"confirmPassword" validate:"required"`
+}
+
+type EmailChangeTokenRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+var BatchUserFields = []string{"uuid", "name", "username", "email", "role", "phoneNumber"}
+
+type BatchUserRequest struct {
+	UUIDs  []string `json:"uuids" validate:"required,min=1,dive,uuid"`
+	Fields []string `json:"fields" validate:"omitempty,dive,oneof=uuid name username email role phoneNumber"`
+}
+
+// BatchUserResponse holds the users found, limited to the requested fields,
+// and the uuids that did not match any user.
+type BatchUserResponse struct {
+	Users   []map[string]interface{} `json:"users"`
+	Missing []string                 `json:"missing"`
+}
+
+type IntrospectRequest struct {
+	Token         string `form:"token" json:"token" validate:"required"`
+	TokenTypeHint string `form:"token_type_hint" json:"token_type_hint"`
+}
+
+// IntrospectResponse follows RFC 7662. Only Active is set for a token that
+// is not active, so nothing leaks about it.
+type IntrospectResponse struct {
+	Active    bool               `json:"active"`
+	Scope     string             `json:"scope,omitempty"`
+	Username  string             `json:"username,omitempty"`
+	TokenType string             `json:"token_type,omitempty"`
+	Exp       int64              `json:"exp,omitempty"`
+	Iat       int64              `json:"iat,omitempty"`
+	Sub       string             `json:"sub,omitempty"`
+	Jti       string             `json:"jti,omitempty"`
+	Role      string             `json:"role,omitempty"`
+	Session   *IntrospectSession `json:"session,omitempty"`
+	// User is the user the token belongs to, for callers inside the service.
+	User *UserResponse `json:"-"`
+}
+
+type IntrospectSession struct {
+	ID           uuid.UUID  `json:"id"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	Impersonator *uuid.UUID `json:"impersonator,omitempty"`
 }
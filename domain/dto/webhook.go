@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type WebhookRequest struct {
+	URL         string   `json:"url" validate:"required,url,max=2048"`
+	Description string   `json:"description" validate:"max=255"`
+	Events      []string `json:"events" validate:"required,min=1,dive,oneof=* user.registered user.updated user.status_changed user.deleted"`
+	Active      *bool    `json:"active"`
+}
+
+type WebhookResponse struct {
+	UUID        uuid.UUID  `json:"uuid"`
+	URL         string     `json:"url"`
+	Description string     `json:"description"`
+	Events      []string   `json:"events"`
+	Active      bool       `json:"active"`
+	Secret      string     `json:"secret,omitempty"`
+	CreatedBy   *uuid.UUID `json:"createdBy,omitempty"`
+	CreatedAt   *time.Time `json:"createdAt"`
+	UpdatedAt   *time.Time `json:"updatedAt"`
+}
+
+type WebhookDeliveryFilterRequest struct {
+	Status string `form:"status" validate:"omitempty,oneof=pending delivered dead"`
+	Event  string `form:"event"`
+	Page   int    `form:"page" validate:"omitempty,min=1"`
+	Limit  int    `form:"limit" validate:"omitempty,min=1,max=100"`
+}
+
+type WebhookDeliveryResponse struct {
+	UUID           uuid.UUID       `json:"uuid"`
+	EventUUID      uuid.UUID       `json:"eventUuid"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode *int            `json:"lastStatusCode,omitempty"`
+	LastError      *string         `json:"lastError,omitempty"`
+	NextAttemptAt  time.Time       `json:"nextAttemptAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+	CreatedAt      *time.Time      `json:"createdAt"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Items []WebhookDeliveryResponse `json:"items"`
+	Page  int                       `json:"page"`
+	Limit int                       `json:"limit"`
+	Total int64                     `json:"total"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+	errConstant "user-service/constants/error"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditLog struct {
+	ID               uint            `gorm:"primaryKey;autoIncrement"`
+	UUID             uuid.UUID       `gorm:"type:uuid;not null"`
+	Event            string          `gorm:"type:varchar(50);not null;index"`
+	ActorUUID        *uuid.UUID      `gorm:"type:uuid;index"`
+	TargetUUID       *uuid.UUID      `gorm:"type:uuid;index"`
+	ImpersonatorUUID *uuid.UUID      `gorm:"type:uuid;index"`
+	IPAddress        string          `gorm:"type:varchar(45)"`
+	UserAgent        string          `gorm:"type:text"`
+	RequestID        string          `gorm:"type:varchar(64);index"`
+	Metadata         json.RawMessage `gorm:"type:jsonb"`
+	// Chain is the hash chain the entry links into, empty for entries
+	// written while there was a single chain for the whole log.
+	Chain     string     `gorm:"type:varchar(64)"`
+	PrevHash  string     `gorm:"type:varchar(64)"`
+	Hash      string     `gorm:"type:varchar(64);index"`
+	CreatedAt *time.Time `gorm:"index"`
+}
+
+// audit logs are append only, any attempt to change or remove a row through
+// gorm is rejected before it reaches the database
+func (a *AuditLog) BeforeUpdate(*gorm.DB) error {
+	return errConstant.ErrAuditLogImmutable
+}
+
+func (a *AuditLog) BeforeDelete(*gorm.DB) error {
+	return errConstant.ErrAuditLogImmutable
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChange is a pending change of a user's email address. Only the
+// SHA-256 hashes of the confirm and cancel tokens are stored.
+type EmailChange struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement"`
+	UUID             uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	UserID           uint      `gorm:"not null;index"`
+	OldEmail         string    `gorm:"type:varchar(255);not null"`
+	NewEmail         string    `gorm:"type:varchar(255);not null"`
+	ConfirmTokenHash string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	CancelTokenHash  string    `gorm:"type:varchar(64);not null;uniqueIndex"`
+	ExpiresAt        time.Time `gorm:"not null"`
+	ConfirmedAt      *time.Time
+	CancelledAt      *time.Time
+	CreatedAt        *time.Time
+	UpdatedAt        *time.Time
+	User             User `gorm:"foreignKey:UserID"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OutboxEvent is a domain event waiting to be published. It is written in
+// the same transaction as the change it describes, and the relay marks it
+// published once the broker accepted it.
+type OutboxEvent struct {
+	ID            uint            `gorm:"primaryKey;autoIncrement"`
+	UUID          uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex"`
+	Topic         string          `gorm:"type:varchar(100);not null"`
+	AggregateUUID uuid.UUID       `gorm:"type:uuid;not null;index"`
+	Payload       json.RawMessage `gorm:"type:jsonb;not null"`
+	Attempts      int             `gorm:"not null;default:0"`
+	LastError     *string         `gorm:"type:text"`
+	NextAttemptAt time.Time       `gorm:"not null;index"`
+	PublishedAt   *time.Time      `gorm:"index"`
+	CreatedAt     *time.Time
+}
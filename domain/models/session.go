@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	ID               uint       `gorm:"primaryKey;autoIncrement"`
+	UUID             uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex"`
+	UserID           uint       `gorm:"not null;index"`
+	ImpersonatorUUID *uuid.UUID `gorm:"type:uuid"`
+	IPAddress        string     `gorm:"type:varchar(45)"`
+	UserAgent        string     `gorm:"type:text"`
+	ExpiresAt        time.Time  `gorm:"not null"`
+	RevokedAt        *time.Time
+	CreatedAt        *time.Time
+	UpdatedAt        *time.Time
+}
@@ -4,18 +4,26 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type User struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement"`
-	UUID        uuid.UUID `gorm:"type:uuid;not null"`
-	Name        string    `gorm:"varchar(100);not null"`
-	Username    string    `gorm:"varchar(20);not null"`
-	Password    string    `gorm:"varchar(250);not null"`
-	Email       string    `gorm:"varchar(100);not null"`
-	PhoneNumber string    `gorm:"varchar(100);not null"`
-	RoleID      uint      `gorm:"type:uint;not null"`
-	CreatedAt   *time.Time
-	UpdatedAt   *time.Time
-	Role        Role `gorm:"foreignKet:role_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	ID              uint       `gorm:"primaryKey;autoIncrement"`
+	UUID            uuid.UUID  `gorm:"type:uuid;not null"`
+	Name            string     `gorm:"type:varchar(100);not null"`
+	Username        string     `gorm:"type:varchar(20);not null;uniqueIndex:idx_users_username_lower,expression:lower(username),where:deleted_at IS NULL"`
+	Password        string     `gorm:"type:varchar(250);not null"`
+	Email           string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_users_email_lower,expression:lower(email),where:deleted_at IS NULL"`
+	PhoneNumber     string     `gorm:"type:varchar(100);not null;uniqueIndex:idx_users_phone_number,where:deleted_at IS NULL"`
+	RoleID          uint       `gorm:"type:uint;not null"`
+	Status          string     `gorm:"type:varchar(20);not null;default:active"`
+	StatusReason    *string    `gorm:"type:text"`
+	StatusChangedBy *uuid.UUID `gorm:"type:uuid"`
+	StatusChangedAt *time.Time
+	StatusExpiresAt *time.Time
+	Version         uint `gorm:"not null;default:1"`
+	CreatedAt       *time.Time
+	UpdatedAt       *time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
+	Role            Role           `gorm:"foreignKet:role_id;references:id;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WebhookSubscription is an endpoint registered by an admin for partners
+// that cannot consume the broker. Events holds a JSON array of event types,
+// "*" matches every event.
+type WebhookSubscription struct {
+	ID          uint            `gorm:"primaryKey;autoIncrement"`
+	UUID        uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex"`
+	URL         string          `gorm:"type:varchar(2048);not null"`
+	Description string          `gorm:"type:varchar(255)"`
+	Events      json.RawMessage `gorm:"type:jsonb;not null"`
+	Secret      string          `gorm:"type:varchar(128);not null"`
+	Active      bool            `gorm:"not null;default:true"`
+	CreatedBy   *uuid.UUID      `gorm:"type:uuid"`
+	CreatedAt   *time.Time
+	UpdatedAt   *time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+// WebhookDelivery is one event to be sent to one subscription. A delivery
+// is created once per pair, however often the event is relayed. While a
+// worker sends it, LeaseID names the worker's batch, which owns it until
+// LeasedUntil.
+type WebhookDelivery struct {
+	ID             uint            `gorm:"primaryKey;autoIncrement"`
+	UUID           uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex"`
+	SubscriptionID uint            `gorm:"not null;uniqueIndex:idx_webhook_deliveries_subscription_event"`
+	EventUUID      uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_webhook_deliveries_subscription_event"`
+	Event          string          `gorm:"type:varchar(100);not null"`
+	Payload        json.RawMessage `gorm:"type:jsonb;not null"`
+	Status         string          `gorm:"type:varchar(20);not null;index"`
+	Attempts       int             `gorm:"not null;default:0"`
+	LastStatusCode *int            `gorm:"type:int"`
+	LastError      *string         `gorm:"type:text"`
+	NextAttemptAt  time.Time       `gorm:"not null;index"`
+	DeliveredAt    *time.Time
+	LeaseID        *uuid.UUID `gorm:"type:uuid"`
+	LeasedUntil    *time.Time
+	CreatedAt      *time.Time
+	UpdatedAt      *time.Time
+	Subscription   WebhookSubscription `gorm:"foreignKey:SubscriptionID"`
+}
@@ -3,20 +3,30 @@ package middlewares
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+	"user-service/common/auth"
+	"user-service/common/ratelimit"
 	"user-service/common/response"
 	"user-service/config"
 	"user-service/constants"
 	errConstants "user-service/constants/error"
+	"user-service/database/resolver"
+	"user-service/domain/dto"
+	serviceRegistry "user-service/services"
+	auditServices "user-service/services/audit"
 	services "user-service/services/user"
 
-	"github.com/didip/tollbooth"
-	"github.com/didip/tollbooth/limiter"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -36,20 +46,126 @@ func HandlePanic() gin.HandlerFunc {
 	}
 }
 
-func RateLimiter(lmt *limiter.Limiter) gin.HandlerFunc {
+func RequestMetadata() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		err := tollbooth.LimitByRequest(lmt, c.Writer, c.Request)
+		requestID := c.GetHeader(constants.XRequestID)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+
+		ctx := context.WithValue(c.Request.Context(), constants.RequestID, requestID)
+		ctx = context.WithValue(ctx, constants.ClientIP, c.ClientIP())
+		ctx = context.WithValue(ctx, constants.UserAgent, c.Request.UserAgent())
+		if strings.EqualFold(c.GetHeader(constants.XReadPrimary), "true") {
+			ctx = resolver.WithPrimary(ctx)
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Header(constants.XRequestID, requestID)
+		c.Next()
+	}
+}
+
+// AuditAdminAction audits every admin request. A service changing state
+// writes the entry in the transaction of its change, so nothing is committed
+// unaudited. Requests that change nothing, or fail, are recorded once the
+// handler returns.
+func AuditAdminAction(service serviceRegistry.IServiceRegistery) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var target *uuid.UUID
+		if targetUUID, err := uuid.Parse(c.Param("uuid")); err == nil {
+			target = &targetUUID
+		}
+
+		metadata := map[string]interface{}{
+			"method": c.Request.Method,
+			"path":   c.FullPath(),
+		}
+
+		ctx := auditServices.WithAdminAction(c.Request.Context(), target, metadata)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		// a failed request may have rolled the entry back with its change
+		if auditServices.AdminActionRecorded(ctx) && c.Writer.Status() < http.StatusBadRequest {
+			return
+		}
+
+		metadata["status"] = c.Writer.Status()
+		service.GetAudit().Record(c.Request.Context(),
+			auditServices.NewAuditLog(c.Request.Context(), constants.AuditEventAdminAction, nil, target, metadata))
+	}
+}
+
+// RateLimit counts requests against the policy called name and answers 429
+// once the budget of their key is spent. The policy is looked up on every
+// request, so a reload applies straight away. Exempt clients and a missing
+// policy are let through, and so is everything while the store fails: an
+// outage of the limiter should not take the service down with it.
+//
+// A route behind more than one policy reports the headers of the last.
+func RateLimit(policies *ratelimit.Policies, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy, ok := policies.Get(name)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		subject := rateLimitSubject(c, policy)
+		if policy.Exempt(subject) {
+			c.Next()
+			return
+		}
+
+		result, err := policy.Allow(c.Request.Context(), subject)
 		if err != nil {
+			logrus.Warnf("rate limit %s unavailable: %v", policy.Name, err)
+			c.Next()
+			return
+		}
+
+		for key, value := range policy.Headers(result) {
+			c.Header(key, value)
+		}
+
+		if !result.Allowed {
 			c.JSON(http.StatusTooManyRequests, response.Response{
 				Status:  constants.Error,
 				Message: errConstants.ErrTooManyRequest.Error(),
 			})
 			c.Abort()
+			return
 		}
 		c.Next()
 	}
 }
 
+// rateLimitSubject resolves the parts of the request policy needs. The user
+// and service only count once their token or api key checked out, so a
+// forged header cannot move a client into someone else's budget.
+func rateLimitSubject(c *gin.Context, policy *ratelimit.Policy) ratelimit.Subject {
+	subject := ratelimit.Subject{IP: c.ClientIP()}
+
+	if policy.Needs(ratelimit.KeyUser) && c.GetHeader(constants.Authorization) != "" {
+		if claims, err := ParseBearerToken(c.GetHeader(constants.Authorization)); err == nil && claims.User != nil {
+			subject.User = claims.User.UUID.String()
+		}
+	}
+
+	if policy.Needs(ratelimit.KeyService) && c.GetHeader(constants.XServiceName) != "" {
+		if validateAPIKey(c) == nil {
+			subject.Service = c.GetHeader(constants.XServiceName)
+		}
+	}
+
+	if policy.Needs(ratelimit.KeyRoute) {
+		subject.Route = c.Request.Method + " " + c.FullPath()
+	}
+
+	return subject
+}
+
 func extractBearerToken(token string) string {
 	arrayToken := strings.Split(token, " ")
 
@@ -68,23 +184,68 @@ func responseUnauthorized(c *gin.Context, message string) {
 	c.Abort()
 }
 
-func validateAPIKey(c *gin.Context) error {
-	apiKey := c.GetHeader(constants.XApiKey)
-	requestAt := c.GetHeader(constants.XRequestAt)
+func responseForbidden(c *gin.Context, message string) {
+	c.JSON(http.StatusForbidden, response.Response{
+		Status:  constants.Error,
+		Message: message,
+	})
+	c.Abort()
+}
+
+// IsService reports whether the request carries a valid api key of a
+// service AuthenticateService would let in, without answering it.
+func IsService(c *gin.Context) bool {
 	serviceName := c.GetHeader(constants.XServiceName)
-	signatureKey := config.Config.SignatureKey
+	if serviceName == "" || validateAPIKey(c) != nil {
+		return false
+	}
 
-	logrus.Info("X-API-KEY", apiKey)
+	return IsInternalService(serviceName)
+}
+
+// IsInternalService reports whether serviceName may call the internal routes
+// and gRPC methods. Every service may while InternalServices is empty.
+func IsInternalService(serviceName string) bool {
+	return len(config.Config.InternalServices) == 0 || slices.Contains(config.Config.InternalServices, serviceName)
+}
+
+func validateAPIKey(c *gin.Context) error {
+	return ValidateAPIKey(c.GetHeader(constants.XServiceName), c.GetHeader(constants.XRequestAt), c.GetHeader(constants.XApiKey))
+}
+
+// defaultAPIKeyMaxSkew is how far the request time of an api key may be
+// from now when apiKeyMaxSkewSecond is unset.
+const defaultAPIKeyMaxSkew = 5 * time.Minute
+
+// ValidateAPIKey checks the key a service sends, the sha256 of its name, the
+// shared signature key and the request time. The request time is a unix
+// timestamp that must be within apiKeyMaxSkewSecond of now, so a captured key
+// stops working soon after. The gRPC server uses it too.
+func ValidateAPIKey(serviceName, requestAt, apiKey string) error {
+	signatureKey := config.Config.SignatureKey
 
 	validateKey := fmt.Sprintf("%s:%s:%s", serviceName, signatureKey, requestAt)
-	logrus.Info(validateKey)
 	hash := sha256.New()
 	hash.Write([]byte(validateKey))
 	resultHash := hex.EncodeToString(hash.Sum(nil))
-	logrus.Info(resultHash)
 
-	if apiKey != resultHash {
-		logrus.Info("Api key didn't same like result hash")
+	if subtle.ConstantTimeCompare([]byte(apiKey), []byte(resultHash)) != 1 {
+		return errConstants.ErrUnauthorized
+	}
+
+	unix, err := strconv.ParseInt(requestAt, 10, 64)
+	if err != nil {
+		return errConstants.ErrUnauthorized
+	}
+
+	maxSkew := time.Duration(config.Config.APIKeyMaxSkewSecond) * time.Second
+	if maxSkew <= 0 {
+		maxSkew = defaultAPIKeyMaxSkew
+	}
+
+	skew := time.Since(time.Unix(unix, 0))
+	if skew > maxSkew || skew < -maxSkew {
+		logrus.Warnf("api key of %s is outside the request time window by %s", serviceName, skew.Round(time.Second))
 		return errConstants.ErrUnauthorized
 	}
 
@@ -92,67 +253,217 @@ func validateAPIKey(c *gin.Context) error {
 }
 
 func validateBearerToken(c *gin.Context, token string) error {
+	claims, err := ParseBearerToken(token)
+	if err != nil {
+		return err
+	}
+
+	// set token to headers
+	c.Request = c.Request.WithContext(WithClaims(c.Request.Context(), claims))
+	c.Set(constants.Token, token)
+	return nil
+}
+
+// ParseBearerToken validates an Authorization header value and returns the
+// claims of its token.
+func ParseBearerToken(token string) (*auth.Claims, error) {
 	// check is token bearer or not
 	if !strings.Contains(token, "Bearer") {
-		return errConstants.ErrUnauthorized
+		return nil, errConstants.ErrUnauthorized
 	}
 
 	// extract bearer token
 	tokenString := extractBearerToken(token)
-	logrus.Info("Check tokenString >>>", tokenString)
 	if tokenString == "" {
-
-		return errConstants.ErrUnauthorized
+		return nil, errConstants.ErrUnauthorized
 	}
 
 	// claims token
-	claims := &services.Claims{}
-	tokenJwt, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
-			return nil, errConstants.ErrInvalidToken
-		}
-
-		jwtSecret := []byte(config.Config.JwtSecretKey)
-		return jwtSecret, nil
-	})
+	claims := &auth.Claims{}
+	tokenJwt, err := jwt.ParseWithClaims(tokenString, claims, services.Keyfunc)
 
 	if err != nil || !tokenJwt.Valid {
-		logrus.Info("token invalid")
-		return errConstants.ErrUnauthorized
+		return nil, errConstants.ErrUnauthorized
+	}
+
+	return claims, nil
+}
+
+// WithClaims stores the user, session and impersonator of a token in ctx.
+func WithClaims(ctx context.Context, claims *auth.Claims) context.Context {
+	ctx = context.WithValue(ctx, constants.UserLogin, claims.User)
+	ctx = context.WithValue(ctx, constants.SessionID, claims.ID)
+	if claims.Impersonator != nil {
+		ctx = context.WithValue(ctx, constants.Impersonator, claims.Impersonator)
+	}
+
+	return ctx
+}
+
+func validateUserStatus(c *gin.Context, service serviceRegistry.IServiceRegistery) error {
+	return CheckUserLogin(c.Request.Context(), service)
+}
+
+// CheckUserLogin makes sure the session behind the user in ctx is still
+// live and that the user is active.
+func CheckUserLogin(ctx context.Context, service serviceRegistry.IServiceRegistery) error {
+	userLogin := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	sessionID, _ := ctx.Value(constants.SessionID).(string)
+
+	// a revoked session invalidates its token before the token expires.
+	// Tokens issued before sessions existed carry no session id and can not
+	// be revoked, so they are refused the same way introspection refuses them
+	err := service.GetUser().CheckSession(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	err = service.GetUser().CheckUserStatus(ctx, userLogin.UUID.String())
+	if err != nil {
+		return err
+	}
+
+	// the admin behind an impersonation token has to stay active as well
+	if impersonator, ok := ctx.Value(constants.Impersonator).(*dto.UserResponse); ok {
+		return service.GetUser().CheckUserStatus(ctx, impersonator.UUID.String())
 	}
 
-	// set token to headers
-	userLogin := c.Request.WithContext(context.WithValue(c.Request.Context(), constants.UserLogin, claims.User))
-	c.Request = userLogin
-	c.Set(constants.Token, token)
 	return nil
 }
 
-func Authenticate() gin.HandlerFunc {
+func logImpersonation(c *gin.Context) {
+	impersonator, ok := c.Request.Context().Value(constants.Impersonator).(*dto.UserResponse)
+	if !ok {
+		return
+	}
+
+	userLogin := c.Request.Context().Value(constants.UserLogin).(*dto.UserResponse)
+	requestID, _ := c.Request.Context().Value(constants.RequestID).(string)
+	logrus.WithFields(logrus.Fields{
+		"impersonated": true,
+		"user":         userLogin.UUID.String(),
+		"impersonator": impersonator.UUID.String(),
+		"requestId":    requestID,
+		"method":       c.Request.Method,
+		"path":         c.Request.URL.Path,
+		"status":       c.Writer.Status(),
+	}).Info("request under impersonation")
+}
+
+func Authenticate(service serviceRegistry.IServiceRegistery) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var err error
-		token := c.GetHeader(constants.Authorization)
+		if !authenticateUser(c, service, true) {
+			return
+		}
 
-		if token == "" {
-			responseUnauthorized(c, errConstants.ErrUnauthorized.Error())
+		c.Next()
+		logImpersonation(c)
+	}
+}
+
+// ForwardAuth authenticates like Authenticate but without the api key,
+// since a reverse proxy forwards the headers of browser requests which
+// never carry one.
+func ForwardAuth(service serviceRegistry.IServiceRegistery) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !authenticateUser(c, service, false) {
 			return
 		}
-		logrus.Info(token)
 
-		err = validateBearerToken(c, token)
+		c.Next()
+	}
+}
+
+func authenticateUser(c *gin.Context, service serviceRegistry.IServiceRegistery, requireAPIKey bool) bool {
+	var err error
+	token := c.GetHeader(constants.Authorization)
+
+	if token == "" {
+		responseUnauthorized(c, errConstants.ErrUnauthorized.Error())
+		return false
+	}
+
+	err = validateBearerToken(c, token)
+	if err != nil {
+		responseUnauthorized(c, err.Error())
+		return false
+	}
+
+	if requireAPIKey {
+		err = validateAPIKey(c)
 		if err != nil {
-			logrus.Info("unvalidate token")
 			responseUnauthorized(c, err.Error())
+			return false
+		}
+	}
+
+	err = validateUserStatus(c, service)
+	if err != nil {
+		switch {
+		case errors.Is(err, errConstants.ErrUserNotFound):
+			responseUnauthorized(c, errConstants.ErrUnauthorized.Error())
+		case errors.Is(err, errConstants.ErrSessionNotFound),
+			errors.Is(err, errConstants.ErrSessionRevoked),
+			errors.Is(err, errConstants.ErrSessionExpired):
+			responseUnauthorized(c, err.Error())
+		case errors.Is(err, errConstants.ErrSqlError):
+			c.JSON(http.StatusInternalServerError, response.Response{
+				Status:  constants.Error,
+				Message: errConstants.ErrInternalServerError.Error(),
+			})
+			c.Abort()
+		default:
+			responseForbidden(c, err.Error())
+		}
+		return false
+	}
+
+	return true
+}
+
+// AuthenticateService lets sibling services in with a valid api key and no
+// user token. When internalServices is configured the caller must also be
+// one of the listed services.
+func AuthenticateService() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		serviceName := c.GetHeader(constants.XServiceName)
+		if serviceName == "" {
+			responseUnauthorized(c, errConstants.ErrUnauthorized.Error())
 			return
 		}
 
-		err = validateAPIKey(c)
+		err := validateAPIKey(c)
 		if err != nil {
 			responseUnauthorized(c, err.Error())
 			return
 		}
 
+		if !IsInternalService(serviceName) {
+			responseForbidden(c, errConstants.ErrForbiden.Error())
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), constants.ServiceName, serviceName)
+		c.Request = c.Request.WithContext(ctx)
 		c.Next()
 	}
 }
+
+func CheckRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userLogin, ok := c.Request.Context().Value(constants.UserLogin).(*dto.UserResponse)
+		if !ok {
+			responseUnauthorized(c, errConstants.ErrUnauthorized.Error())
+			return
+		}
+
+		for _, role := range roles {
+			if strings.EqualFold(userLogin.Role, role) {
+				c.Next()
+				return
+			}
+		}
+
+		responseForbidden(c, errConstants.ErrForbiden.Error())
+	}
+}
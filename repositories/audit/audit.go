@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-service/common/audit"
+	errWrap "user-service/common/error"
+	errConstant "user-service/constants/error"
+	"user-service/domain/dto"
+	"user-service/domain/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+type IAuditRepository interface {
+	Create(context.Context, *models.AuditLog) (*models.AuditLog, error)
+	FindAll(context.Context, *dto.AuditFilterRequest) ([]models.AuditLog, int64, error)
+	FindLast(context.Context) (*models.AuditLog, error)
+	FindByID(context.Context, uint) (*models.AuditLog, error)
+	FindInBatches(context.Context, int, func([]models.AuditLog) error) error
+}
+
+func (r *AuditRepository) Create(ctx context.Context, log *models.AuditLog) (*models.AuditLog, error) {
+	if log.UUID == uuid.Nil {
+		log.UUID = uuid.New()
+	}
+
+	log.Chain = audit.ChainOf(log)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// serialise the writers of one chain so every entry links to the one
+		// committed before it, other chains are written concurrently
+		err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "audit_logs:"+log.Chain).Error
+		if err != nil {
+			return err
+		}
+
+		var last models.AuditLog
+		err = tx.Select("hash").Where("chain = ?", log.Chain).Order("id DESC").Limit(1).Find(&last).Error
+		if err != nil {
+			return err
+		}
+
+		createdAt := audit.Timestamp(time.Now())
+		log.CreatedAt = &createdAt
+		log.PrevHash = last.Hash
+		log.Hash = audit.ComputeHash(last.Hash, log)
+
+		return tx.Create(log).Error
+	})
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return log, nil
+}
+
+func (r *AuditRepository) FindAll(ctx context.Context, req *dto.AuditFilterRequest) ([]models.AuditLog, int64, error) {
+	var (
+		logs  []models.AuditLog
+		total int64
+	)
+
+	query := r.db.WithContext(ctx).Model(&models.AuditLog{})
+	if req.Event != "" {
+		query = query.Where("event = ?", req.Event)
+	}
+	if req.ActorUUID != "" {
+		query = query.Where("actor_uuid = ?", req.ActorUUID)
+	}
+	if req.TargetUUID != "" {
+		query = query.Where("target_uuid = ?", req.TargetUUID)
+	}
+	if req.ImpersonatorUUID != "" {
+		query = query.Where("impersonator_uuid = ?", req.ImpersonatorUUID)
+	}
+	if req.RequestID != "" {
+		query = query.Where("request_id = ?", req.RequestID)
+	}
+	if req.From != nil {
+		query = query.Where("created_at >= ?", req.From)
+	}
+	if req.To != nil {
+		query = query.Where("created_at <= ?", req.To)
+	}
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	err = query.
+		Order("id DESC").
+		Offset((req.Page - 1) * req.Limit).
+		Limit(req.Limit).
+		Find(&logs).Error
+	if err != nil {
+		return nil, 0, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return logs, total, nil
+}
+
+func (r *AuditRepository) FindLast(ctx context.Context) (*models.AuditLog, error) {
+	var log models.AuditLog
+
+	err := r.db.WithContext(ctx).Order("id DESC").First(&log).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWrap.WrapError(errConstant.ErrAuditLogNotFound)
+		}
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return &log, nil
+}
+
+func (r *AuditRepository) FindByID(ctx context.Context, id uint) (*models.AuditLog, error) {
+	var log models.AuditLog
+
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&log).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWrap.WrapError(errConstant.ErrAuditLogNotFound)
+		}
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return &log, nil
+}
+
+// FindInBatches walks the whole audit log in id order, handing fn one batch
+// at a time. Returning an error from fn stops the walk.
+func (r *AuditRepository) FindInBatches(ctx context.Context, size int, fn func([]models.AuditLog) error) error {
+	var logs []models.AuditLog
+
+	result := r.db.WithContext(ctx).
+		FindInBatches(&logs, size, func(_ *gorm.DB, _ int) error {
+			return fn(logs)
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	return nil
+}
+
+func NewAuditRepository(db *gorm.DB) IAuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,113 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	errWrap "user-service/common/error"
+	errConstant "user-service/constants/error"
+	"user-service/domain/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailChangeRepository struct {
+	db *gorm.DB
+}
+
+type IEmailChangeRepository interface {
+	Create(context.Context, *models.EmailChange) (*models.EmailChange, error)
+	FindByConfirmTokenHash(context.Context, string) (*models.EmailChange, error)
+	FindByCancelTokenHash(context.Context, string) (*models.EmailChange, error)
+	Confirm(context.Context, uint) error
+	Cancel(context.Context, uint) error
+	CancelPendingByUserID(context.Context, uint) error
+}
+
+func (r *EmailChangeRepository) Create(ctx context.Context, change *models.EmailChange) (*models.EmailChange, error) {
+	if change.UUID == uuid.Nil {
+		change.UUID = uuid.New()
+	}
+
+	err := r.db.WithContext(ctx).Create(change).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return change, nil
+}
+
+// findBy also loads a user that was deleted since the request was made, so
+// the caller can tell that apart from a missing request.
+func (r *EmailChangeRepository) findBy(ctx context.Context, column, hash string) (*models.EmailChange, error) {
+	var change models.EmailChange
+
+	err := r.db.WithContext(ctx).
+		Preload("User", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Where(column+" = ?", hash).
+		First(&change).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWrap.WrapError(errConstant.ErrEmailChangeNotFound)
+		}
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return &change, nil
+}
+
+func (r *EmailChangeRepository) FindByConfirmTokenHash(ctx context.Context, hash string) (*models.EmailChange, error) {
+	return r.findBy(ctx, "confirm_token_hash", hash)
+}
+
+func (r *EmailChangeRepository) FindByCancelTokenHash(ctx context.Context, hash string) (*models.EmailChange, error) {
+	return r.findBy(ctx, "cancel_token_hash", hash)
+}
+
+// resolve stamps column on a request that is still open, so two racing
+// confirm and cancel calls cannot both succeed.
+func (r *EmailChangeRepository) resolve(ctx context.Context, id uint, column string, open string) error {
+	result := r.db.WithContext(ctx).
+		Model(&models.EmailChange{}).
+		Where("id = ? AND "+open, id).
+		Update(column, time.Now())
+	if result.Error != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	if result.RowsAffected == 0 {
+		return errWrap.WrapError(errConstant.ErrEmailChangeResolved)
+	}
+
+	return nil
+}
+
+func (r *EmailChangeRepository) Confirm(ctx context.Context, id uint) error {
+	return r.resolve(ctx, id, "confirmed_at", "confirmed_at IS NULL AND cancelled_at IS NULL")
+}
+
+// Cancel also applies to a confirmed request, which the caller then rolls
+// back to the old address.
+func (r *EmailChangeRepository) Cancel(ctx context.Context, id uint) error {
+	return r.resolve(ctx, id, "cancelled_at", "cancelled_at IS NULL")
+}
+
+// CancelPendingByUserID supersedes every open request of the user.
+func (r *EmailChangeRepository) CancelPendingByUserID(ctx context.Context, userID uint) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.EmailChange{}).
+		Where("user_id = ? AND confirmed_at IS NULL AND cancelled_at IS NULL", userID).
+		Update("cancelled_at", time.Now()).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
+func NewEmailChangeRepository(db *gorm.DB) IEmailChangeRepository {
+	return &EmailChangeRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,101 @@
+package repositories
+
+import (
+	"context"
+	"time"
+	errWrap "user-service/common/error"
+	errConstant "user-service/constants/error"
+	"user-service/domain/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepository struct {
+	db *gorm.DB
+}
+
+type IOutboxRepository interface {
+	Create(context.Context, *models.OutboxEvent) error
+	FindPending(context.Context, int) ([]models.OutboxEvent, error)
+	MarkPublished(context.Context, uint) error
+	MarkFailed(context.Context, uint, string, time.Time) error
+}
+
+func (r *OutboxRepository) Create(ctx context.Context, event *models.OutboxEvent) error {
+	if event.UUID == uuid.Nil {
+		event.UUID = uuid.New()
+	}
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = time.Now()
+	}
+
+	err := r.db.WithContext(ctx).Create(event).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
+// FindPending locks up to limit events that are due, skipping the ones
+// another relay holds, so it has to run inside a transaction. An event waits
+// while an earlier event of the same aggregate is unpublished, so one that
+// is backing off or held by another relay is never overtaken.
+func (r *OutboxRepository) FindPending(ctx context.Context, limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+
+	err := r.db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("published_at IS NULL AND next_attempt_at <= ?", time.Now()).
+		Where("NOT EXISTS (SELECT 1 FROM outbox_events earlier " +
+			"WHERE earlier.aggregate_uuid = outbox_events.aggregate_uuid " +
+			"AND earlier.published_at IS NULL AND earlier.id < outbox_events.id)").
+		Order("id").
+		Limit(limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id uint) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"published_at": time.Now(),
+			"attempts":     gorm.Expr("attempts + 1"),
+			"last_error":   nil,
+		}).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id uint, reason string, nextAttemptAt time.Time) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.OutboxEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"attempts":        gorm.Expr("attempts + 1"),
+			"last_error":      reason,
+			"next_attempt_at": nextAttemptAt,
+		}).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
+func NewOutboxRepository(db *gorm.DB) IOutboxRepository {
+	return &OutboxRepository{
+		db: db,
+	}
+}
@@ -1,25 +1,94 @@
 package repositories
 
 import (
+	"context"
+	auditRepositories "user-service/repositories/audit"
+	emailChangeRepositories "user-service/repositories/emailchange"
+	outboxRepositories "user-service/repositories/outbox"
+	sessionRepositories "user-service/repositories/session"
 	repositories "user-service/repositories/user"
+	webhookRepositories "user-service/repositories/webhook"
 
 	"gorm.io/gorm"
 )
 
 type Registry struct {
-	db *gorm.DB
+	db        *gorm.DB
+	userCache *repositories.UserCache
+	pending   *repositories.PendingInvalidations
 }
 
 type IRepositoryRegistry interface {
 	GetUser() repositories.IUserRepository
+	GetAudit() auditRepositories.IAuditRepository
+	GetSession() sessionRepositories.ISessionRepository
+	GetEmailChange() emailChangeRepositories.IEmailChangeRepository
+	GetOutbox() outboxRepositories.IOutboxRepository
+	GetWebhook() webhookRepositories.IWebhookRepository
+	WithTransaction(context.Context, func(IRepositoryRegistry) error) error
 }
 
-func NewRepositoryRegistry(db *gorm.DB) IRepositoryRegistry {
+// NewRepositoryRegistry builds the repositories on db. userCache may be nil
+// to look users up in the database every time.
+func NewRepositoryRegistry(db *gorm.DB, userCache *repositories.UserCache) IRepositoryRegistry {
 	return &Registry{
-		db: db,
+		db:        db,
+		userCache: userCache,
 	}
 }
 
 func (r *Registry) GetUser() repositories.IUserRepository {
-	return repositories.NewUserRepository(r.db)
+	repository := repositories.NewUserRepository(r.db)
+	if r.userCache == nil {
+		return repository
+	}
+
+	return repositories.NewCachedUserRepository(repository, r.userCache, r.pending)
+}
+
+func (r *Registry) GetAudit() auditRepositories.IAuditRepository {
+	return auditRepositories.NewAuditRepository(r.db)
+}
+
+func (r *Registry) GetSession() sessionRepositories.ISessionRepository {
+	return sessionRepositories.NewSessionRepository(r.db)
+}
+
+func (r *Registry) GetEmailChange() emailChangeRepositories.IEmailChangeRepository {
+	return emailChangeRepositories.NewEmailChangeRepository(r.db)
+}
+
+func (r *Registry) GetOutbox() outboxRepositories.IOutboxRepository {
+	return outboxRepositories.NewOutboxRepository(r.db)
+}
+
+func (r *Registry) GetWebhook() webhookRepositories.IWebhookRepository {
+	return webhookRepositories.NewWebhookRepository(r.db)
+}
+
+// WithTransaction runs fn with repositories bound to a single database
+// transaction, committing when fn returns nil. An error or a panic in fn
+// rolls the transaction back, and the panic is raised again afterwards.
+//
+// Called on a registry that fn already received, it opens a savepoint
+// instead, so a nested unit of work that fails only undoes its own writes
+// and the caller decides whether the outer transaction still commits.
+//
+// Cached users written inside the transaction are invalidated again once
+// the outermost transaction has ended.
+func (r *Registry) WithTransaction(ctx context.Context, fn func(IRepositoryRegistry) error) error {
+	pending := r.pending
+	if pending == nil {
+		pending = &repositories.PendingInvalidations{}
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Registry{db: tx, userCache: r.userCache, pending: pending})
+	})
+
+	if r.pending == nil && r.userCache != nil {
+		pending.Flush(ctx, r.userCache)
+	}
+
+	return err
 }
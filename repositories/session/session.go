@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+	errWrap "user-service/common/error"
+	errConstant "user-service/constants/error"
+	"user-service/domain/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+type ISessionRepository interface {
+	Create(context.Context, *models.Session) (*models.Session, error)
+	FindByUUID(context.Context, string) (*models.Session, error)
+	RevokeByUserID(context.Context, uint, string) error
+	Revoke(context.Context, string) error
+}
+
+func (r *SessionRepository) Create(ctx context.Context, session *models.Session) (*models.Session, error) {
+	if session.UUID == uuid.Nil {
+		session.UUID = uuid.New()
+	}
+
+	err := r.db.WithContext(ctx).Create(session).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return session, nil
+}
+
+func (r *SessionRepository) FindByUUID(ctx context.Context, uuid string) (*models.Session, error) {
+	var session models.Session
+
+	err := r.db.WithContext(ctx).
+		Where("uuid = ?", uuid).
+		First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWrap.WrapError(errConstant.ErrSessionNotFound)
+		}
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return &session, nil
+}
+
+// RevokeByUserID revokes every active session of the user except the one
+// identified by except, which may be empty to revoke them all.
+func (r *SessionRepository) RevokeByUserID(ctx context.Context, userID uint, except string) error {
+	query := r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID)
+	if except != "" {
+		query = query.Where("uuid <> ?", except)
+	}
+
+	err := query.Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
+// Revoke ends a single session, a session that is already revoked is left
+// as it is.
+func (r *SessionRepository) Revoke(ctx context.Context, uuid string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("uuid = ? AND revoked_at IS NULL", uuid).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
+func NewSessionRepository(db *gorm.DB) ISessionRepository {
+	return &SessionRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,291 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+	"user-service/common/cache"
+	errWrap "user-service/common/error"
+	errConstant "user-service/constants/error"
+	"user-service/database/resolver"
+	"user-service/domain/dto"
+	"user-service/domain/models"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// the version segment lets a release that changes models.User ignore
+	// entries written by the previous one instead of decoding them
+	userCacheKeyPrefix = "user-service:user:v2:"
+	notFoundEntry      = "null"
+
+	defaultUserCacheTTL         = time.Minute
+	defaultUserCacheNegativeTTL = 10 * time.Second
+)
+
+// UserCache holds FindByUUID results, including the lookups that found
+// nothing, for a short while.
+type UserCache struct {
+	cache       cache.ICache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	metrics     *cache.Metrics
+}
+
+func NewUserCache(backend cache.ICache, ttl, negativeTTL time.Duration) *UserCache {
+	if ttl <= 0 {
+		ttl = defaultUserCacheTTL
+	}
+	if negativeTTL <= 0 {
+		negativeTTL = defaultUserCacheNegativeTTL
+	}
+
+	return &UserCache{
+		cache:       backend,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		metrics:     cache.MetricsFor("user"),
+	}
+}
+
+func userCacheKey(uuid string) string {
+	return userCacheKeyPrefix + uuid
+}
+
+// Invalidate drops the cached lookups of the given users. A failure only
+// means the entry lives until its ttl, so it is logged and not returned.
+func (c *UserCache) Invalidate(ctx context.Context, uuids ...string) {
+	if len(uuids) == 0 {
+		return
+	}
+
+	keys := make([]string, len(uuids))
+	for i, uuid := range uuids {
+		keys[i] = userCacheKey(uuid)
+	}
+
+	if err := c.cache.Delete(ctx, keys...); err != nil {
+		c.metrics.Error()
+		logrus.Warnf("failed to invalidate cached users %v: %v", uuids, err)
+	}
+}
+
+// PendingInvalidations collects the users written inside a transaction so
+// their cache entries can be dropped again once it commits. Dropping them
+// only at write time would let a concurrent lookup cache the old row
+// before the commit makes the new one visible.
+type PendingInvalidations struct {
+	mu    sync.Mutex
+	uuids []string
+}
+
+func (p *PendingInvalidations) add(uuid string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.uuids = append(p.uuids, uuid)
+}
+
+// Flush invalidates everything collected so far.
+func (p *PendingInvalidations) Flush(ctx context.Context, userCache *UserCache) {
+	p.mu.Lock()
+	uuids := p.uuids
+	p.uuids = nil
+	p.mu.Unlock()
+
+	userCache.Invalidate(ctx, uuids...)
+}
+
+// cachedUser is what the cache keeps of a user. The password hash is left
+// out so it never leaves the database, the one lookup that needs it reads
+// the primary, which skips the cache.
+type cachedUser struct {
+	ID              uint           `json:"id"`
+	UUID            uuid.UUID      `json:"uuid"`
+	Name            string         `json:"name"`
+	Username        string         `json:"username"`
+	Email           string         `json:"email"`
+	PhoneNumber     string         `json:"phoneNumber"`
+	RoleID          uint           `json:"roleId"`
+	Status          string         `json:"status"`
+	StatusReason    *string        `json:"statusReason"`
+	StatusChangedBy *uuid.UUID     `json:"statusChangedBy"`
+	StatusChangedAt *time.Time     `json:"statusChangedAt"`
+	StatusExpiresAt *time.Time     `json:"statusExpiresAt"`
+	Version         uint           `json:"version"`
+	CreatedAt       *time.Time     `json:"createdAt"`
+	UpdatedAt       *time.Time     `json:"updatedAt"`
+	DeletedAt       gorm.DeletedAt `json:"deletedAt"`
+	Role            models.Role    `json:"role"`
+}
+
+func newCachedUser(user *models.User) cachedUser {
+	return cachedUser{
+		ID:              user.ID,
+		UUID:            user.UUID,
+		Name:            user.Name,
+		Username:        user.Username,
+		Email:           user.Email,
+		PhoneNumber:     user.PhoneNumber,
+		RoleID:          user.RoleID,
+		Status:          user.Status,
+		StatusReason:    user.StatusReason,
+		StatusChangedBy: user.StatusChangedBy,
+		StatusChangedAt: user.StatusChangedAt,
+		StatusExpiresAt: user.StatusExpiresAt,
+		Version:         user.Version,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		DeletedAt:       user.DeletedAt,
+		Role:            user.Role,
+	}
+}
+
+func (c *cachedUser) toModel() *models.User {
+	return &models.User{
+		ID:              c.ID,
+		UUID:            c.UUID,
+		Name:            c.Name,
+		Username:        c.Username,
+		Email:           c.Email,
+		PhoneNumber:     c.PhoneNumber,
+		RoleID:          c.RoleID,
+		Status:          c.Status,
+		StatusReason:    c.StatusReason,
+		StatusChangedBy: c.StatusChangedBy,
+		StatusChangedAt: c.StatusChangedAt,
+		StatusExpiresAt: c.StatusExpiresAt,
+		Version:         c.Version,
+		CreatedAt:       c.CreatedAt,
+		UpdatedAt:       c.UpdatedAt,
+		DeletedAt:       c.DeletedAt,
+		Role:            c.Role,
+	}
+}
+
+type CachedUserRepository struct {
+	IUserRepository
+	cache   *UserCache
+	pending *PendingInvalidations
+}
+
+// NewCachedUserRepository serves FindByUUID from userCache in front of
+// repository and drops the entry whenever a user is written. Inside a
+// transaction pass the transaction's pending invalidations: lookups then
+// bypass the cache, which must not hold rows nobody else can see yet.
+func NewCachedUserRepository(repository IUserRepository, userCache *UserCache, pending *PendingInvalidations) IUserRepository {
+	return &CachedUserRepository{IUserRepository: repository, cache: userCache, pending: pending}
+}
+
+func (r *CachedUserRepository) FindByUUID(ctx context.Context, uuid string) (*models.User, error) {
+	if r.pending != nil {
+		return r.IUserRepository.FindByUUID(ctx, uuid)
+	}
+
+	key := userCacheKey(uuid)
+
+	// a caller reading its own write asked for the primary, the cache may
+	// be as old as a replica so it is refreshed instead of consulted
+	if !resolver.PrimaryRequested(ctx) {
+		if user, cached := r.lookup(ctx, key); cached {
+			if user == nil {
+				return nil, errWrap.WrapError(errConstant.ErrUserNotFound)
+			}
+			return user, nil
+		}
+	}
+
+	// a replica may still hold the row from before a write whose
+	// invalidation already ran, caching it would keep it for a whole ttl
+	user, err := r.IUserRepository.FindByUUID(resolver.WithPrimary(ctx), uuid)
+	switch {
+	case err == nil:
+		r.store(ctx, key, user, r.cache.ttl)
+	case errors.Is(err, errConstant.ErrUserNotFound):
+		r.store(ctx, key, nil, r.cache.negativeTTL)
+	}
+
+	return user, err
+}
+
+// lookup reports whether key was cached, with a nil user for a lookup that
+// found nothing.
+func (r *CachedUserRepository) lookup(ctx context.Context, key string) (*models.User, bool) {
+	value, err := r.cache.cache.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, cache.ErrMiss) {
+			r.cache.metrics.Miss()
+		} else {
+			r.cache.metrics.Error()
+			logrus.Warnf("failed to read cached user %s: %v", key, err)
+		}
+		return nil, false
+	}
+
+	if string(value) == notFoundEntry {
+		r.cache.metrics.NegativeHit()
+		return nil, true
+	}
+
+	var user cachedUser
+	if err := json.Unmarshal(value, &user); err != nil {
+		r.cache.metrics.Error()
+		logrus.Warnf("failed to decode cached user %s: %v", key, err)
+		return nil, false
+	}
+
+	r.cache.metrics.Hit()
+	return user.toModel(), true
+}
+
+func (r *CachedUserRepository) store(ctx context.Context, key string, user *models.User, ttl time.Duration) {
+	value := []byte(notFoundEntry)
+	if user != nil {
+		var err error
+		value, err = json.Marshal(newCachedUser(user))
+		if err != nil {
+			r.cache.metrics.Error()
+			return
+		}
+	}
+
+	if err := r.cache.cache.Set(ctx, key, value, ttl); err != nil {
+		r.cache.metrics.Error()
+		logrus.Warnf("failed to cache user %s: %v", key, err)
+	}
+}
+
+func (r *CachedUserRepository) invalidate(ctx context.Context, uuid string) {
+	r.cache.Invalidate(ctx, uuid)
+	if r.pending != nil {
+		r.pending.add(uuid)
+	}
+}
+
+func (r *CachedUserRepository) Update(ctx context.Context, req *dto.UpdateRequest, uuid string) (*models.User, error) {
+	user, err := r.IUserRepository.Update(ctx, req, uuid)
+	r.invalidate(ctx, uuid)
+	return user, err
+}
+
+func (r *CachedUserRepository) UpdateStatus(ctx context.Context, req *dto.UpdateStatusRequest, uuid string) (*models.User, error) {
+	user, err := r.IUserRepository.UpdateStatus(ctx, req, uuid)
+	r.invalidate(ctx, uuid)
+	return user, err
+}
+
+func (r *CachedUserRepository) UpdateRole(ctx context.Context, roleCode string, uuid string) (*models.User, error) {
+	user, err := r.IUserRepository.UpdateRole(ctx, roleCode, uuid)
+	r.invalidate(ctx, uuid)
+	return user, err
+}
+
+func (r *CachedUserRepository) UpdatePassword(ctx context.Context, password string, uuid string) error {
+	err := r.IUserRepository.UpdatePassword(ctx, password, uuid)
+	r.invalidate(ctx, uuid)
+	return err
+}
@@ -3,8 +3,11 @@ package repositories
 import (
 	"context"
 	"errors"
+	"time"
 	errWrap "user-service/common/error"
+	"user-service/constants"
 	errConstant "user-service/constants/error"
+	"user-service/database/resolver"
 	"user-service/domain/dto"
 	"user-service/domain/models"
 
@@ -16,12 +19,35 @@ type UserRepository struct {
 	db *gorm.DB
 }
 
+// uniqueErrors maps the unique indexes on users to the error reported when
+// a write would duplicate a value another user already holds.
+var uniqueErrors = map[string]error{
+	"idx_users_username_lower": errConstant.ErrUsernameExist,
+	"idx_users_email_lower":    errConstant.ErrEmailExist,
+	"idx_users_phone_number":   errConstant.ErrPhoneNumberExist,
+}
+
+func writeError(err error) error {
+	if constraint, ok := errWrap.UniqueViolation(err); ok {
+		if uniqueErr, ok := uniqueErrors[constraint]; ok {
+			return errWrap.WrapError(uniqueErr)
+		}
+	}
+
+	return errWrap.WrapError(errConstant.ErrSqlError)
+}
+
 type IUserRepository interface {
 	Register(context.Context, *dto.RegisterRequest) (*models.User, error)
 	Update(context.Context, *dto.UpdateRequest, string) (*models.User, error)
 	FindByEmail(context.Context, string) (*models.User, error)
 	FindByUsername(context.Context, string) (*models.User, error)
 	FindByUUID(context.Context, string) (*models.User, error)
+	FindByUUIDs(context.Context, []string) ([]models.User, error)
+	FindByUUIDWithDeleted(context.Context, string) (*models.User, error)
+	UpdateStatus(context.Context, *dto.UpdateStatusRequest, string) (*models.User, error)
+	UpdateRole(context.Context, string, string) (*models.User, error)
+	UpdatePassword(context.Context, string, string) error
 }
 
 func (r *UserRepository) Register(ctx context.Context, req *dto.RegisterRequest) (*models.User, error) {
@@ -33,31 +59,59 @@ func (r *UserRepository) Register(ctx context.Context, req *dto.RegisterRequest)
 		Password:    req.Password,
 		PhoneNumber: req.PhoneNumber,
 		RoleID:      req.RoleID,
+		Status:      constants.UserStatusActive,
 	}
 
 	err := r.db.WithContext(ctx).Create(&user).Error
 	if err != nil {
-		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+		return nil, writeError(err)
 	}
 
 	return &user, nil
 }
 
 func (r *UserRepository) Update(ctx context.Context, req *dto.UpdateRequest, uuid string) (*models.User, error) {
-	user := models.User{
-		Username:    req.Username,
-		Name:        req.Name,
-		Password:    *req.Password,
-		PhoneNumber: req.PhoneNumber,
+	values := map[string]interface{}{}
+	if req.Name != nil {
+		values["name"] = *req.Name
 	}
-
-	err := r.db.WithContext(ctx).Where("uuid = ?", uuid).Updates(&user).Error
-	if err != nil {
-		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	if req.Username != nil {
+		values["username"] = *req.Username
+	}
+	if req.Email != nil {
+		values["email"] = *req.Email
+	}
+	if req.PhoneNumber != nil {
+		values["phone_number"] = *req.PhoneNumber
 	}
 
-	return &user, nil
+	if len(values) > 0 {
+		values["version"] = gorm.Expr("version + 1")
+
+		query := r.db.WithContext(ctx).
+			Model(&models.User{}).
+			Where("uuid = ?", uuid)
+
+		// only write when nobody bumped the version since the caller read it
+		if req.Version != 0 {
+			query = query.Where("version = ?", req.Version)
+		}
 
+		result := query.Updates(values)
+		if result.Error != nil {
+			return nil, writeError(result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			_, err := r.FindByUUID(resolver.WithPrimary(ctx), uuid)
+			if err != nil {
+				return nil, err
+			}
+			return nil, errWrap.WrapError(errConstant.ErrConflict)
+		}
+	}
+
+	return r.FindByUUID(resolver.WithPrimary(ctx), uuid)
 }
 
 func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
@@ -65,7 +119,7 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models
 
 	err := r.db.WithContext(ctx).
 		Preload("Role").
-		Where("email = ?", email).
+		Where("lower(email) = lower(?)", email).
 		First(&user).Error
 
 	if err != nil {
@@ -83,7 +137,7 @@ func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*
 
 	err := r.db.WithContext(ctx).
 		Preload("Role").
-		Where("username = ?", username).
+		Where("lower(username) = lower(?)", username).
 		First(&user).Error
 
 	if err != nil {
@@ -114,6 +168,111 @@ func (r *UserRepository) FindByUUID(ctx context.Context, uuid string) (*models.U
 	return &user, nil
 }
 
+func (r *UserRepository) FindByUUIDs(ctx context.Context, uuids []string) ([]models.User, error) {
+	var users []models.User
+
+	err := r.db.WithContext(ctx).
+		Preload("Role").
+		Where("uuid IN ?", uuids).
+		Find(&users).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return users, nil
+}
+
+func (r *UserRepository) FindByUUIDWithDeleted(ctx context.Context, uuid string) (*models.User, error) {
+	var user models.User
+
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Preload("Role").
+		Where("uuid = ?", uuid).
+		First(&user).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWrap.WrapError(errConstant.ErrUserNotFound)
+		}
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return &user, nil
+}
+
+func (r *UserRepository) UpdateStatus(ctx context.Context, req *dto.UpdateStatusRequest, uuid string) (*models.User, error) {
+	now := time.Now()
+	values := map[string]interface{}{
+		"status":            req.Status,
+		"status_reason":     req.Reason,
+		"status_changed_by": req.ChangedBy,
+		"status_changed_at": now,
+		"status_expires_at": req.ExpiresAt,
+		"deleted_at":        nil,
+		"version":           gorm.Expr("version + 1"),
+	}
+
+	// soft deleted users keep their row, only deleted_at marks them as gone
+	if req.Status == constants.UserStatusDeleted {
+		values["deleted_at"] = now
+	}
+
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Model(&models.User{}).
+		Where("uuid = ?", uuid).
+		Updates(values).Error
+	if err != nil {
+		// restoring a deleted user takes its values back
+		return nil, writeError(err)
+	}
+
+	return r.FindByUUIDWithDeleted(resolver.WithPrimary(ctx), uuid)
+}
+
+func (r *UserRepository) UpdateRole(ctx context.Context, roleCode string, uuid string) (*models.User, error) {
+	var role models.Role
+
+	err := r.db.WithContext(ctx).
+		Where("code = ?", roleCode).
+		First(&role).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWrap.WrapError(errConstant.ErrRoleNotFound)
+		}
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	err = r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("uuid = ?", uuid).
+		Updates(map[string]interface{}{
+			"role_id": role.ID,
+			"version": gorm.Expr("version + 1"),
+		}).Error
+	if err != nil {
+		return nil, writeError(err)
+	}
+
+	return r.FindByUUID(resolver.WithPrimary(ctx), uuid)
+}
+
+func (r *UserRepository) UpdatePassword(ctx context.Context, password string, uuid string) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.User{}).
+		Where("uuid = ?", uuid).
+		Updates(map[string]interface{}{
+			"password": password,
+			"version":  gorm.Expr("version + 1"),
+		}).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
 func NewUserRepository(db *gorm.DB) IUserRepository {
 	return &UserRepository{
 		db: db,
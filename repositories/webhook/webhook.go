@@ -0,0 +1,271 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	errWrap "user-service/common/error"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/domain/dto"
+	"user-service/domain/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+type IWebhookRepository interface {
+	Create(context.Context, *models.WebhookSubscription) (*models.WebhookSubscription, error)
+	FindAll(context.Context) ([]models.WebhookSubscription, error)
+	FindByUUID(context.Context, string) (*models.WebhookSubscription, error)
+	FindActiveByEvent(context.Context, string) ([]models.WebhookSubscription, error)
+	Update(context.Context, *models.WebhookSubscription) (*models.WebhookSubscription, error)
+	Delete(context.Context, uint) error
+	CreateDeliveries(context.Context, []models.WebhookDelivery) error
+	FindDueDeliveries(context.Context, int) ([]models.WebhookDelivery, error)
+	LeaseDeliveries(context.Context, []uint, uuid.UUID, time.Time) error
+	UpdateLeasedDelivery(context.Context, *models.WebhookDelivery, uuid.UUID) error
+	FindDeliveries(context.Context, uint, *dto.WebhookDeliveryFilterRequest) ([]models.WebhookDelivery, int64, error)
+	FindDeliveryByUUID(context.Context, uint, string) (*models.WebhookDelivery, error)
+	UpdateDelivery(context.Context, *models.WebhookDelivery) error
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	if subscription.UUID == uuid.Nil {
+		subscription.UUID = uuid.New()
+	}
+
+	err := r.db.WithContext(ctx).Create(subscription).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return subscription, nil
+}
+
+func (r *WebhookRepository) FindAll(ctx context.Context) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+
+	err := r.db.WithContext(ctx).Order("id").Find(&subscriptions).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepository) FindByUUID(ctx context.Context, uuid string) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+
+	err := r.db.WithContext(ctx).Where("uuid = ?", uuid).First(&subscription).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWrap.WrapError(errConstant.ErrWebhookNotFound)
+		}
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return &subscription, nil
+}
+
+// FindActiveByEvent returns the active subscriptions listening to event,
+// either by name or through the wildcard.
+func (r *WebhookRepository) FindActiveByEvent(ctx context.Context, event string) ([]models.WebhookSubscription, error) {
+	var subscriptions []models.WebhookSubscription
+
+	err := r.db.WithContext(ctx).
+		Where("active AND (events @> ? OR events @> ?)",
+			fmt.Sprintf("[%q]", event), fmt.Sprintf("[%q]", constants.WebhookAllEvents)).
+		Order("id").
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return subscriptions, nil
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, subscription *models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	err := r.db.WithContext(ctx).
+		Model(subscription).
+		Select("url", "description", "events", "active", "updated_at").
+		Updates(subscription).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return subscription, nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id uint) error {
+	err := r.db.WithContext(ctx).Delete(&models.WebhookSubscription{}, id).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
+// CreateDeliveries skips deliveries that already exist for the same
+// subscription and event, so an event relayed twice is sent once.
+func (r *WebhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Omit(clause.Associations).
+		Create(&deliveries).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
+// FindDueDeliveries locks up to limit pending deliveries whose next attempt
+// is due, skipping the ones another worker holds, so it has to run inside
+// a transaction.
+func (r *WebhookRepository) FindDueDeliveries(ctx context.Context, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	now := time.Now()
+	err := r.db.WithContext(ctx).
+		Preload("Subscription").
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", constants.WebhookDeliveryPending, now).
+		Where("leased_until IS NULL OR leased_until <= ?", now).
+		Order("id").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return deliveries, nil
+}
+
+// LeaseDeliveries hands the deliveries to the batch leaseID until until, so
+// other workers skip them while they are sent outside a transaction.
+func (r *WebhookRepository) LeaseDeliveries(ctx context.Context, ids []uint, leaseID uuid.UUID, until time.Time) error {
+	err := r.db.WithContext(ctx).
+		Model(&models.WebhookDelivery{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"lease_id":     leaseID,
+			"leased_until": until,
+		}).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
+// UpdateLeasedDelivery stores the outcome of sending delivery and ends its
+// lease, unless the batch leaseID lost the delivery to a replay or an
+// expired lease meanwhile.
+func (r *WebhookRepository) UpdateLeasedDelivery(ctx context.Context, delivery *models.WebhookDelivery, leaseID uuid.UUID) error {
+	delivery.LeaseID = nil
+	delivery.LeasedUntil = nil
+
+	result := r.db.WithContext(ctx).
+		Model(delivery).
+		Omit(clause.Associations).
+		Where("lease_id = ?", leaseID).
+		Select(deliveryColumns).
+		Updates(delivery)
+	if result.Error != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	if result.RowsAffected == 0 {
+		return errWrap.WrapError(errConstant.ErrWebhookLeaseLost)
+	}
+
+	return nil
+}
+
+func (r *WebhookRepository) FindDeliveries(
+	ctx context.Context,
+	subscriptionID uint,
+	req *dto.WebhookDeliveryFilterRequest,
+) ([]models.WebhookDelivery, int64, error) {
+	var (
+		deliveries []models.WebhookDelivery
+		total      int64
+	)
+
+	query := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscriptionID)
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.Event != "" {
+		query = query.Where("event = ?", req.Event)
+	}
+
+	err := query.Count(&total).Error
+	if err != nil {
+		return nil, 0, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	err = query.
+		Order("id DESC").
+		Offset((req.Page - 1) * req.Limit).
+		Limit(req.Limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return deliveries, total, nil
+}
+
+func (r *WebhookRepository) FindDeliveryByUUID(ctx context.Context, subscriptionID uint, uuid string) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+
+	err := r.db.WithContext(ctx).
+		Where("subscription_id = ? AND uuid = ?", subscriptionID, uuid).
+		First(&delivery).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWrap.WrapError(errConstant.ErrWebhookDeliveryNotFound)
+		}
+		return nil, errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return &delivery, nil
+}
+
+// deliveryColumns are the columns of a delivery that change once it exists.
+var deliveryColumns = []string{
+	"status", "attempts", "last_status_code", "last_error", "next_attempt_at", "delivered_at",
+	"lease_id", "leased_until", "updated_at",
+}
+
+// UpdateDelivery saves the outcome of an attempt, or the reset of a replay.
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	err := r.db.WithContext(ctx).
+		Model(delivery).
+		Omit(clause.Associations).
+		Select(deliveryColumns).
+		Updates(delivery).Error
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrSqlError)
+	}
+
+	return nil
+}
+
+func NewWebhookRepository(db *gorm.DB) IWebhookRepository {
+	return &WebhookRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,49 @@
+package routes
+
+import (
+	"user-service/constants"
+	"user-service/controllers"
+	"user-service/middlewares"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminRoute struct {
+	controllers controllers.IControllerRegistry
+	services    services.IServiceRegistery
+	group       *gin.RouterGroup
+}
+
+type IAdminRoute interface {
+	Run()
+}
+
+func NewAdminRoute(controllers controllers.IControllerRegistry, services services.IServiceRegistery, group *gin.RouterGroup) IAdminRoute {
+	return &AdminRoute{
+		controllers: controllers,
+		services:    services,
+		group:       group,
+	}
+}
+
+func (a *AdminRoute) Run() {
+	group := a.group.Group("/admin")
+	group.Use(
+		middlewares.Authenticate(a.services),
+		middlewares.CheckRole(constants.AdminRole),
+		middlewares.AuditAdminAction(a.services),
+	)
+	group.PUT("/users/:uuid/status", a.controllers.GetUserController().UpdateStatus)
+	group.PUT("/users/:uuid/role", a.controllers.GetUserController().UpdateRole)
+	group.POST("/users/:uuid/impersonate", a.controllers.GetUserController().Impersonate)
+	group.DELETE("/users/:uuid", a.controllers.GetUserController().Delete)
+
+	group.POST("/webhooks", a.controllers.GetWebhookController().Create)
+	group.GET("/webhooks", a.controllers.GetWebhookController().FindAll)
+	group.GET("/webhooks/:uuid", a.controllers.GetWebhookController().FindByUUID)
+	group.PUT("/webhooks/:uuid", a.controllers.GetWebhookController().Update)
+	group.DELETE("/webhooks/:uuid", a.controllers.GetWebhookController().Delete)
+	group.GET("/webhooks/:uuid/deliveries", a.controllers.GetWebhookController().FindDeliveries)
+	group.POST("/webhooks/:uuid/deliveries/:deliveryUuid/replay", a.controllers.GetWebhookController().Replay)
+}
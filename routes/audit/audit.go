@@ -0,0 +1,34 @@
+package routes
+
+import (
+	"user-service/constants"
+	"user-service/controllers"
+	"user-service/middlewares"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AuditRoute struct {
+	controllers controllers.IControllerRegistry
+	services    services.IServiceRegistery
+	group       *gin.RouterGroup
+}
+
+type IAuditRoute interface {
+	Run()
+}
+
+func NewAuditRoute(controllers controllers.IControllerRegistry, services services.IServiceRegistery, group *gin.RouterGroup) IAuditRoute {
+	return &AuditRoute{
+		controllers: controllers,
+		services:    services,
+		group:       group,
+	}
+}
+
+func (a *AuditRoute) Run() {
+	group := a.group.Group("/audit")
+	group.Use(middlewares.Authenticate(a.services), middlewares.CheckRole(constants.AdminRole))
+	group.GET("", a.controllers.GetAuditController().FindAll)
+}
@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"user-service/common/cache"
+	"user-service/controllers"
+	"user-service/middlewares"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InternalRoute struct {
+	controllers controllers.IControllerRegistry
+	services    services.IServiceRegistery
+	group       *gin.RouterGroup
+}
+
+type IInternalRoute interface {
+	Run()
+}
+
+func NewInternalRoute(controllers controllers.IControllerRegistry, services services.IServiceRegistery, group *gin.RouterGroup) IInternalRoute {
+	return &InternalRoute{
+		controllers: controllers,
+		services:    services,
+		group:       group,
+	}
+}
+
+// Run registers the endpoints meant for sibling services. They accept the
+// service api key instead of a user token.
+func (i *InternalRoute) Run() {
+	group := i.group.Group("/internal")
+	group.Use(middlewares.AuthenticateService())
+	group.POST("/users/batch", i.controllers.GetUserController().BatchLookup)
+	group.GET("/metrics", cache.ServeMetrics)
+}
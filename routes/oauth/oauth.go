@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"user-service/controllers"
+	"user-service/middlewares"
+	"user-service/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OAuthRoute struct {
+	controllers controllers.IControllerRegistry
+	services    services.IServiceRegistery
+	group       *gin.RouterGroup
+}
+
+type IOAuthRoute interface {
+	Run()
+}
+
+func NewOAuthRoute(controllers controllers.IControllerRegistry, services services.IServiceRegistery, group *gin.RouterGroup) IOAuthRoute {
+	return &OAuthRoute{
+		controllers: controllers,
+		services:    services,
+		group:       group,
+	}
+}
+
+func (o *OAuthRoute) Run() {
+	group := o.group.Group("/oauth")
+	group.POST("/introspect", middlewares.AuthenticateService(), o.controllers.GetUserController().Introspect)
+}
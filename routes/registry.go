@@ -1,32 +1,62 @@
 package routes
 
 import (
+	"user-service/common/ratelimit"
 	"user-service/controllers"
+	adminRoutes "user-service/routes/admin"
+	auditRoutes "user-service/routes/audit"
+	internalRoutes "user-service/routes/internal"
+	oauthRoutes "user-service/routes/oauth"
 	routes "user-service/routes/user"
+	"user-service/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Registry struct {
 	controller controllers.IControllerRegistry
+	service    services.IServiceRegistery
 	group      *gin.RouterGroup
+	limits     *ratelimit.Policies
 }
 
 type IRoutesRegistry interface {
 	Serve()
 }
 
-func NewRouteRegistry(controller controllers.IControllerRegistry, group *gin.RouterGroup) IRoutesRegistry {
+func NewRouteRegistry(controller controllers.IControllerRegistry, service services.IServiceRegistery, group *gin.RouterGroup, limits *ratelimit.Policies) IRoutesRegistry {
 	return &Registry{
 		controller: controller,
+		service:    service,
 		group:      group,
+		limits:     limits,
 	}
 }
 
 func (r *Registry) Serve() {
 	r.userRoute().Run()
+	r.adminRoute().Run()
+	r.auditRoute().Run()
+	r.internalRoute().Run()
+	r.oauthRoute().Run()
 }
 
 func (r *Registry) userRoute() routes.IUserRoute {
-	return routes.NewUserRoute(r.controller, r.group)
+	return routes.NewUserRoute(r.controller, r.service, r.group, r.limits)
+}
+
+func (r *Registry) adminRoute() adminRoutes.IAdminRoute {
+	return adminRoutes.NewAdminRoute(r.controller, r.service, r.group)
+}
+
+func (r *Registry) auditRoute() auditRoutes.IAuditRoute {
+	return auditRoutes.NewAuditRoute(r.controller, r.service, r.group)
+}
+
+func (r *Registry) internalRoute() internalRoutes.IInternalRoute {
+	return internalRoutes.NewInternalRoute(r.controller, r.service, r.group)
+}
+
+func (r *Registry) oauthRoute() oauthRoutes.IOAuthRoute {
+	return oauthRoutes.NewOAuthRoute(r.controller, r.service, r.group)
 }
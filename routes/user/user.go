@@ -1,33 +1,54 @@
 package routes
 
 import (
+	"user-service/common/ratelimit"
+	"user-service/constants"
 	"user-service/controllers"
 	"user-service/middlewares"
+	"user-service/services"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserRoute struct {
 	controllers controllers.IControllerRegistry
+	services    services.IServiceRegistery
 	group       *gin.RouterGroup
+	limits      *ratelimit.Policies
 }
 
 type IUserRoute interface {
 	Run()
 }
 
-func NewUserRoute(controllers controllers.IControllerRegistry, group *gin.RouterGroup) IUserRoute {
+func NewUserRoute(controllers controllers.IControllerRegistry, services services.IServiceRegistery, group *gin.RouterGroup, limits *ratelimit.Policies) IUserRoute {
 	return &UserRoute{
 		controllers: controllers,
+		services:    services,
 		group:       group,
+		limits:      limits,
 	}
 }
 
 func (u *UserRoute) Run() {
 	group := u.group.Group("/auth")
-	group.GET("/user", middlewares.Authenticate(), u.controllers.GetUserController().GetUserLogin)
-	group.GET("/:uuid", middlewares.Authenticate(), u.controllers.GetUserController().GetUserByUUID)
-	group.POST("/login", u.controllers.GetUserController().Login)
-	group.POST("/register", u.controllers.GetUserController().Register)
-	group.PUT("/:uuid", middlewares.Authenticate(), u.controllers.GetUserController().Update)
+	group.GET("/user", middlewares.Authenticate(u.services), u.controllers.GetUserController().GetUserLogin)
+	group.GET("/verify", middlewares.ForwardAuth(u.services), u.controllers.GetUserController().Verify)
+	group.GET("/:uuid", middlewares.Authenticate(u.services), u.controllers.GetUserController().GetUserByUUID)
+	group.POST("/login", u.limit(constants.RateLimitLogin), u.controllers.GetUserController().Login)
+	group.POST("/register", u.limit(constants.RateLimitRegister), u.controllers.GetUserController().Register)
+	group.POST("/password/change", u.limit(constants.RateLimitPassword), middlewares.Authenticate(u.services), u.controllers.GetUserController().ChangePassword)
+	group.POST("/email/confirm", u.limit(constants.RateLimitEmail), u.controllers.GetUserController().ConfirmEmailChange)
+	group.POST("/email/cancel", u.limit(constants.RateLimitEmail), u.controllers.GetUserController().CancelEmailChange)
+	group.PUT("/:uuid", middlewares.Authenticate(u.services), u.controllers.GetUserController().Update)
+	group.POST("/impersonation/stop", middlewares.Authenticate(u.services), u.controllers.GetUserController().StopImpersonation)
+
+	u.group.GET("/.well-known/jwks.json", u.controllers.GetUserController().JWKS)
+
+	users := u.group.Group("/users")
+	users.PATCH("/:uuid", middlewares.Authenticate(u.services), u.controllers.GetUserController().Patch)
+}
+
+func (u *UserRoute) limit(policy string) gin.HandlerFunc {
+	return middlewares.RateLimit(u.limits, policy)
 }
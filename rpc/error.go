@@ -0,0 +1,69 @@
+package rpc
+
+import (
+	"errors"
+	"strings"
+	errWrap "user-service/common/error"
+	errConstant "user-service/constants/error"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus turns a service error into a gRPC status. Like the HTTP
+// response, only errors listed in constants/error keep their message.
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if !errConstant.ErrMapping(err) {
+		return status.Error(codes.Internal, errConstant.ErrInternalServerError.Error())
+	}
+
+	code := codes.InvalidArgument
+	switch {
+	case errors.Is(err, errConstant.ErrSqlError), errors.Is(err, errConstant.ErrInternalServerError):
+		code = codes.Internal
+	case errors.Is(err, errConstant.ErrUnauthorized),
+		errors.Is(err, errConstant.ErrInvalidToken),
+		errors.Is(err, errConstant.ErrSessionNotFound),
+		errors.Is(err, errConstant.ErrSessionRevoked),
+		errors.Is(err, errConstant.ErrSessionExpired):
+		code = codes.Unauthenticated
+	case errors.Is(err, errConstant.ErrForbiden),
+		errors.Is(err, errConstant.ErrUserNotActive),
+		errors.Is(err, errConstant.ErrUserSuspended),
+		errors.Is(err, errConstant.ErrUserBanned):
+		code = codes.PermissionDenied
+	case errors.Is(err, errConstant.ErrUserNotFound):
+		code = codes.NotFound
+	case errors.Is(err, errConstant.ErrUsernameExist),
+		errors.Is(err, errConstant.ErrEmailExist),
+		errors.Is(err, errConstant.ErrPhoneNumberExist):
+		code = codes.AlreadyExists
+	case errors.Is(err, errConstant.ErrTooManyRequest):
+		code = codes.ResourceExhausted
+	}
+
+	return status.Error(code, err.Error())
+}
+
+// validationStatus reports validator errors with the same messages the HTTP
+// controllers return, joined into one string.
+func validationStatus(err error) error {
+	messages := make([]string, 0)
+	for _, item := range errWrap.ErrValidationResponse(err) {
+		messages = append(messages, item.Message)
+	}
+
+	if len(messages) == 0 {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.InvalidArgument, strings.Join(messages, "; "))
+}
@@ -0,0 +1,218 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime/debug"
+	"strings"
+	"user-service/common/ratelimit"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/database/resolver"
+	"user-service/middlewares"
+	serviceRegistry "user-service/services"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// access is who may call a method, mirroring the middlewares in front of
+// the matching HTTP route.
+type access int
+
+const (
+	accessPublic access = iota
+	accessUser
+	accessService
+)
+
+// recoverPanic is the gRPC counterpart of middlewares.HandlePanic.
+func recoverPanic() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				logrus.Errorf("Recovered from panic in %s: %v\n%s", info.FullMethod, r, debug.Stack())
+				err = status.Error(codes.Internal, errConstant.ErrInternalServerError.Error())
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// requestMetadata is the gRPC counterpart of middlewares.RequestMetadata.
+func requestMetadata() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		requestID := firstMetadata(ctx, constants.XRequestID)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+
+		ctx = context.WithValue(ctx, constants.RequestID, requestID)
+		ctx = context.WithValue(ctx, constants.ClientIP, clientIP(ctx))
+		ctx = context.WithValue(ctx, constants.UserAgent, firstMetadata(ctx, "user-agent"))
+		if strings.EqualFold(firstMetadata(ctx, constants.XReadPrimary), "true") {
+			ctx = resolver.WithPrimary(ctx)
+		}
+
+		_ = grpc.SetHeader(ctx, metadata.Pairs(constants.XRequestID, requestID))
+		return handler(ctx, req)
+	}
+}
+
+// rateLimit shares the policies of the HTTP router, so a client gets one
+// budget whichever protocol it uses. Every call counts against the default
+// policy and the one methods names, with the method as its route.
+func rateLimit(policies *ratelimit.Policies, methods map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		names := []string{ratelimit.DefaultPolicy}
+		if name, ok := methods[info.FullMethod]; ok {
+			names = append(names, name)
+		}
+
+		headers := metadata.MD{}
+		defer func() { _ = grpc.SetHeader(ctx, headers) }()
+
+		for _, name := range names {
+			policy, ok := policies.Get(name)
+			if !ok {
+				continue
+			}
+
+			subject := rateLimitSubject(ctx, info.FullMethod, policy)
+			if policy.Exempt(subject) {
+				continue
+			}
+
+			result, err := policy.Allow(ctx, subject)
+			if err != nil {
+				logrus.Warnf("rate limit %s unavailable: %v", policy.Name, err)
+				continue
+			}
+
+			for key, value := range policy.Headers(result) {
+				headers.Set(key, value)
+			}
+
+			if !result.Allowed {
+				return nil, status.Error(codes.ResourceExhausted, errConstant.ErrTooManyRequest.Error())
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// rateLimitSubject is the gRPC counterpart of the HTTP one, the user and
+// service only count with a valid token or api key.
+func rateLimitSubject(ctx context.Context, method string, policy *ratelimit.Policy) ratelimit.Subject {
+	subject := ratelimit.Subject{IP: clientIP(ctx), Route: method}
+
+	if policy.Needs(ratelimit.KeyUser) && firstMetadata(ctx, constants.Authorization) != "" {
+		if claims, err := middlewares.ParseBearerToken(firstMetadata(ctx, constants.Authorization)); err == nil && claims.User != nil {
+			subject.User = claims.User.UUID.String()
+		}
+	}
+
+	if policy.Needs(ratelimit.KeyService) && firstMetadata(ctx, constants.XServiceName) != "" {
+		if validateAPIKey(ctx) == nil {
+			subject.Service = firstMetadata(ctx, constants.XServiceName)
+		}
+	}
+
+	return subject
+}
+
+// authenticate applies the access rule of each method. Methods missing from
+// rules, like health checks and reflection, are public.
+func authenticate(service serviceRegistry.IServiceRegistery, rules map[string]access) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		switch rules[info.FullMethod] {
+		case accessService:
+			err := validateAPIKey(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			serviceName := firstMetadata(ctx, constants.XServiceName)
+			if !middlewares.IsInternalService(serviceName) {
+				return nil, status.Error(codes.PermissionDenied, errConstant.ErrForbiden.Error())
+			}
+			ctx = context.WithValue(ctx, constants.ServiceName, serviceName)
+		case accessUser:
+			claims, err := middlewares.ParseBearerToken(firstMetadata(ctx, constants.Authorization))
+			if err != nil {
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+
+			err = validateAPIKey(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			ctx = middlewares.WithClaims(ctx, claims)
+			err = middlewares.CheckUserLogin(ctx, service)
+			if err != nil {
+				// a user that no longer exists is a bad token, as over HTTP
+				if errors.Is(err, errConstant.ErrUserNotFound) {
+					return nil, statusUnauthenticated()
+				}
+				return nil, toStatus(err)
+			}
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func validateAPIKey(ctx context.Context) error {
+	err := middlewares.ValidateAPIKey(
+		firstMetadata(ctx, constants.XServiceName),
+		firstMetadata(ctx, constants.XRequestAt),
+		firstMetadata(ctx, constants.XApiKey),
+	)
+	if err != nil {
+		return statusUnauthenticated()
+	}
+
+	return nil
+}
+
+func statusUnauthenticated() error {
+	return status.Error(codes.Unauthenticated, errConstant.ErrUnauthorized.Error())
+}
+
+// firstMetadata reads an incoming header. gRPC lower cases metadata keys.
+func firstMetadata(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	values := md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+
+	return values[0]
+}
+
+func clientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+
+	return host
+}
@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"user-service/common/ratelimit"
+	"user-service/constants"
+	"user-service/proto/pb"
+	serviceRegistry "user-service/services"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+var rules = map[string]access{
+	pb.UserService_Login_FullMethodName:         accessPublic,
+	pb.UserService_Register_FullMethodName:      accessPublic,
+	pb.UserService_GetUser_FullMethodName:       accessUser,
+	pb.UserService_BatchGetUsers_FullMethodName: accessService,
+	pb.UserService_VerifyToken_FullMethodName:   accessService,
+}
+
+// limits are the rate limit policies of methods that have a stricter one
+// than the default, the same as their HTTP routes.
+var limits = map[string]string{
+	pb.UserService_Login_FullMethodName:    constants.RateLimitLogin,
+	pb.UserService_Register_FullMethodName: constants.RateLimitRegister,
+}
+
+// NewServer builds the gRPC server that runs beside the Gin router, with
+// health checking and reflection registered.
+func NewServer(service serviceRegistry.IServiceRegistery, policies *ratelimit.Policies) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		recoverPanic(),
+		requestMetadata(),
+		rateLimit(policies, limits),
+		authenticate(service, rules),
+	))
+
+	pb.RegisterUserServiceServer(server, NewUserServer(service))
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(pb.UserService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+
+	return server
+}
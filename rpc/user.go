@@ -0,0 +1,193 @@
+package rpc
+
+import (
+	"context"
+	"user-service/domain/dto"
+	"user-service/proto/pb"
+	serviceRegistry "user-service/services"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// UserServer serves the gRPC user API from the same services as the HTTP
+// controllers.
+type UserServer struct {
+	pb.UnimplementedUserServiceServer
+	service serviceRegistry.IServiceRegistery
+}
+
+func NewUserServer(service serviceRegistry.IServiceRegistery) pb.UserServiceServer {
+	return &UserServer{
+		service: service,
+	}
+}
+
+func (u *UserServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	request := &dto.LoginRequest{
+		Username: req.GetUsername(),
+		Password: req.GetPassword(),
+	}
+
+	err := validator.New().Struct(request)
+	if err != nil {
+		return nil, validationStatus(err)
+	}
+
+	user, err := u.service.GetUser().Login(ctx, request)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.LoginResponse{
+		User:  toUser(&user.User),
+		Token: user.Token,
+	}, nil
+}
+
+func (u *UserServer) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	request := &dto.RegisterRequest{
+		Name:            req.GetName(),
+		Username:        req.GetUsername(),
+		Email:           req.GetEmail(),
+		Password:        req.GetPassword(),
+		ConfirmPassword: req.GetConfirmPassword(),
+		PhoneNumber:     req.GetPhoneNumber(),
+	}
+
+	err := validator.New().Struct(request)
+	if err != nil {
+		return nil, validationStatus(err)
+	}
+
+	user, err := u.service.GetUser().Register(ctx, request)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.RegisterResponse{
+		User: toUser(&user.User),
+	}, nil
+}
+
+func (u *UserServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.GetUserResponse, error) {
+	request := struct {
+		UUID string `validate:"required,uuid"`
+	}{UUID: req.GetUuid()}
+
+	err := validator.New().Struct(request)
+	if err != nil {
+		return nil, validationStatus(err)
+	}
+
+	user, err := u.service.GetUser().GetUserByUUID(ctx, request.UUID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &pb.GetUserResponse{
+		User: toUser(user),
+	}, nil
+}
+
+func (u *UserServer) BatchGetUsers(ctx context.Context, req *pb.BatchGetUsersRequest) (*pb.BatchGetUsersResponse, error) {
+	request := &dto.BatchUserRequest{
+		UUIDs:  req.GetUuids(),
+		Fields: req.GetFields(),
+	}
+
+	err := validator.New().Struct(request)
+	if err != nil {
+		return nil, validationStatus(err)
+	}
+
+	result, err := u.service.GetUser().FindByUUIDs(ctx, request)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	users := make([]*pb.User, 0, len(result.Users))
+	for _, item := range result.Users {
+		users = append(users, toPartialUser(item))
+	}
+
+	return &pb.BatchGetUsersResponse{
+		Users:   users,
+		Missing: result.Missing,
+	}, nil
+}
+
+// VerifyToken answers from the introspection result, so revoked sessions
+// and inactive users come back as not active. The user is the one the
+// introspection loaded, it is not looked up again.
+func (u *UserServer) VerifyToken(ctx context.Context, req *pb.VerifyTokenRequest) (*pb.VerifyTokenResponse, error) {
+	request := &dto.IntrospectRequest{Token: req.GetToken()}
+
+	err := validator.New().Struct(request)
+	if err != nil {
+		return nil, validationStatus(err)
+	}
+
+	result, err := u.service.GetUser().Introspect(ctx, request)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	if !result.Active {
+		return &pb.VerifyTokenResponse{Active: false}, nil
+	}
+
+	response := &pb.VerifyTokenResponse{
+		Active:    true,
+		User:      toUser(result.User),
+		SessionId: result.Jti,
+		ExpiresAt: result.Exp,
+		Scope:     result.Scope,
+	}
+	if result.Session != nil && result.Session.Impersonator != nil {
+		response.ImpersonatorUuid = result.Session.Impersonator.String()
+	}
+
+	return response, nil
+}
+
+func toUser(user *dto.UserResponse) *pb.User {
+	return &pb.User{
+		Uuid:        user.UUID.String(),
+		Name:        user.Name,
+		Username:    user.Username,
+		Email:       user.Email,
+		Role:        user.Role,
+		PhoneNumber: user.PhoneNumber,
+	}
+}
+
+// toPartialUser fills the fields a batch lookup selected and leaves the
+// others empty.
+func toPartialUser(fields map[string]interface{}) *pb.User {
+	user := &pb.User{}
+	for field, value := range fields {
+		text := ""
+		if stringer, ok := value.(interface{ String() string }); ok {
+			text = stringer.String()
+		} else if str, ok := value.(string); ok {
+			text = str
+		}
+
+		switch field {
+		case "uuid":
+			user.Uuid = text
+		case "name":
+			user.Name = text
+		case "username":
+			user.Username = text
+		case "email":
+			user.Email = text
+		case "role":
+			user.Role = text
+		case "phoneNumber":
+			user.PhoneNumber = text
+		}
+	}
+
+	return user
+}
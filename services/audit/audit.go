@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+	"user-service/common/audit"
+	"user-service/config"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/domain/dto"
+	"user-service/domain/models"
+	"user-service/repositories"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditService struct {
+	repository repositories.IRepositoryRegistry
+}
+
+type IAuditService interface {
+	Record(context.Context, *models.AuditLog)
+	FindAll(context.Context, *dto.AuditFilterRequest) (*dto.AuditListResponse, error)
+	Verify(context.Context) (*dto.AuditVerifyResult, error)
+	Checkpoint(context.Context) (*audit.Checkpoint, error)
+	VerifyCheckpoint(context.Context, *audit.Checkpoint, string) error
+}
+
+const verifyBatchSize = 500
+
+func NewAuditService(repository repositories.IRepositoryRegistry) IAuditService {
+	return &AuditService{
+		repository: repository,
+	}
+}
+
+// NewAuditLog builds an audit entry carrying the request metadata found in
+// ctx. When actor is nil the logged in user, if any, is used instead, and
+// requests made under impersonation also record the acting admin.
+func NewAuditLog(ctx context.Context, event string, actor, target *uuid.UUID, metadata interface{}) *models.AuditLog {
+	log := &models.AuditLog{
+		UUID:       uuid.New(),
+		Event:      event,
+		ActorUUID:  actor,
+		TargetUUID: target,
+	}
+
+	if log.ActorUUID == nil {
+		if userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse); ok {
+			actorUUID := userLogin.UUID
+			log.ActorUUID = &actorUUID
+		}
+	}
+
+	if impersonator, ok := ctx.Value(constants.Impersonator).(*dto.UserResponse); ok {
+		impersonatorUUID := impersonator.UUID
+		log.ImpersonatorUUID = &impersonatorUUID
+	}
+
+	if ip, ok := ctx.Value(constants.ClientIP).(string); ok {
+		log.IPAddress = ip
+	}
+	if userAgent, ok := ctx.Value(constants.UserAgent).(string); ok {
+		log.UserAgent = userAgent
+	}
+	if requestID, ok := ctx.Value(constants.RequestID).(string); ok {
+		log.RequestID = requestID
+	}
+
+	if metadata != nil {
+		raw, err := json.Marshal(metadata)
+		if err != nil {
+			logrus.Errorf("failed to marshal audit metadata: %v", err)
+		} else {
+			log.Metadata = raw
+		}
+	}
+
+	return log
+}
+
+// adminAction is the admin request being served. The service making its
+// change writes it, once, in the transaction of that change.
+type adminAction struct {
+	target   *uuid.UUID
+	metadata map[string]interface{}
+	recorded bool
+}
+
+// WithAdminAction marks ctx as serving an admin request on target.
+func WithAdminAction(ctx context.Context, target *uuid.UUID, metadata map[string]interface{}) context.Context {
+	return context.WithValue(ctx, constants.AdminAction, &adminAction{target: target, metadata: metadata})
+}
+
+// RecordAdminAction writes the admin action of ctx with tx, so the entry
+// commits or rolls back together with the change it describes. It does
+// nothing outside an admin request or when the action was already written.
+func RecordAdminAction(ctx context.Context, tx repositories.IRepositoryRegistry) error {
+	action, ok := ctx.Value(constants.AdminAction).(*adminAction)
+	if !ok || action.recorded {
+		return nil
+	}
+
+	_, err := tx.GetAudit().Create(ctx,
+		NewAuditLog(ctx, constants.AuditEventAdminAction, nil, action.target, action.metadata))
+	if err != nil {
+		return err
+	}
+
+	action.recorded = true
+	return nil
+}
+
+// AdminActionRecorded reports whether a service wrote the admin action of
+// ctx. A transaction that rolled back afterwards still counts.
+func AdminActionRecorded(ctx context.Context) bool {
+	action, ok := ctx.Value(constants.AdminAction).(*adminAction)
+	return ok && action.recorded
+}
+
+// Diff returns the fields whose value differs between before and after.
+// Values of redacted fields are never written to the audit trail.
+func Diff(before, after map[string]interface{}, redacted ...string) map[string]dto.FieldChange {
+	changes := make(map[string]dto.FieldChange)
+
+	for field, newValue := range after {
+		oldValue := before[field]
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+
+		change := dto.FieldChange{Old: oldValue, New: newValue}
+		for _, item := range redacted {
+			if item == field {
+				change = dto.FieldChange{Old: constants.AuditRedacted, New: constants.AuditRedacted}
+			}
+		}
+
+		changes[field] = change
+	}
+
+	return changes
+}
+
+// Record writes an audit entry outside of any transaction. It is meant for
+// events that have no state change to go with them, such as a failed login,
+// so a failure is logged rather than returned.
+func (a *AuditService) Record(ctx context.Context, log *models.AuditLog) {
+	_, err := a.repository.GetAudit().Create(ctx, log)
+	if err != nil {
+		logrus.Errorf("failed to record audit event %s: %v", log.Event, err)
+	}
+}
+
+func (a *AuditService) FindAll(ctx context.Context, req *dto.AuditFilterRequest) (*dto.AuditListResponse, error) {
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	logs, total, err := a.repository.GetAudit().FindAll(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.AuditLogResponse, 0, len(logs))
+	for _, log := range logs {
+		items = append(items, dto.AuditLogResponse{
+			UUID:             log.UUID,
+			Event:            log.Event,
+			ActorUUID:        log.ActorUUID,
+			TargetUUID:       log.TargetUUID,
+			ImpersonatorUUID: log.ImpersonatorUUID,
+			IPAddress:        log.IPAddress,
+			UserAgent:        log.UserAgent,
+			RequestID:        log.RequestID,
+			Metadata:         log.Metadata,
+			CreatedAt:        log.CreatedAt,
+		})
+	}
+
+	data := dto.AuditListResponse{
+		Items: items,
+		Page:  req.Page,
+		Limit: req.Limit,
+		Total: total,
+	}
+
+	return &data, nil
+}
+
+// chainWalk follows every hash chain of the audit log in id order. Entries
+// written before the chain existed carry no hash and are skipped.
+type chainWalk struct {
+	result  *dto.AuditVerifyResult
+	heads   map[string]string
+	started bool
+}
+
+func newChainWalk() *chainWalk {
+	return &chainWalk{
+		result: &dto.AuditVerifyResult{Valid: true},
+		heads:  map[string]string{},
+	}
+}
+
+// next checks log against the entry before it on its chain and returns
+// ErrAuditChainBroken when it does not match.
+func (w *chainWalk) next(log *models.AuditLog) error {
+	if !w.started && log.Hash == "" && log.Chain == "" {
+		w.result.Unchained++
+		return nil
+	}
+	w.started = true
+	w.result.Checked++
+
+	if log.PrevHash != w.heads[log.Chain] {
+		w.result.Valid = false
+		w.result.BrokenAtID = log.ID
+		w.result.Reason = fmt.Sprintf("entry %d does not link to the entry before it", log.ID)
+		return errConstant.ErrAuditChainBroken
+	}
+
+	if audit.ComputeHash(log.PrevHash, log) != log.Hash {
+		w.result.Valid = false
+		w.result.BrokenAtID = log.ID
+		w.result.Reason = fmt.Sprintf("entry %d content does not match its hash", log.ID)
+		return errConstant.ErrAuditChainBroken
+	}
+
+	w.heads[log.Chain] = log.Hash
+	w.result.LastID = log.ID
+	w.result.LastHash = log.Hash
+	return nil
+}
+
+// walk runs a chain walk over the entries up to lastID, or over all of them
+// when lastID is zero.
+func (a *AuditService) walk(ctx context.Context, lastID uint) (*chainWalk, error) {
+	walk := newChainWalk()
+	errStop := errors.New("past the last entry")
+
+	err := a.repository.GetAudit().FindInBatches(ctx, verifyBatchSize, func(logs []models.AuditLog) error {
+		for i := range logs {
+			if lastID != 0 && logs[i].ID > lastID {
+				return errStop
+			}
+
+			err := walk.next(&logs[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) && !errors.Is(err, errConstant.ErrAuditChainBroken) {
+		return nil, err
+	}
+
+	return walk, nil
+}
+
+// Verify recomputes every hash chain from its first entry and stops at the
+// first entry whose content or link to its predecessor does not match.
+func (a *AuditService) Verify(ctx context.Context) (*dto.AuditVerifyResult, error) {
+	walk, err := a.walk(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return walk.result, nil
+}
+
+// Checkpoint signs the latest entry together with the head of every chain.
+// A log that does not verify is not checkpointed.
+func (a *AuditService) Checkpoint(ctx context.Context) (*audit.Checkpoint, error) {
+	last, err := a.repository.GetAudit().FindLast(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	walk, err := a.walk(ctx, last.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !walk.result.Valid {
+		return nil, errConstant.ErrAuditChainBroken
+	}
+
+	checkpoint := &audit.Checkpoint{
+		LastID:      last.ID,
+		LastHash:    last.Hash,
+		Count:       walk.result.Checked + walk.result.Unchained,
+		HeadsDigest: audit.HeadsDigest(walk.heads),
+		CreatedAt:   time.Now(),
+	}
+
+	err = audit.SignCheckpoint(checkpoint, config.Config.AuditSigningKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+// VerifyCheckpoint checks the checkpoint signature, that the entry it points
+// at still carries the same hash and that every chain still ends where it
+// did, which together with Verify proves nothing up to that point was
+// edited, removed or appended out of order.
+func (a *AuditService) VerifyCheckpoint(ctx context.Context, checkpoint *audit.Checkpoint, publicKey string) error {
+	err := audit.VerifyCheckpoint(checkpoint, publicKey)
+	if err != nil {
+		return err
+	}
+
+	log, err := a.repository.GetAudit().FindByID(ctx, checkpoint.LastID)
+	if err != nil {
+		return err
+	}
+
+	if log.Hash != checkpoint.LastHash {
+		return errConstant.ErrAuditChainBroken
+	}
+
+	// checkpoints taken with a single chain only vouch for their last entry
+	if checkpoint.HeadsDigest == "" {
+		return nil
+	}
+
+	walk, err := a.walk(ctx, checkpoint.LastID)
+	if err != nil {
+		return err
+	}
+	if !walk.result.Valid || audit.HeadsDigest(walk.heads) != checkpoint.HeadsDigest {
+		return errConstant.ErrAuditChainBroken
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+	"user-service/common/broker"
+	"user-service/config"
+	"user-service/constants"
+	"user-service/domain/dto"
+	"user-service/domain/models"
+	"user-service/repositories"
+	webhookServices "user-service/services/webhook"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type OutboxService struct {
+	repository repositories.IRepositoryRegistry
+	broker     broker.IBroker
+}
+
+type IOutboxService interface {
+	Run(context.Context)
+	PublishPending(context.Context) (int, error)
+}
+
+const (
+	defaultRelayIntervalSecond = 5
+	defaultRelayBatchSize      = 100
+	maxRetryBackoff            = 5 * time.Minute
+)
+
+func NewOutboxService(repository repositories.IRepositoryRegistry, broker broker.IBroker) IOutboxService {
+	return &OutboxService{
+		repository: repository,
+		broker:     broker,
+	}
+}
+
+// NewUserEvent builds the outbox entry announcing that user changed. It is
+// meant to be created in the same transaction as the change itself, so the
+// event exists exactly when the change was committed.
+func NewUserEvent(ctx context.Context, event string, user *models.User, changes map[string]dto.FieldChange) (*models.OutboxEvent, error) {
+	payload := dto.UserEvent{
+		ID:         uuid.New(),
+		Type:       event,
+		OccurredAt: time.Now(),
+		Data: dto.UserEventData{
+			UUID:        user.UUID,
+			Name:        user.Name,
+			Username:    user.Username,
+			Email:       user.Email,
+			PhoneNumber: user.PhoneNumber,
+			Status:      user.Status,
+			Changes:     changes,
+		},
+	}
+
+	if user.Role.Code != "" {
+		payload.Data.Role = user.Role.Code
+	}
+	if requestID, ok := ctx.Value(constants.RequestID).(string); ok {
+		payload.RequestID = requestID
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s event: %w", event, err)
+	}
+
+	return &models.OutboxEvent{
+		UUID:          payload.ID,
+		Topic:         event,
+		AggregateUUID: user.UUID,
+		Payload:       raw,
+		NextAttemptAt: payload.OccurredAt,
+	}, nil
+}
+
+// CreateUserEvent writes the event NewUserEvent builds within tx, an event
+// that cannot be built fails the change.
+func CreateUserEvent(
+	ctx context.Context,
+	tx repositories.IRepositoryRegistry,
+	event string,
+	user *models.User,
+	changes map[string]dto.FieldChange,
+) error {
+	outboxEvent, err := NewUserEvent(ctx, event, user, changes)
+	if err != nil {
+		return err
+	}
+
+	return tx.GetOutbox().Create(ctx, outboxEvent)
+}
+
+// Run relays pending events until ctx is cancelled. A batch in flight stops
+// with it, PublishPending keeps what it already published.
+func (o *OutboxService) Run(ctx context.Context) {
+	interval := config.Config.Outbox.RelayIntervalSecond
+	if interval == 0 {
+		interval = defaultRelayIntervalSecond
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		for {
+			published, err := o.PublishPending(ctx)
+			if err != nil && ctx.Err() == nil {
+				logrus.Errorf("failed to relay outbox events: %v", err)
+			}
+			// keep draining while full batches come back
+			if err != nil || published < batchSize() || ctx.Err() != nil {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending hands one batch of due events to the broker, queues their
+// webhook deliveries, and returns how many were published. The batch stays
+// locked until it is marked, so several relays can run side by side without
+// sending an event twice in the same round. An event whose publish fails is retried later with an
+// exponential backoff; one that was published but could not be marked is
+// sent again, which is why delivery is at least once. Once ctx is done the
+// events not published yet are left for the next round, and the ones that
+// were are still marked.
+func (o *OutboxService) PublishPending(ctx context.Context) (int, error) {
+	// rolling back would publish the events handed over so far again
+	record := context.WithoutCancel(ctx)
+
+	published := 0
+	err := o.repository.WithTransaction(record, func(tx repositories.IRepositoryRegistry) error {
+		events, err := tx.GetOutbox().FindPending(record, batchSize())
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if ctx.Err() != nil {
+				break
+			}
+
+			err = o.broker.Publish(ctx, &broker.Message{
+				ID:      event.UUID.String(),
+				Topic:   event.Topic,
+				Key:     event.AggregateUUID.String(),
+				Payload: event.Payload,
+			})
+			if err != nil {
+				// a publish cut off by shutdown says nothing about the broker
+				if ctx.Err() != nil {
+					break
+				}
+
+				logrus.Warnf("failed to publish event %s: %v", event.UUID, err)
+				err = tx.GetOutbox().MarkFailed(record, event.ID, err.Error(), time.Now().Add(retryBackoff(event.Attempts)))
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			// deliveries are queued once, with the publish that succeeded
+			err = webhookServices.EnqueueDeliveries(record, tx, &event)
+			if err != nil {
+				return err
+			}
+
+			err = tx.GetOutbox().MarkPublished(record, event.ID)
+			if err != nil {
+				return err
+			}
+			published++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return published, nil
+}
+
+func batchSize() int {
+	if config.Config.Outbox.BatchSize > 0 {
+		return config.Config.Outbox.BatchSize
+	}
+
+	return defaultRelayBatchSize
+}
+
+func retryBackoff(attempts int) time.Duration {
+	backoff := time.Duration(math.Pow(2, float64(attempts))) * time.Second
+	if backoff <= 0 || backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+
+	return backoff
+}
@@ -1,22 +1,44 @@
 package services
 
 import (
+	"user-service/common/broker"
+	"user-service/common/mailer"
 	"user-service/repositories"
+	auditServices "user-service/services/audit"
+	outboxServices "user-service/services/outbox"
 	services "user-service/services/user"
+	webhookServices "user-service/services/webhook"
 )
 
 type Registry struct {
 	repository repositories.IRepositoryRegistry
+	mailer     mailer.IMailer
+	broker     broker.IBroker
 }
 
 type IServiceRegistery interface {
 	GetUser() services.IUserService
+	GetAudit() auditServices.IAuditService
+	GetOutbox() outboxServices.IOutboxService
+	GetWebhook() webhookServices.IWebhookService
 }
 
-func NewServiceRegistry(repository repositories.IRepositoryRegistry) IServiceRegistery {
-	return &Registry{repository: repository}
+func NewServiceRegistry(repository repositories.IRepositoryRegistry, mailer mailer.IMailer, broker broker.IBroker) IServiceRegistery {
+	return &Registry{repository: repository, mailer: mailer, broker: broker}
 }
 
 func (r *Registry) GetUser() services.IUserService {
-	return services.NewUserService(r.repository)
+	return services.NewUserService(r.repository, r.mailer)
+}
+
+func (r *Registry) GetAudit() auditServices.IAuditService {
+	return auditServices.NewAuditService(r.repository)
+}
+
+func (r *Registry) GetOutbox() outboxServices.IOutboxService {
+	return outboxServices.NewOutboxService(r.repository, r.broker)
+}
+
+func (r *Registry) GetWebhook() webhookServices.IWebhookService {
+	return webhookServices.NewWebhookService(r.repository)
 }
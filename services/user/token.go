@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"user-service/common/auth"
+	"user-service/config"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/domain/dto"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKeys are the RSA keys tokens are signed and checked with.
+type signingKeys struct {
+	private *rsa.PrivateKey
+	kid     string
+	// public holds the current key and the one it replaced by key id
+	public map[string]*rsa.PublicKey
+	jwks   []auth.JWK
+	err    error
+}
+
+var (
+	signingKeysOnce sync.Once
+	loadedKeys      signingKeys
+)
+
+// loadSigningKeys parses the configured RSA keys once. Without a key tokens
+// are signed with the shared HS256 secret and no JWKS is published.
+func loadSigningKeys() *signingKeys {
+	signingKeysOnce.Do(func() {
+		loadedKeys = parseSigningKeys()
+	})
+
+	return &loadedKeys
+}
+
+func parseSigningKeys() signingKeys {
+	keys := signingKeys{public: map[string]*rsa.PublicKey{}}
+	if config.Config.JwtPrivateKey == "" {
+		return keys
+	}
+
+	key, err := auth.ParseRSAPrivateKey(config.Config.JwtPrivateKey)
+	if err != nil {
+		keys.err = fmt.Errorf("jwt private key is invalid: %w", err)
+		return keys
+	}
+
+	current := auth.NewJWK(&key.PublicKey, config.Config.JwtKeyID)
+	keys.private = key
+	keys.kid = current.Kid
+	keys.public[current.Kid] = &key.PublicKey
+	keys.jwks = append(keys.jwks, current)
+
+	if config.Config.JwtPreviousPublicKey == "" {
+		return keys
+	}
+
+	previousKey, err := auth.ParseRSAPublicKey(config.Config.JwtPreviousPublicKey)
+	if err != nil {
+		return signingKeys{err: fmt.Errorf("jwt previous public key is invalid: %w", err)}
+	}
+
+	previous := auth.NewJWK(previousKey, config.Config.JwtPreviousKeyID)
+	if previous.Kid == current.Kid {
+		return signingKeys{err: errors.New("jwt previous key has the key id of the current key")}
+	}
+	keys.public[previous.Kid] = previousKey
+	keys.jwks = append(keys.jwks, previous)
+
+	return keys
+}
+
+// CheckSigningKey reports whether tokens can be signed with the configured
+// key. The server refuses to start when it fails, a key that does not parse
+// must not quietly leave tokens signed with the shared secret.
+func CheckSigningKey() error {
+	keys := loadSigningKeys()
+	if keys.err != nil {
+		return keys.err
+	}
+	if keys.private == nil && config.Config.JwtSecretKey == "" {
+		return errors.New("no jwt signing key is configured")
+	}
+
+	return nil
+}
+
+func generateToken(claims *auth.Claims) (string, error) {
+	keys := loadSigningKeys()
+	if keys.err != nil {
+		return "", keys.err
+	}
+
+	if keys.private == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(config.Config.JwtSecretKey))
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keys.kid
+	return token.SignedString(keys.private)
+}
+
+// Keyfunc resolves the key a token was signed with. HS256 tokens are only
+// accepted while no RSA key is configured, or with jwtAcceptHs256 while the
+// tokens from before the switch expire.
+func Keyfunc(token *jwt.Token) (interface{}, error) {
+	keys := loadSigningKeys()
+	if keys.err != nil {
+		return nil, errConstant.ErrInvalidToken
+	}
+
+	switch token.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.public[kid]
+		if !ok {
+			return nil, errConstant.ErrInvalidToken
+		}
+		return key, nil
+	case *jwt.SigningMethodHMAC:
+		if config.Config.JwtSecretKey == "" || (keys.private != nil && !config.Config.JwtAcceptHS256) {
+			return nil, errConstant.ErrInvalidToken
+		}
+		return []byte(config.Config.JwtSecretKey), nil
+	default:
+		return nil, errConstant.ErrInvalidToken
+	}
+}
+
+// JWKS publishes the current key and the one it replaced, so verifiers keep
+// accepting tokens signed before a rotation.
+func (u *UserService) JWKS() *auth.JWKS {
+	keys := loadSigningKeys()
+
+	jwks := &auth.JWKS{Keys: make([]auth.JWK, 0, len(keys.jwks))}
+	jwks.Keys = append(jwks.Keys, keys.jwks...)
+
+	return jwks
+}
+
+// Introspect reports whether a token is currently usable. Besides the
+// signature and expiry it requires the session to be live and the user, and
+// the admin behind an impersonation, to be active. Only database failures
+// are returned as errors, every other problem makes the token inactive.
+func (u *UserService) Introspect(ctx context.Context, req *dto.IntrospectRequest) (*dto.IntrospectResponse, error) {
+	inactive := &dto.IntrospectResponse{Active: false}
+
+	claims := &auth.Claims{}
+	token, err := jwt.ParseWithClaims(req.Token, claims, Keyfunc)
+	if err != nil || !token.Valid || claims.User == nil {
+		return inactive, nil
+	}
+
+	session, err := u.findActiveSession(ctx, claims.ID)
+	if err != nil {
+		return inactiveOrError(inactive, err)
+	}
+
+	user, err := u.repository.GetUser().FindByUUID(ctx, claims.User.UUID.String())
+	if err != nil {
+		return inactiveOrError(inactive, err)
+	}
+
+	if checkStatus(user) != nil {
+		return inactive, nil
+	}
+
+	if claims.Impersonator != nil {
+		err = u.CheckUserStatus(ctx, claims.Impersonator.UUID.String())
+		if err != nil {
+			return inactiveOrError(inactive, err)
+		}
+	}
+
+	role := strings.ToLower(user.Role.Code)
+	scopes := append([]string{}, constants.RoleScopes[role]...)
+	for _, permission := range config.Config.UserPermissions[user.UUID.String()] {
+		if !slices.Contains(scopes, permission) {
+			scopes = append(scopes, permission)
+		}
+	}
+
+	response := &dto.IntrospectResponse{
+		Active:    true,
+		Scope:     strings.Join(scopes, " "),
+		Username:  user.Username,
+		TokenType: "Bearer",
+		Sub:       user.UUID.String(),
+		Jti:       claims.ID,
+		Role:      role,
+		User: &dto.UserResponse{
+			UUID:        user.UUID,
+			Name:        user.Name,
+			Username:    user.Username,
+			Email:       user.Email,
+			PhoneNumber: user.PhoneNumber,
+			Role:        role,
+			Version:     user.Version,
+		},
+		Session: &dto.IntrospectSession{
+			ID:           session.UUID,
+			CreatedAt:    session.CreatedAt,
+			ExpiresAt:    session.ExpiresAt,
+			Impersonator: session.ImpersonatorUUID,
+		},
+	}
+	if claims.ExpiresAt != nil {
+		response.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response.Iat = claims.IssuedAt.Unix()
+	}
+
+	return response, nil
+}
+
+func inactiveOrError(inactive *dto.IntrospectResponse, err error) (*dto.IntrospectResponse, error) {
+	if errors.Is(err, errConstant.ErrSqlError) {
+		return nil, err
+	}
+
+	return inactive, nil
+}
@@ -2,22 +2,32 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
+	"user-service/common/auth"
 	errWrap "user-service/common/error"
+	"user-service/common/mailer"
+	"user-service/common/util"
 	"user-service/config"
 	"user-service/constants"
 	errConstant "user-service/constants/error"
+	"user-service/database/resolver"
 	"user-service/domain/dto"
 	"user-service/domain/models"
 	"user-service/repositories"
+	auditServices "user-service/services/audit"
+	outboxServices "user-service/services/outbox"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type UserService struct {
 	repository repositories.IRepositoryRegistry
+	mailer     mailer.IMailer
 }
 
 type IUserService interface {
@@ -26,31 +36,57 @@ type IUserService interface {
 	Update(context.Context, *dto.UpdateRequest, string) (*dto.UserResponse, error)
 	GetUserLogin(context.Context) (*dto.UserResponse, error)
 	GetUserByUUID(context.Context, string) (*dto.UserResponse, error)
+	UpdateStatus(context.Context, *dto.UpdateStatusRequest, string) (*dto.UserStatusResponse, error)
+	UpdateRole(context.Context, *dto.UpdateRoleRequest, string) (*dto.UserResponse, error)
+	CheckUserStatus(context.Context, string) error
+	Impersonate(context.Context, string) (*dto.ImpersonateResponse, error)
+	StopImpersonation(context.Context) error
+	CheckSession(context.Context, string) error
+	ChangePassword(context.Context, *dto.ChangePasswordRequest) error
+	ConfirmEmailChange(context.Context, *dto.EmailChangeTokenRequest) (*dto.UserResponse, error)
+	CancelEmailChange(context.Context, *dto.EmailChangeTokenRequest) error
+	FindByUUIDs(context.Context, *dto.BatchUserRequest) (*dto.BatchUserResponse, error)
+	JWKS() *auth.JWKS
+	Introspect(context.Context, *dto.IntrospectRequest) (*dto.IntrospectResponse, error)
 }
 
-type Claims struct {
-	User *dto.UserResponse
-	jwt.RegisteredClaims
-}
+const (
+	defaultImpersonationExpirationTime = 15
+	defaultEmailChangeExpirationTime   = 60
+	defaultBatchLookupMaxSize          = 100
+	// emailChangeCancelWindow is how long the old address can still undo a
+	// change that was already confirmed.
+	emailChangeCancelWindow = 7 * 24 * time.Hour
+)
 
-func NewUserService(repository repositories.IRepositoryRegistry) IUserService {
+func NewUserService(repository repositories.IRepositoryRegistry, mailer mailer.IMailer) IUserService {
 	return &UserService{
 		repository: repository,
+		mailer:     mailer,
 	}
 }
 
 func (u *UserService) Login(ctx context.Context, req *dto.LoginRequest) (*dto.LoginResponse, error) {
-	user, err := u.repository.GetUser().FindByUsername(ctx, req.Username)
+	// a replica may not have the account or password of a moment ago yet
+	user, err := u.repository.GetUser().FindByUsername(resolver.WithPrimary(ctx), req.Username)
 	if err != nil {
+		u.recordLoginFailure(ctx, nil, req.Username, err)
 		return nil, err
 	}
 
 	// encrypt password
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password))
 	if err != nil {
+		u.recordLoginFailure(ctx, &user.UUID, req.Username, errConstant.ErrPasswordIncorrect)
 		return nil, errWrap.WrapError(errConstant.ErrPasswordIncorrect)
 	}
 
+	err = checkStatus(user)
+	if err != nil {
+		u.recordLoginFailure(ctx, &user.UUID, req.Username, err)
+		return nil, err
+	}
+
 	// create expiration time
 	data := &dto.UserResponse{
 		UUID:        user.UUID,
@@ -60,21 +96,39 @@ func (u *UserService) Login(ctx context.Context, req *dto.LoginRequest) (*dto.Lo
 		PhoneNumber: user.PhoneNumber,
 		Role:        strings.ToLower(user.Role.Code),
 	}
-	expirationTime := time.Now().Add(time.Duration(config.Config.JwtExpirationTime) * time.Minute).Unix()
+	expiresAt := time.Now().Add(time.Duration(config.Config.JwtExpirationTime) * time.Minute)
+
+	var session *models.Session
+	err = u.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		var txErr error
+		session, txErr = tx.GetSession().Create(ctx, newSession(ctx, user, nil, expiresAt))
+		if txErr != nil {
+			return txErr
+		}
+
+		metadata := map[string]interface{}{
+			"sessionId": session.UUID,
+		}
+		_, txErr = tx.GetAudit().Create(ctx,
+			auditServices.NewAuditLog(ctx, constants.AuditEventLoginSuccess, &user.UUID, &user.UUID, metadata))
+		return txErr
+	})
+	if err != nil {
+		return nil, err
+	}
 
 	// create claims
-	claims := &Claims{
+	claims := &auth.Claims{
 		User: data,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Unix(expirationTime, 0)),
+			ID:        session.UUID.String(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 
 	// create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-
-	// get token string
-	tokenString, err := token.SignedString([]byte(config.Config.JwtSecretKey))
+	tokenString, err := generateToken(claims)
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +143,39 @@ func (u *UserService) Login(ctx context.Context, req *dto.LoginRequest) (*dto.Lo
 
 }
 
+// newSession describes the session a token is issued for. Every token
+// carries the session id so it can be revoked before it expires.
+func newSession(ctx context.Context, user *models.User, impersonator *dto.UserResponse, expiresAt time.Time) *models.Session {
+	session := &models.Session{
+		UUID:      uuid.New(),
+		UserID:    user.ID,
+		ExpiresAt: expiresAt,
+	}
+
+	if impersonator != nil {
+		impersonatorUUID := impersonator.UUID
+		session.ImpersonatorUUID = &impersonatorUUID
+	}
+	if ip, ok := ctx.Value(constants.ClientIP).(string); ok {
+		session.IPAddress = ip
+	}
+	if userAgent, ok := ctx.Value(constants.UserAgent).(string); ok {
+		session.UserAgent = userAgent
+	}
+
+	return session
+}
+
+func (u *UserService) recordLoginFailure(ctx context.Context, target *uuid.UUID, username string, reason error) {
+	metadata := map[string]interface{}{
+		"username": username,
+		"reason":   reason.Error(),
+	}
+
+	auditServices.NewAuditService(u.repository).Record(ctx,
+		auditServices.NewAuditLog(ctx, constants.AuditEventLoginFailure, target, target, metadata))
+}
+
 func (u *UserService) isUsernameExist(ctx context.Context, username string) bool {
 	user, err := u.repository.GetUser().FindByUsername(ctx, username)
 	if err != nil {
@@ -142,7 +229,27 @@ func (u *UserService) Register(ctx context.Context, req *dto.RegisterRequest) (*
 		RoleID:      constants.User,
 	}
 
-	user, err := u.repository.GetUser().Register(ctx, data)
+	var user *models.User
+	err = u.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		var txErr error
+		user, txErr = tx.GetUser().Register(ctx, data)
+		if txErr != nil {
+			return txErr
+		}
+
+		metadata := map[string]interface{}{
+			"username":    user.Username,
+			"email":       user.Email,
+			"phoneNumber": user.PhoneNumber,
+		}
+		_, txErr = tx.GetAudit().Create(ctx,
+			auditServices.NewAuditLog(ctx, constants.AuditEventRegister, &user.UUID, &user.UUID, metadata))
+		if txErr != nil {
+			return txErr
+		}
+
+		return outboxServices.CreateUserEvent(ctx, tx, constants.EventUserRegistered, user, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -161,68 +268,224 @@ func (u *UserService) Register(ctx context.Context, req *dto.RegisterRequest) (*
 	return response, nil
 }
 
+func (u *UserService) canManage(ctx context.Context, uuid string) bool {
+	userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if !ok {
+		return false
+	}
+
+	return userLogin.UUID.String() == uuid || strings.EqualFold(userLogin.Role, constants.AdminRole)
+}
+
+// Update applies every field of req that is not nil, so it serves both a
+// full PUT, where the controller requires all fields, and a merge patch,
+// where only the fields present in the document are set. A new email is
+// not applied here, it only opens a change request that the new address
+// has to confirm.
 func (u *UserService) Update(ctx context.Context, req *dto.UpdateRequest, uuid string) (*dto.UserResponse, error) {
 	var (
-		password         string
-		hashedPassword   []byte
 		user, userResult *models.User
 		err              error
-		data             dto.UserResponse
+		data             *dto.UserResponse
+		pendingEmail     string
+		tokens           *emailChangeTokens
 	)
 
-	user, err = u.repository.GetUser().FindByUUID(ctx, uuid)
+	if !u.canManage(ctx, uuid) {
+		return nil, errConstant.ErrForbiden
+	}
+
+	// a replica behind the primary would fail the version check below
+	user, err = u.repository.GetUser().FindByUUID(resolver.WithPrimary(ctx), uuid)
 	if err != nil {
 		return nil, err
 	}
 
-	// check if user already input others username but already taken
-	isUsernameExist := u.isUsernameExist(ctx, req.Username)
-	if isUsernameExist && user.Username != req.Username {
+	if req.Version != 0 && req.Version != user.Version {
+		return nil, errConstant.ErrConflict
+	}
+
+	// check if user already input others username but already taken, a
+	// change of case only still matches the user's own row
+	if req.Username != nil && !strings.EqualFold(*req.Username, user.Username) && u.isUsernameExist(ctx, *req.Username) {
 		return nil, errConstant.ErrUsernameExist
 	}
 
-	// check if user already input others emails but already taken
-	isEmailExist := u.isEmailExist(ctx, req.Email)
-	if isEmailExist && user.Email != req.Email {
-		return nil, errConstant.ErrEmailExist
+	if req.Email != nil && *req.Email != user.Email {
+		// an impersonating admin could otherwise move the account to an
+		// address they control
+		if _, ok := ctx.Value(constants.Impersonator).(*dto.UserResponse); ok {
+			return nil, errConstant.ErrImpersonationNotAllowed
+		}
+
+		// check if user already input others emails but already taken
+		if !strings.EqualFold(*req.Email, user.Email) && u.isEmailExist(ctx, *req.Email) {
+			return nil, errConstant.ErrEmailExist
+		}
+
+		pendingEmail = *req.Email
 	}
 
-	if req.Password != nil {
-		if *req.Password != *req.ConfirmPassword {
-			return nil, errConstant.ErrPasswordDoesNotMatch
+	before := map[string]interface{}{}
+	after := map[string]interface{}{}
+	update := &dto.UpdateRequest{Version: req.Version}
+	if req.Name != nil {
+		before["name"], after["name"] = user.Name, *req.Name
+		update.Name = req.Name
+	}
+	if req.Username != nil {
+		before["username"], after["username"] = user.Username, *req.Username
+		update.Username = req.Username
+	}
+	if req.PhoneNumber != nil {
+		before["phoneNumber"], after["phoneNumber"] = user.PhoneNumber, *req.PhoneNumber
+		update.PhoneNumber = req.PhoneNumber
+	}
+
+	changes := auditServices.Diff(before, after)
+	if len(changes) == 0 && pendingEmail == "" {
+		return u.GetUserByUUID(ctx, uuid)
+	}
+
+	err = u.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		var txErr error
+		if len(changes) > 0 {
+			userResult, txErr = tx.GetUser().Update(ctx, update, uuid)
+			if txErr != nil {
+				return txErr
+			}
+
+			_, txErr = tx.GetAudit().Create(ctx,
+				auditServices.NewAuditLog(ctx, constants.AuditEventProfileUpdate, nil, &user.UUID, changes))
+			if txErr != nil {
+				return txErr
+			}
+
+			txErr = outboxServices.CreateUserEvent(ctx, tx, constants.EventUserUpdated, userResult, changes)
+			if txErr != nil {
+				return txErr
+			}
+		}
+
+		if pendingEmail != "" {
+			tokens, txErr = u.requestEmailChange(ctx, tx, user, pendingEmail)
+			if txErr != nil {
+				return txErr
+			}
 		}
 
-		hashedPassword, err = bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if userResult == nil {
+		data, err = u.GetUserByUUID(resolver.WithPrimary(ctx), uuid)
 		if err != nil {
 			return nil, err
 		}
+	} else {
+		data = &dto.UserResponse{
+			UUID:        userResult.UUID,
+			Name:        userResult.Name,
+			Username:    userResult.Username,
+			Email:       userResult.Email,
+			Role:        strings.ToLower(userResult.Role.Code),
+			PhoneNumber: userResult.PhoneNumber,
+			Version:     userResult.Version,
+		}
+	}
 
-		password = string(hashedPassword)
+	if tokens != nil {
+		u.sendEmailChangeMails(user, pendingEmail, tokens)
+		data.PendingEmail = pendingEmail
 	}
 
-	userResult, err = u.repository.GetUser().Update(ctx,
-		&dto.UpdateRequest{
-			Name:        req.Name,
-			Username:    req.Username,
-			Password:    &password,
-			Email:       req.Email,
-			PhoneNumber: req.PhoneNumber,
-		}, uuid)
+	return data, nil
+}
 
+type emailChangeTokens struct {
+	confirm   string
+	cancel    string
+	expiresAt time.Time
+}
+
+// requestEmailChange replaces any open email change of user with a new one
+// for email and returns the tokens to mail out.
+func (u *UserService) requestEmailChange(
+	ctx context.Context,
+	tx repositories.IRepositoryRegistry,
+	user *models.User,
+	email string,
+) (*emailChangeTokens, error) {
+	confirmToken, confirmHash, err := util.GenerateToken()
 	if err != nil {
 		return nil, err
 	}
 
-	data = dto.UserResponse{
-		UUID:        userResult.UUID,
-		Name:        userResult.Username,
-		Username:    userResult.Username,
-		Email:       userResult.Email,
-		Role:        userResult.Role.Code,
-		PhoneNumber: userResult.PhoneNumber,
+	cancelToken, cancelHash, err := util.GenerateToken()
+	if err != nil {
+		return nil, err
 	}
 
-	return &data, nil
+	expirationTime := config.Config.EmailChangeExpirationTime
+	if expirationTime == 0 {
+		expirationTime = defaultEmailChangeExpirationTime
+	}
+	expiresAt := time.Now().Add(time.Duration(expirationTime) * time.Minute)
+
+	err = tx.GetEmailChange().CancelPendingByUserID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	change, err := tx.GetEmailChange().Create(ctx, &models.EmailChange{
+		UserID:           user.ID,
+		OldEmail:         user.Email,
+		NewEmail:         email,
+		ConfirmTokenHash: confirmHash,
+		CancelTokenHash:  cancelHash,
+		ExpiresAt:        expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{
+		"email":         dto.FieldChange{Old: user.Email, New: email},
+		"emailChangeId": change.UUID,
+		"expiresAt":     expiresAt,
+	}
+	_, err = tx.GetAudit().Create(ctx,
+		auditServices.NewAuditLog(ctx, constants.AuditEventEmailChangeRequest, nil, &user.UUID, metadata))
+	if err != nil {
+		return nil, err
+	}
+
+	return &emailChangeTokens{confirm: confirmToken, cancel: cancelToken, expiresAt: expiresAt}, nil
+}
+
+func (u *UserService) sendEmailChangeMails(user *models.User, email string, tokens *emailChangeTokens) {
+	u.sendMail(&mailer.Message{
+		To:      []string{email},
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm that %s is the new email address of your account by opening the link below "+
+			"before %s.\n\n%s\n\nIf you did not ask for this change, ignore this message.\n",
+			user.Name, email, tokens.expiresAt.Format(time.RFC1123), emailChangeLink("confirm", tokens.confirm)),
+	})
+
+	u.sendMail(&mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nA change of the email address of your account to %s was requested. "+
+			"If this was not you, cancel it with the link below and reset your password.\n\n%s\n",
+			user.Name, email, emailChangeLink("cancel", tokens.cancel)),
+	})
+}
+
+func emailChangeLink(action, token string) string {
+	return fmt.Sprintf("%s/email/%s?token=%s", strings.TrimRight(config.Config.AppURL, "/"), action, token)
 }
 
 func (u *UserService) GetUserLogin(ctx context.Context) (*dto.UserResponse, error) {
@@ -255,7 +518,642 @@ func (u *UserService) GetUserByUUID(ctx context.Context, uuid string) (*dto.User
 		Username:    user.Username,
 		Email:       user.Email,
 		PhoneNumber: user.PhoneNumber,
+		Role:        strings.ToLower(user.Role.Code),
+		Version:     user.Version,
+	}
+
+	return &data, nil
+}
+
+// FindByUUIDs looks up a batch of users in one query for other services.
+// Users come back in the order of the request, duplicates are collapsed and
+// uuids without a user are listed as missing.
+func (u *UserService) FindByUUIDs(ctx context.Context, req *dto.BatchUserRequest) (*dto.BatchUserResponse, error) {
+	maxSize := config.Config.BatchLookupMaxSize
+	if maxSize == 0 {
+		maxSize = defaultBatchLookupMaxSize
+	}
+
+	uuids := make([]string, 0, len(req.UUIDs))
+	seen := make(map[string]bool, len(req.UUIDs))
+	for _, item := range req.UUIDs {
+		item = strings.ToLower(item)
+		if !seen[item] {
+			seen[item] = true
+			uuids = append(uuids, item)
+		}
+	}
+
+	if len(uuids) > maxSize {
+		return nil, errConstant.ErrBatchTooLarge
+	}
+
+	users, err := u.repository.GetUser().FindByUUIDs(ctx, uuids)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]*models.User, len(users))
+	for i := range users {
+		found[users[i].UUID.String()] = &users[i]
+	}
+
+	fields := req.Fields
+	if len(fields) == 0 {
+		fields = dto.BatchUserFields
+	}
+
+	response := &dto.BatchUserResponse{
+		Users:   make([]map[string]interface{}, 0, len(users)),
+		Missing: make([]string, 0),
+	}
+	for _, item := range uuids {
+		user, ok := found[item]
+		if !ok {
+			response.Missing = append(response.Missing, item)
+			continue
+		}
+
+		response.Users = append(response.Users, selectUserFields(user, fields))
+	}
+
+	return response, nil
+}
+
+func selectUserFields(user *models.User, fields []string) map[string]interface{} {
+	values := map[string]interface{}{
+		"uuid":        user.UUID,
+		"name":        user.Name,
+		"username":    user.Username,
+		"email":       user.Email,
+		"role":        strings.ToLower(user.Role.Code),
+		"phoneNumber": user.PhoneNumber,
+	}
+
+	selected := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		selected[field] = values[field]
+	}
+
+	return selected
+}
+
+// checkStatus only lets active users through. A suspension or ban with an
+// expiry that already passed is treated as active again.
+func checkStatus(user *models.User) error {
+	if user.Status == constants.UserStatusActive {
+		return nil
+	}
+
+	if user.StatusExpiresAt != nil && user.StatusExpiresAt.Before(time.Now()) &&
+		(user.Status == constants.UserStatusSuspended || user.Status == constants.UserStatusBanned) {
+		return nil
+	}
+
+	switch user.Status {
+	case constants.UserStatusSuspended:
+		return errWrap.WrapError(errConstant.ErrUserSuspended)
+	case constants.UserStatusBanned:
+		return errWrap.WrapError(errConstant.ErrUserBanned)
+	case constants.UserStatusDeleted:
+		return errWrap.WrapError(errConstant.ErrUserNotFound)
+	default:
+		return errWrap.WrapError(errConstant.ErrUserNotActive)
+	}
+}
+
+func (u *UserService) CheckUserStatus(ctx context.Context, uuid string) error {
+	user, err := u.repository.GetUser().FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	return checkStatus(user)
+}
+
+func (u *UserService) UpdateStatus(ctx context.Context, req *dto.UpdateStatusRequest, uuid string) (*dto.UserStatusResponse, error) {
+	userLogin := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if userLogin.UUID.String() == uuid {
+		return nil, errConstant.ErrChangeOwnStatus
+	}
+
+	if req.ExpiresAt != nil {
+		if req.Status != constants.UserStatusSuspended && req.Status != constants.UserStatusBanned {
+			return nil, errConstant.ErrInvalidStatusExpiry
+		}
+
+		if !req.ExpiresAt.After(time.Now()) {
+			return nil, errConstant.ErrInvalidStatusExpiry
+		}
+	}
+
+	current, err := u.repository.GetUser().FindByUUIDWithDeleted(resolver.WithPrimary(ctx), uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	err = u.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		var txErr error
+		user, txErr = tx.GetUser().UpdateStatus(ctx, &dto.UpdateStatusRequest{
+			Status:    req.Status,
+			Reason:    req.Reason,
+			ExpiresAt: req.ExpiresAt,
+			ChangedBy: userLogin.UUID,
+		}, uuid)
+		if txErr != nil {
+			return txErr
+		}
+
+		statusChange := dto.FieldChange{Old: current.Status, New: req.Status}
+		metadata := map[string]interface{}{
+			"status":    statusChange,
+			"reason":    req.Reason,
+			"expiresAt": req.ExpiresAt,
+		}
+		_, txErr = tx.GetAudit().Create(ctx,
+			auditServices.NewAuditLog(ctx, constants.AuditEventStatusChange, nil, &current.UUID, metadata))
+		if txErr != nil {
+			return txErr
+		}
+
+		txErr = auditServices.RecordAdminAction(ctx, tx)
+		if txErr != nil {
+			return txErr
+		}
+
+		event := constants.EventUserStatusChanged
+		if req.Status == constants.UserStatusDeleted {
+			event = constants.EventUserDeleted
+		}
+		return outboxServices.CreateUserEvent(ctx, tx,
+			event, user, map[string]dto.FieldChange{"status": statusChange})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := dto.UserStatusResponse{
+		UUID:      user.UUID,
+		Status:    user.Status,
+		Reason:    user.StatusReason,
+		ChangedBy: user.StatusChangedBy,
+		ChangedAt: user.StatusChangedAt,
+		ExpiresAt: user.StatusExpiresAt,
 	}
 
 	return &data, nil
 }
+
+func (u *UserService) UpdateRole(ctx context.Context, req *dto.UpdateRoleRequest, uuid string) (*dto.UserResponse, error) {
+	userLogin := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	if userLogin.UUID.String() == uuid {
+		return nil, errConstant.ErrChangeOwnRole
+	}
+
+	current, err := u.repository.GetUser().FindByUUID(resolver.WithPrimary(ctx), uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	err = u.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		var txErr error
+		user, txErr = tx.GetUser().UpdateRole(ctx, strings.ToUpper(req.Role), uuid)
+		if txErr != nil {
+			return txErr
+		}
+
+		// tokens carry the role they were issued with, sign the user out so
+		// a demoted admin does not keep their access until the token expires
+		txErr = tx.GetSession().RevokeByUserID(ctx, current.ID, "")
+		if txErr != nil {
+			return txErr
+		}
+
+		roleChange := dto.FieldChange{Old: strings.ToLower(current.Role.Code), New: strings.ToLower(user.Role.Code)}
+		metadata := map[string]interface{}{
+			"role":            roleChange,
+			"sessionsRevoked": true,
+		}
+		_, txErr = tx.GetAudit().Create(ctx,
+			auditServices.NewAuditLog(ctx, constants.AuditEventRoleChange, nil, &current.UUID, metadata))
+		if txErr != nil {
+			return txErr
+		}
+
+		txErr = auditServices.RecordAdminAction(ctx, tx)
+		if txErr != nil {
+			return txErr
+		}
+
+		return outboxServices.CreateUserEvent(ctx, tx,
+			constants.EventUserUpdated, user, map[string]dto.FieldChange{"role": roleChange})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := dto.UserResponse{
+		UUID:        user.UUID,
+		Name:        user.Name,
+		Username:    user.Username,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+		Role:        strings.ToLower(user.Role.Code),
+	}
+
+	return &data, nil
+}
+
+func hasPermission(user *dto.UserResponse, permission string) bool {
+	for _, item := range config.Config.UserPermissions[user.UUID.String()] {
+		if item == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (u *UserService) Impersonate(ctx context.Context, uuid string) (*dto.ImpersonateResponse, error) {
+	userLogin := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+
+	// the admin route checks the role already, this guards every other
+	// caller of a path that hands out someone else's identity
+	if !strings.EqualFold(userLogin.Role, constants.AdminRole) {
+		return nil, errConstant.ErrForbiden
+	}
+
+	// an impersonation token can not be used to start another impersonation
+	if _, ok := ctx.Value(constants.Impersonator).(*dto.UserResponse); ok {
+		return nil, errConstant.ErrImpersonationNotAllowed
+	}
+
+	if userLogin.UUID.String() == uuid {
+		return nil, errConstant.ErrImpersonationNotAllowed
+	}
+
+	user, err := u.repository.GetUser().FindByUUID(resolver.WithPrimary(ctx), uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(user.Role.Code, constants.AdminRole) && !hasPermission(userLogin, constants.PermissionImpersonateAdmin) {
+		return nil, errConstant.ErrImpersonateAdmin
+	}
+
+	expirationMinute := config.Config.ImpersonationExpirationTime
+	if expirationMinute == 0 {
+		expirationMinute = defaultImpersonationExpirationTime
+	}
+	expiresAt := time.Now().Add(time.Duration(expirationMinute) * time.Minute)
+
+	data := &dto.UserResponse{
+		UUID:        user.UUID,
+		Name:        user.Name,
+		Username:    user.Username,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+		Role:        strings.ToLower(user.Role.Code),
+	}
+
+	var session *models.Session
+	err = u.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		var txErr error
+		session, txErr = tx.GetSession().Create(ctx, newSession(ctx, user, userLogin, expiresAt))
+		if txErr != nil {
+			return txErr
+		}
+
+		metadata := map[string]interface{}{
+			"sessionId": session.UUID,
+			"expiresAt": expiresAt,
+		}
+		_, txErr = tx.GetAudit().Create(ctx,
+			auditServices.NewAuditLog(ctx, constants.AuditEventImpersonation, nil, &user.UUID, metadata))
+		if txErr != nil {
+			return txErr
+		}
+
+		return auditServices.RecordAdminAction(ctx, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &auth.Claims{
+		User:         data,
+		Impersonator: userLogin,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        session.UUID.String(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	tokenString, err := generateToken(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &dto.ImpersonateResponse{
+		User:         *data,
+		Impersonator: *userLogin,
+		Token:        tokenString,
+		ExpiresAt:    expiresAt,
+	}
+
+	return response, nil
+}
+
+// StopImpersonation ends the impersonation the token in ctx was issued for.
+// Its session is revoked, so the token stops working before it expires.
+func (u *UserService) StopImpersonation(ctx context.Context) error {
+	impersonator, ok := ctx.Value(constants.Impersonator).(*dto.UserResponse)
+	if !ok {
+		return errConstant.ErrNotImpersonating
+	}
+
+	userLogin := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	sessionID, _ := ctx.Value(constants.SessionID).(string)
+
+	return u.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		txErr := tx.GetSession().Revoke(ctx, sessionID)
+		if txErr != nil {
+			return txErr
+		}
+
+		metadata := map[string]interface{}{
+			"sessionId": sessionID,
+		}
+		_, txErr = tx.GetAudit().Create(ctx,
+			auditServices.NewAuditLog(ctx, constants.AuditEventImpersonationStop, &impersonator.UUID, &userLogin.UUID, metadata))
+		return txErr
+	})
+}
+
+func (u *UserService) CheckSession(ctx context.Context, sessionID string) error {
+	_, err := u.findActiveSession(ctx, sessionID)
+	return err
+}
+
+func (u *UserService) findActiveSession(ctx context.Context, sessionID string) (*models.Session, error) {
+	if sessionID == "" {
+		return nil, errWrap.WrapError(errConstant.ErrSessionNotFound)
+	}
+
+	// sessions are checked right after login and must see revocations at
+	// once, so they are never read from a replica
+	session, err := u.repository.GetSession().FindByUUID(resolver.WithPrimary(ctx), sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.RevokedAt != nil {
+		return nil, errWrap.WrapError(errConstant.ErrSessionRevoked)
+	}
+
+	if session.ExpiresAt.Before(time.Now()) {
+		return nil, errWrap.WrapError(errConstant.ErrSessionExpired)
+	}
+
+	return session, nil
+}
+
+func (u *UserService) ChangePassword(ctx context.Context, req *dto.ChangePasswordRequest) error {
+	userLogin := ctx.Value(constants.UserLogin).(*dto.UserResponse)
+	sessionID, _ := ctx.Value(constants.SessionID).(string)
+
+	// support staff must never be able to take over the account they look at
+	if _, ok := ctx.Value(constants.Impersonator).(*dto.UserResponse); ok {
+		return errConstant.ErrImpersonationNotAllowed
+	}
+
+	if req.NewPassword != req.ConfirmPassword {
+		return errConstant.ErrPasswordDoesNotMatch
+	}
+
+	user, err := u.repository.GetUser().FindByUUID(resolver.WithPrimary(ctx), userLogin.UUID.String())
+	if err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword))
+	if err != nil {
+		return errWrap.WrapError(errConstant.ErrPasswordIncorrect)
+	}
+
+	if req.NewPassword == req.CurrentPassword {
+		return errConstant.ErrPasswordReused
+	}
+
+	err = util.ValidatePasswordPolicy(req.NewPassword, config.Config.PasswordMinLength)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	err = u.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		txErr := tx.GetUser().UpdatePassword(ctx, string(hashedPassword), user.UUID.String())
+		if txErr != nil {
+			return txErr
+		}
+
+		// keep the session that proved it knows the password, drop the rest
+		txErr = tx.GetSession().RevokeByUserID(ctx, user.ID, sessionID)
+		if txErr != nil {
+			return txErr
+		}
+
+		metadata := map[string]interface{}{
+			"password":        dto.FieldChange{Old: constants.AuditRedacted, New: constants.AuditRedacted},
+			"sessionsRevoked": true,
+		}
+		_, txErr = tx.GetAudit().Create(ctx,
+			auditServices.NewAuditLog(ctx, constants.AuditEventPasswordChange, nil, &user.UUID, metadata))
+		return txErr
+	})
+	if err != nil {
+		return err
+	}
+
+	u.sendMail(&mailer.Message{
+		To:      []string{user.Email},
+		Subject: "Your password has been changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe password of your account was changed on %s and every other session was signed out.\n"+
+			"If this was not you, reset your password right away and contact support.\n",
+			user.Name, time.Now().Format(time.RFC1123)),
+	})
+
+	return nil
+}
+
+func (u *UserService) ConfirmEmailChange(ctx context.Context, req *dto.EmailChangeTokenRequest) (*dto.UserResponse, error) {
+	change, err := u.repository.GetEmailChange().FindByConfirmTokenHash(resolver.WithPrimary(ctx), util.HashToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+
+	if change.ConfirmedAt != nil || change.CancelledAt != nil {
+		return nil, errWrap.WrapError(errConstant.ErrEmailChangeResolved)
+	}
+
+	if change.ExpiresAt.Before(time.Now()) {
+		return nil, errWrap.WrapError(errConstant.ErrEmailChangeExpired)
+	}
+
+	if change.User.ID == 0 || change.User.DeletedAt.Valid {
+		return nil, errWrap.WrapError(errConstant.ErrUserNotFound)
+	}
+
+	// the address may have been taken while the request was open
+	if !strings.EqualFold(change.NewEmail, change.OldEmail) && u.isEmailExist(ctx, change.NewEmail) {
+		return nil, errConstant.ErrEmailExist
+	}
+
+	user := &change.User
+	err = u.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		txErr := tx.GetEmailChange().Confirm(ctx, change.ID)
+		if txErr != nil {
+			return txErr
+		}
+
+		user, txErr = tx.GetUser().Update(ctx, &dto.UpdateRequest{Email: &change.NewEmail}, change.User.UUID.String())
+		if txErr != nil {
+			return txErr
+		}
+
+		emailChange := dto.FieldChange{Old: change.OldEmail, New: change.NewEmail}
+		metadata := map[string]interface{}{
+			"email":         emailChange,
+			"emailChangeId": change.UUID,
+		}
+		_, txErr = tx.GetAudit().Create(ctx,
+			auditServices.NewAuditLog(ctx, constants.AuditEventEmailChange, &user.UUID, &user.UUID, metadata))
+		if txErr != nil {
+			return txErr
+		}
+
+		return outboxServices.CreateUserEvent(ctx, tx,
+			constants.EventUserUpdated, user, map[string]dto.FieldChange{"email": emailChange})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := dto.UserResponse{
+		UUID:        user.UUID,
+		Name:        user.Name,
+		Username:    user.Username,
+		Email:       user.Email,
+		Role:        strings.ToLower(user.Role.Code),
+		PhoneNumber: user.PhoneNumber,
+		Version:     user.Version,
+	}
+
+	return &data, nil
+}
+
+// CancelEmailChange is reached from the link mailed to the old address. It
+// drops an open request, and for one that was already confirmed it restores
+// the old address and signs every session out, since the change was not
+// made by the owner. The address is only restored while the account still
+// uses the one the request set, a later change is left alone.
+func (u *UserService) CancelEmailChange(ctx context.Context, req *dto.EmailChangeTokenRequest) error {
+	change, err := u.repository.GetEmailChange().FindByCancelTokenHash(resolver.WithPrimary(ctx), util.HashToken(req.Token))
+	if err != nil {
+		return err
+	}
+
+	if change.CancelledAt != nil {
+		return errWrap.WrapError(errConstant.ErrEmailChangeResolved)
+	}
+
+	confirmed := change.ConfirmedAt != nil
+	if confirmed && change.ConfirmedAt.Add(emailChangeCancelWindow).Before(time.Now()) {
+		return errWrap.WrapError(errConstant.ErrEmailChangeExpired)
+	}
+
+	if change.User.ID == 0 || change.User.DeletedAt.Valid {
+		return errWrap.WrapError(errConstant.ErrUserNotFound)
+	}
+
+	user := &change.User
+	reverted := false
+	err = u.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		txErr := tx.GetEmailChange().Cancel(ctx, change.ID)
+		if txErr != nil {
+			return txErr
+		}
+
+		if confirmed {
+			current, txErr := tx.GetUser().FindByUUID(ctx, user.UUID.String())
+			if txErr != nil {
+				return txErr
+			}
+			reverted = strings.EqualFold(current.Email, change.NewEmail)
+			user = current
+		}
+
+		metadata := map[string]interface{}{
+			"emailChangeId": change.UUID,
+			"reverted":      reverted,
+		}
+
+		if reverted {
+			// the version check fails the revert if the email changes meanwhile
+			restored, txErr := tx.GetUser().Update(ctx,
+				&dto.UpdateRequest{Email: &change.OldEmail, Version: user.Version}, user.UUID.String())
+			if txErr != nil {
+				return txErr
+			}
+
+			emailChange := dto.FieldChange{Old: change.NewEmail, New: change.OldEmail}
+			txErr = outboxServices.CreateUserEvent(ctx, tx,
+				constants.EventUserUpdated, restored, map[string]dto.FieldChange{"email": emailChange})
+			if txErr != nil {
+				return txErr
+			}
+
+			txErr = tx.GetSession().RevokeByUserID(ctx, user.ID, "")
+			if txErr != nil {
+				return txErr
+			}
+
+			metadata["email"] = emailChange
+			metadata["sessionsRevoked"] = true
+		}
+
+		_, txErr = tx.GetAudit().Create(ctx,
+			auditServices.NewAuditLog(ctx, constants.AuditEventEmailChangeCancel, &user.UUID, &user.UUID, metadata))
+		return txErr
+	})
+	if err != nil {
+		return err
+	}
+
+	if reverted {
+		u.sendMail(&mailer.Message{
+			To:      []string{change.NewEmail},
+			Subject: "Your email address change was reverted",
+			Body: fmt.Sprintf("Hi %s,\n\nThe owner of %s cancelled the change of the email address of this account, "+
+				"so it no longer uses this address.\n", user.Name, change.OldEmail),
+		})
+	}
+
+	return nil
+}
+
+// sendMail hands a notification to the mail queue, a mail server outage
+// must not fail a change that is already committed.
+func (u *UserService) sendMail(message *mailer.Message) {
+	err := u.mailer.Send(context.Background(), message)
+	if err != nil {
+		logrus.Errorf("failed to queue %q mail: %v", message.Subject, err)
+	}
+}
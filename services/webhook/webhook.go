@@ -0,0 +1,548 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+	errWrap "user-service/common/error"
+	"user-service/common/util"
+	"user-service/common/webhook"
+	"user-service/config"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/domain/dto"
+	"user-service/domain/models"
+	"user-service/repositories"
+	auditServices "user-service/services/audit"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type WebhookService struct {
+	repository repositories.IRepositoryRegistry
+	client     *http.Client
+	timeout    time.Duration
+	// allowPrivate skips the checks that keep targets on public addresses
+	allowPrivate bool
+}
+
+type IWebhookService interface {
+	Create(context.Context, *dto.WebhookRequest) (*dto.WebhookResponse, error)
+	FindAll(context.Context) ([]dto.WebhookResponse, error)
+	FindByUUID(context.Context, string) (*dto.WebhookResponse, error)
+	Update(context.Context, *dto.WebhookRequest, string) (*dto.WebhookResponse, error)
+	Delete(context.Context, string) error
+	FindDeliveries(context.Context, string, *dto.WebhookDeliveryFilterRequest) (*dto.WebhookDeliveryListResponse, error)
+	Replay(context.Context, string, string) (*dto.WebhookDeliveryResponse, error)
+	Run(context.Context)
+	DeliverPending(context.Context) (int, error)
+}
+
+const (
+	defaultDeliveryIntervalSecond = 5
+	defaultDeliveryBatchSize      = 20
+	defaultMaxAttempts            = 10
+	defaultTimeoutSecond          = 10
+	baseRetryBackoff              = 30 * time.Second
+	maxRetryBackoff               = 6 * time.Hour
+	// maxResponseBody caps how much of a receiver's reply is kept as the
+	// error of a failed attempt.
+	maxResponseBody = 1024
+	// leaseMargin is added to the time a batch may take to send, so a slow
+	// batch does not lose its deliveries to another worker.
+	leaseMargin = 30 * time.Second
+)
+
+func NewWebhookService(repository repositories.IRepositoryRegistry) IWebhookService {
+	timeoutSecond := config.Config.Webhook.TimeoutSecond
+	if timeoutSecond == 0 {
+		timeoutSecond = defaultTimeoutSecond
+	}
+	timeout := time.Duration(timeoutSecond) * time.Second
+
+	allowPrivate := config.Config.Webhook.AllowPrivateTargets
+	client := webhook.NewClient(timeout)
+	if allowPrivate {
+		client = &http.Client{Timeout: timeout}
+	}
+
+	return &WebhookService{
+		repository:   repository,
+		client:       client,
+		timeout:      timeout,
+		allowPrivate: allowPrivate,
+	}
+}
+
+// checkURL refuses a target on a loopback, link-local or private address
+// when it is registered. The client checks the address again on every
+// delivery, since the host may resolve elsewhere by then.
+func (w *WebhookService) checkURL(ctx context.Context, rawURL string) error {
+	if w.allowPrivate {
+		return nil
+	}
+
+	err := webhook.CheckURL(ctx, rawURL)
+	if err != nil {
+		logrus.Warnf("refused webhook url %q: %v", rawURL, err)
+		return errWrap.WrapError(errConstant.ErrWebhookURLNotAllowed)
+	}
+
+	return nil
+}
+
+// EnqueueDeliveries creates a delivery of event for every subscription
+// listening to it. The outbox relay calls it in its own transaction, so
+// requests never wait on webhook endpoints.
+func EnqueueDeliveries(ctx context.Context, tx repositories.IRepositoryRegistry, event *models.OutboxEvent) error {
+	subscriptions, err := tx.GetWebhook().FindActiveByEvent(ctx, event.Topic)
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			UUID:           uuid.New(),
+			SubscriptionID: subscription.ID,
+			EventUUID:      event.UUID,
+			Event:          event.Topic,
+			Payload:        event.Payload,
+			Status:         constants.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+	}
+
+	return tx.GetWebhook().CreateDeliveries(ctx, deliveries)
+}
+
+func (w *WebhookService) Create(ctx context.Context, req *dto.WebhookRequest) (*dto.WebhookResponse, error) {
+	err := w.checkURL(ctx, req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, _, err := util.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := json.Marshal(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription := &models.WebhookSubscription{
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      events,
+		Secret:      secret,
+		Active:      req.Active == nil || *req.Active,
+	}
+	if userLogin, ok := ctx.Value(constants.UserLogin).(*dto.UserResponse); ok {
+		createdBy := userLogin.UUID
+		subscription.CreatedBy = &createdBy
+	}
+
+	err = w.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		var txErr error
+		subscription, txErr = tx.GetWebhook().Create(ctx, subscription)
+		if txErr != nil {
+			return txErr
+		}
+
+		return auditServices.RecordAdminAction(ctx, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the secret is only shown once, receivers need it to check signatures
+	data := toWebhookResponse(subscription)
+	data.Secret = subscription.Secret
+
+	return &data, nil
+}
+
+func (w *WebhookService) FindAll(ctx context.Context) ([]dto.WebhookResponse, error) {
+	subscriptions, err := w.repository.GetWebhook().FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]dto.WebhookResponse, 0, len(subscriptions))
+	for i := range subscriptions {
+		data = append(data, toWebhookResponse(&subscriptions[i]))
+	}
+
+	return data, nil
+}
+
+func (w *WebhookService) FindByUUID(ctx context.Context, uuid string) (*dto.WebhookResponse, error) {
+	subscription, err := w.repository.GetWebhook().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	data := toWebhookResponse(subscription)
+	return &data, nil
+}
+
+func (w *WebhookService) Update(ctx context.Context, req *dto.WebhookRequest, uuid string) (*dto.WebhookResponse, error) {
+	err := w.checkURL(ctx, req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := w.repository.GetWebhook().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := json.Marshal(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription.URL = req.URL
+	subscription.Description = req.Description
+	subscription.Events = events
+	if req.Active != nil {
+		subscription.Active = *req.Active
+	}
+
+	err = w.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		var txErr error
+		subscription, txErr = tx.GetWebhook().Update(ctx, subscription)
+		if txErr != nil {
+			return txErr
+		}
+
+		return auditServices.RecordAdminAction(ctx, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := toWebhookResponse(subscription)
+	return &data, nil
+}
+
+// Delete removes the subscription. Its pending deliveries are dropped to
+// the dead state the next time the worker picks them up.
+func (w *WebhookService) Delete(ctx context.Context, uuid string) error {
+	subscription, err := w.repository.GetWebhook().FindByUUID(ctx, uuid)
+	if err != nil {
+		return err
+	}
+
+	return w.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		txErr := tx.GetWebhook().Delete(ctx, subscription.ID)
+		if txErr != nil {
+			return txErr
+		}
+
+		return auditServices.RecordAdminAction(ctx, tx)
+	})
+}
+
+func (w *WebhookService) FindDeliveries(
+	ctx context.Context,
+	uuid string,
+	req *dto.WebhookDeliveryFilterRequest,
+) (*dto.WebhookDeliveryListResponse, error) {
+	if req.Page == 0 {
+		req.Page = 1
+	}
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	subscription, err := w.repository.GetWebhook().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, total, err := w.repository.GetWebhook().FindDeliveries(ctx, subscription.ID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]dto.WebhookDeliveryResponse, 0, len(deliveries))
+	for i := range deliveries {
+		items = append(items, toWebhookDeliveryResponse(&deliveries[i]))
+	}
+
+	data := dto.WebhookDeliveryListResponse{
+		Items: items,
+		Page:  req.Page,
+		Limit: req.Limit,
+		Total: total,
+	}
+
+	return &data, nil
+}
+
+// Replay queues a delivery again from its first attempt, whatever state it
+// ended in. The receiver gets the same delivery and event IDs as before.
+func (w *WebhookService) Replay(ctx context.Context, uuid, deliveryUUID string) (*dto.WebhookDeliveryResponse, error) {
+	subscription, err := w.repository.GetWebhook().FindByUUID(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !subscription.Active {
+		return nil, errWrap.WrapError(errConstant.ErrWebhookInactive)
+	}
+
+	delivery, err := w.repository.GetWebhook().FindDeliveryByUUID(ctx, subscription.ID, deliveryUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Status = constants.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.LastStatusCode = nil
+	delivery.LastError = nil
+	delivery.NextAttemptAt = time.Now()
+	delivery.DeliveredAt = nil
+	// a worker still sending it loses the delivery and drops its outcome
+	delivery.LeaseID = nil
+	delivery.LeasedUntil = nil
+
+	err = w.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		txErr := tx.GetWebhook().UpdateDelivery(ctx, delivery)
+		if txErr != nil {
+			return txErr
+		}
+
+		return auditServices.RecordAdminAction(ctx, tx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := toWebhookDeliveryResponse(delivery)
+	return &data, nil
+}
+
+// Run sends due deliveries until ctx is cancelled. A batch in flight stops
+// with it, DeliverPending hands the deliveries it did not send back.
+func (w *WebhookService) Run(ctx context.Context) {
+	interval := config.Config.Webhook.DeliveryIntervalSecond
+	if interval == 0 {
+		interval = defaultDeliveryIntervalSecond
+	}
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		_, err := w.DeliverPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			logrus.Errorf("failed to deliver webhooks: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverPending sends one batch of due deliveries and returns how many
+// were accepted. The batch is leased in one short transaction and its
+// outcome stored in another, no transaction is open while receivers are
+// called. A failed attempt is retried with an exponential backoff until the
+// configured number of attempts is used up, after which the delivery is
+// dead and only a replay sends it again. Once ctx is done the deliveries
+// not sent yet are handed back untouched.
+func (w *WebhookService) DeliverPending(ctx context.Context) (int, error) {
+	batchSize := config.Config.Webhook.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultDeliveryBatchSize
+	}
+
+	leaseID := uuid.New()
+	var deliveries []models.WebhookDelivery
+	err := w.repository.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+		var txErr error
+		deliveries, txErr = tx.GetWebhook().FindDueDeliveries(ctx, batchSize)
+		if txErr != nil || len(deliveries) == 0 {
+			return txErr
+		}
+
+		ids := make([]uint, 0, len(deliveries))
+		for i := range deliveries {
+			ids = append(ids, deliveries[i].ID)
+		}
+
+		// the deliveries are sent one after the other
+		leasedUntil := time.Now().Add(time.Duration(len(deliveries))*w.timeout + leaseMargin)
+		return tx.GetWebhook().LeaseDeliveries(ctx, ids, leaseID, leasedUntil)
+	})
+	if err != nil || len(deliveries) == 0 {
+		return 0, err
+	}
+
+	delivered := 0
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+
+		delivery := &deliveries[i]
+		unsent := *delivery
+		w.attempt(ctx, delivery)
+		// a request cut off by shutdown says nothing about the receiver
+		if ctx.Err() != nil {
+			*delivery = unsent
+			break
+		}
+
+		if delivery.Status == constants.WebhookDeliveryDelivered {
+			delivered++
+		}
+	}
+
+	// the outcome is stored even when ctx is done, it only takes a moment
+	record := context.WithoutCancel(ctx)
+	err = w.repository.WithTransaction(record, func(tx repositories.IRepositoryRegistry) error {
+		for i := range deliveries {
+			txErr := tx.GetWebhook().UpdateLeasedDelivery(record, &deliveries[i], leaseID)
+			if errors.Is(txErr, errConstant.ErrWebhookLeaseLost) {
+				logrus.Warnf("webhook delivery %s was taken over while it was sent", deliveries[i].UUID)
+				continue
+			}
+			if txErr != nil {
+				return txErr
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return delivered, nil
+}
+
+// attempt sends delivery once and records the outcome on it.
+func (w *WebhookService) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+
+	// a deleted subscription is not preloaded
+	if delivery.Subscription.ID == 0 || !delivery.Subscription.Active {
+		reason := errConstant.ErrWebhookInactive.Error()
+		delivery.LastError = &reason
+		delivery.Status = constants.WebhookDeliveryDead
+		return
+	}
+
+	statusCode, err := w.send(ctx, delivery)
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+
+	if err == nil {
+		now := time.Now()
+		delivery.Status = constants.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = nil
+		return
+	}
+
+	reason := err.Error()
+	delivery.LastError = &reason
+
+	maxAttempts := config.Config.Webhook.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	if delivery.Attempts >= maxAttempts {
+		logrus.Warnf("webhook delivery %s is dead after %d attempts: %v", delivery.UUID, delivery.Attempts, err)
+		delivery.Status = constants.WebhookDeliveryDead
+		return
+	}
+
+	delivery.NextAttemptAt = time.Now().Add(retryBackoff(delivery.Attempts))
+}
+
+func (w *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", fmt.Sprintf("%s-webhook", config.Config.AppName))
+	request.Header.Set(constants.XWebhookEvent, delivery.Event)
+	request.Header.Set(constants.XWebhookDelivery, delivery.UUID.String())
+	request.Header.Set(constants.XWebhookSignature, webhook.Sign(delivery.Subscription.Secret, time.Now(), delivery.Payload))
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, maxResponseBody))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d: %s", response.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return response.StatusCode, nil
+}
+
+func retryBackoff(attempts int) time.Duration {
+	backoff := baseRetryBackoff << (attempts - 1)
+	if attempts > 20 || backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+
+	return backoff
+}
+
+func toWebhookResponse(subscription *models.WebhookSubscription) dto.WebhookResponse {
+	var events []string
+	err := json.Unmarshal(subscription.Events, &events)
+	if err != nil {
+		logrus.Errorf("failed to unmarshal events of webhook %s: %v", subscription.UUID, err)
+	}
+
+	return dto.WebhookResponse{
+		UUID:        subscription.UUID,
+		URL:         subscription.URL,
+		Description: subscription.Description,
+		Events:      events,
+		Active:      subscription.Active,
+		CreatedBy:   subscription.CreatedBy,
+		CreatedAt:   subscription.CreatedAt,
+		UpdatedAt:   subscription.UpdatedAt,
+	}
+}
+
+func toWebhookDeliveryResponse(delivery *models.WebhookDelivery) dto.WebhookDeliveryResponse {
+	return dto.WebhookDeliveryResponse{
+		UUID:           delivery.UUID,
+		EventUUID:      delivery.EventUUID,
+		Event:          delivery.Event,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+}
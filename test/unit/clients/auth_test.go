@@ -0,0 +1,159 @@
+package clients_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	authClient "user-service/clients/auth"
+	"user-service/common/auth"
+	"user-service/constants"
+	"user-service/domain/dto"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newJWKSServer(t *testing.T, key *rsa.PrivateKey, kid string) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		json.NewEncoder(w).Encode(auth.JWKS{Keys: []auth.JWK{auth.NewJWK(&key.PublicKey, kid)}})
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &calls
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, user *dto.UserResponse) string {
+	claims := &auth.Claims{
+		User: user,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	user := &dto.UserResponse{UUID: uuid.New(), Username: "faisal", Role: "user"}
+
+	t.Run("success", func(t *testing.T) {
+		server, calls := newJWKSServer(t, key, "key-1")
+		verifier := authClient.NewVerifier(authClient.Config{JWKSURL: server.URL})
+		token := signToken(t, jwt.SigningMethodRS256, key, "key-1", user)
+
+		claims, err := verifier.Verify(context.Background(), "Bearer "+token)
+		require.NoError(t, err)
+		assert.Equal(t, user.UUID, claims.User.UUID)
+
+		// the second token is checked against the cached keys
+		_, err = verifier.Verify(context.Background(), token)
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+
+	t.Run("unknown key id refreshes at most once per interval", func(t *testing.T) {
+		server, calls := newJWKSServer(t, key, "key-1")
+		verifier := authClient.NewVerifier(authClient.Config{JWKSURL: server.URL, MinRefreshInterval: time.Hour})
+
+		for i := 0; i < 3; i++ {
+			_, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodRS256, key, "key-2", user))
+			assert.ErrorIs(t, err, authClient.ErrInvalidToken)
+		}
+		assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+	})
+
+	t.Run("serves known keys while a refresh is in flight", func(t *testing.T) {
+		var calls int32
+		fetching := make(chan struct{})
+		release := make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) > 1 {
+				close(fetching)
+				<-release
+			}
+			json.NewEncoder(w).Encode(auth.JWKS{Keys: []auth.JWK{auth.NewJWK(&key.PublicKey, "key-1")}})
+		}))
+		t.Cleanup(server.Close)
+		verifier := authClient.NewVerifier(authClient.Config{
+			JWKSURL:            server.URL,
+			RefreshInterval:    time.Hour,
+			MinRefreshInterval: time.Nanosecond,
+		})
+		known := signToken(t, jwt.SigningMethodRS256, key, "key-1", user)
+
+		_, err := verifier.Verify(context.Background(), known)
+		require.NoError(t, err)
+
+		unknown := make(chan error)
+		go func() {
+			_, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodRS256, key, "key-2", user))
+			unknown <- err
+		}()
+		<-fetching
+
+		_, err = verifier.Verify(context.Background(), known)
+		assert.NoError(t, err)
+
+		close(release)
+		assert.ErrorIs(t, <-unknown, authClient.ErrInvalidToken)
+	})
+
+	t.Run("rejects hmac tokens", func(t *testing.T) {
+		server, _ := newJWKSServer(t, key, "key-1")
+		verifier := authClient.NewVerifier(authClient.Config{JWKSURL: server.URL})
+
+		_, err := verifier.Verify(context.Background(), signToken(t, jwt.SigningMethodHS256, []byte("secret"), "key-1", user))
+		assert.ErrorIs(t, err, authClient.ErrInvalidToken)
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	server, _ := newJWKSServer(t, key, "key-1")
+	user := &dto.UserResponse{UUID: uuid.New(), Username: "faisal", Role: "user"}
+
+	router := gin.New()
+	router.GET("/me", authClient.Authenticate(authClient.NewVerifier(authClient.Config{JWKSURL: server.URL})), func(c *gin.Context) {
+		userLogin := c.Request.Context().Value(constants.UserLogin).(*dto.UserResponse)
+		c.String(http.StatusOK, userLogin.Username)
+	})
+
+	t.Run("success", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set(constants.Authorization, "Bearer "+signToken(t, jwt.SigningMethodRS256, key, "key-1", user))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "faisal", rec.Body.String())
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+}
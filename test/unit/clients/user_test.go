@@ -0,0 +1,137 @@
+package clients_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	clients "user-service/clients/user"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/domain/dto"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClient(url string) clients.IUserClient {
+	return clients.NewUserClient(clients.Config{
+		BaseURL:      url,
+		ServiceName:  "order-service",
+		SignatureKey: "secret",
+		RetryBackoff: time.Millisecond,
+	})
+}
+
+func TestUserClient_GetUserByUUID(t *testing.T) {
+	t.Run("signs the request", func(t *testing.T) {
+		userUUID := uuid.New()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/auth/"+userUUID.String(), r.URL.Path)
+			assert.Equal(t, "Bearer token", r.Header.Get(constants.Authorization))
+			assert.Equal(t, "request-id", r.Header.Get(constants.XRequestID))
+
+			hash := sha256.Sum256([]byte(fmt.Sprintf("order-service:secret:%s", r.Header.Get(constants.XRequestAt))))
+			assert.Equal(t, hex.EncodeToString(hash[:]), r.Header.Get(constants.XApiKey))
+
+			fmt.Fprintf(w, `{"status":"success","message":"OK","data":{"uuid":%q,"name":"faisal"}}`, userUUID)
+		}))
+		defer server.Close()
+
+		ctx := context.WithValue(context.Background(), constants.RequestID, "request-id")
+		user, err := newClient(server.URL).GetUserByUUID(ctx, "token", userUUID.String())
+
+		require.NoError(t, err)
+		assert.Equal(t, userUUID, user.UUID)
+		assert.Equal(t, "faisal", user.Name)
+	})
+
+	t.Run("maps error messages", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"status":"error","message":"user not found","data":null}`)
+		}))
+		defer server.Close()
+
+		user, err := newClient(server.URL).GetUserByUUID(context.Background(), "token", uuid.New().String())
+
+		var clientErr *clients.Error
+		require.True(t, errors.As(err, &clientErr))
+		assert.Nil(t, user)
+		assert.Equal(t, http.StatusBadRequest, clientErr.StatusCode)
+		assert.True(t, errors.Is(err, errConstant.ErrUserNotFound))
+	})
+
+	t.Run("retries server errors", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, `{"status":"success","message":"OK","data":{"name":"faisal"}}`)
+		}))
+		defer server.Close()
+
+		user, err := newClient(server.URL).GetUserByUUID(context.Background(), "token", uuid.New().String())
+
+		require.NoError(t, err)
+		assert.Equal(t, "faisal", user.Name)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestUserClient_Login(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			fmt.Fprint(w, `{"status":"success","message":"OK","data":{"username":"faisal"},"token":"jwt"}`)
+		}))
+		defer server.Close()
+
+		resp, err := newClient(server.URL).Login(context.Background(), &dto.LoginRequest{Username: "faisal", Password: "secret"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "jwt", resp.Token)
+		assert.Equal(t, "faisal", resp.User.Username)
+	})
+
+	t.Run("is not retried", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"status":"error","message":"internal server error","data":null}`)
+		}))
+		defer server.Close()
+
+		_, err := newClient(server.URL).Login(context.Background(), &dto.LoginRequest{Username: "faisal", Password: "secret"})
+
+		assert.True(t, errors.Is(err, errConstant.ErrInternalServerError))
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestUserClient_KnownErrors(t *testing.T) {
+	for _, known := range errConstant.AllErrors() {
+		t.Run(known.Error(), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprintf(w, `{"status":"error","message":%q,"data":null}`, known.Error())
+			}))
+			defer server.Close()
+
+			_, err := newClient(server.URL).GetUserByUUID(context.Background(), "token", uuid.New().String())
+
+			assert.True(t, errConstant.ErrMapping(known))
+			assert.ErrorIs(t, err, known)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package common_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+	"user-service/common/audit"
+	"user-service/domain/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeHash(t *testing.T) {
+	createdAt := audit.Timestamp(time.Now())
+	log := &models.AuditLog{
+		UUID:      uuid.New(),
+		Event:     "user.registered",
+		Metadata:  json.RawMessage(`{"username":"faisal","email":"faisal@mail.com"}`),
+		CreatedAt: &createdAt,
+	}
+
+	t.Run("metadata key order does not change the hash", func(t *testing.T) {
+		stored := *log
+		stored.Metadata = json.RawMessage(`{"email": "faisal@mail.com", "username": "faisal"}`)
+
+		assert.Equal(t, audit.ComputeHash("", log), audit.ComputeHash("", &stored))
+	})
+
+	t.Run("previous hash is part of the hash", func(t *testing.T) {
+		assert.NotEqual(t, audit.ComputeHash("", log), audit.ComputeHash("previous", log))
+	})
+
+	t.Run("chain is part of the hash", func(t *testing.T) {
+		moved := *log
+		moved.Chain = audit.ChainGlobal
+
+		assert.NotEqual(t, audit.ComputeHash("", log), audit.ComputeHash("", &moved))
+	})
+
+	t.Run("edited entry changes the hash", func(t *testing.T) {
+		edited := *log
+		edited.Event = "user.deleted"
+
+		assert.NotEqual(t, audit.ComputeHash("", log), audit.ComputeHash("", &edited))
+	})
+}
+
+func TestHeadsDigest(t *testing.T) {
+	heads := map[string]string{"global": "a", "user": "b"}
+
+	assert.Equal(t, audit.HeadsDigest(heads), audit.HeadsDigest(map[string]string{"user": "b", "global": "a"}))
+	assert.NotEqual(t, audit.HeadsDigest(heads), audit.HeadsDigest(map[string]string{"global": "a", "user": "c"}))
+	assert.NotEqual(t, audit.HeadsDigest(heads), audit.HeadsDigest(map[string]string{"global": "a"}))
+}
+
+func TestCheckpoint(t *testing.T) {
+	seed := base64.StdEncoding.EncodeToString(make([]byte, ed25519.SeedSize))
+
+	t.Run("success", func(t *testing.T) {
+		checkpoint := &audit.Checkpoint{LastID: 10, LastHash: "hash", Count: 10, CreatedAt: time.Now()}
+		require.NoError(t, audit.SignCheckpoint(checkpoint, seed))
+
+		assert.NoError(t, audit.VerifyCheckpoint(checkpoint, ""))
+	})
+
+	t.Run("tampered checkpoint", func(t *testing.T) {
+		checkpoint := &audit.Checkpoint{LastID: 10, LastHash: "hash", Count: 10, CreatedAt: time.Now()}
+		require.NoError(t, audit.SignCheckpoint(checkpoint, seed))
+
+		checkpoint.LastHash = "other"
+		assert.ErrorIs(t, audit.VerifyCheckpoint(checkpoint, ""), audit.ErrInvalidSignature)
+	})
+
+	t.Run("invalid signing key", func(t *testing.T) {
+		checkpoint := &audit.Checkpoint{LastID: 10}
+		assert.ErrorIs(t, audit.SignCheckpoint(checkpoint, "not-a-key"), audit.ErrInvalidSigningKey)
+	})
+}
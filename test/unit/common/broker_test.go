@@ -0,0 +1,81 @@
+package common_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"user-service/common/broker"
+	"user-service/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBroker_Publish(t *testing.T) {
+	memory := broker.NewMemoryBroker()
+
+	var topic, all []string
+	memory.Subscribe("user.registered", func(message *broker.Message) { topic = append(topic, message.ID) })
+	memory.Subscribe("", func(message *broker.Message) { all = append(all, message.ID) })
+
+	require.NoError(t, memory.Publish(context.Background(), &broker.Message{ID: "1", Topic: "user.registered"}))
+	require.NoError(t, memory.Publish(context.Background(), &broker.Message{ID: "2", Topic: "user.deleted"}))
+
+	assert.Equal(t, []string{"1"}, topic)
+	assert.Equal(t, []string{"1", "2"}, all)
+}
+
+func TestFileBroker_Publish(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "events.jsonl")
+	file, err := broker.NewFileBroker(path)
+	require.NoError(t, err)
+
+	err = file.Publish(context.Background(), &broker.Message{
+		ID:      "1",
+		Topic:   "user.registered",
+		Key:     "user-1",
+		Payload: []byte(`{"type":"user.registered"}`),
+	})
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	raw, err := os.Open(path)
+	require.NoError(t, err)
+	defer raw.Close()
+
+	scanner := bufio.NewScanner(raw)
+	require.True(t, scanner.Scan())
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+	assert.Equal(t, "1", record["id"])
+	assert.Equal(t, "user.registered", record["topic"])
+	assert.Equal(t, map[string]interface{}{"type": "user.registered"}, record["payload"])
+	assert.False(t, scanner.Scan())
+}
+
+func TestNewBroker(t *testing.T) {
+	t.Run("memory in a local environment", func(t *testing.T) {
+		memory, err := broker.NewBroker(config.Broker{Driver: broker.DriverMemory}, "local")
+		require.NoError(t, err)
+		assert.IsType(t, &broker.MemoryBroker{}, memory)
+	})
+
+	t.Run("memory in production", func(t *testing.T) {
+		_, err := broker.NewBroker(config.Broker{Driver: broker.DriverMemory}, "production")
+		assert.Error(t, err)
+	})
+
+	t.Run("no driver", func(t *testing.T) {
+		_, err := broker.NewBroker(config.Broker{}, "local")
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown driver", func(t *testing.T) {
+		_, err := broker.NewBroker(config.Broker{Driver: "kafka"}, "local")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,135 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-service/common/cache"
+	"user-service/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("evicts the least recently used entry", func(t *testing.T) {
+		lru := cache.NewLRU(2)
+
+		require.NoError(t, lru.Set(ctx, "a", []byte("1"), 0))
+		require.NoError(t, lru.Set(ctx, "b", []byte("2"), 0))
+		_, err := lru.Get(ctx, "a")
+		require.NoError(t, err)
+		require.NoError(t, lru.Set(ctx, "c", []byte("3"), 0))
+
+		_, err = lru.Get(ctx, "b")
+		assert.ErrorIs(t, err, cache.ErrMiss)
+		value, err := lru.Get(ctx, "a")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("1"), value)
+		assert.Equal(t, 2, lru.Len())
+	})
+
+	t.Run("expires entries", func(t *testing.T) {
+		lru := cache.NewLRU(10)
+
+		require.NoError(t, lru.Set(ctx, "a", []byte("1"), 10*time.Millisecond))
+		time.Sleep(20 * time.Millisecond)
+
+		_, err := lru.Get(ctx, "a")
+		assert.ErrorIs(t, err, cache.ErrMiss)
+		assert.Equal(t, 0, lru.Len())
+	})
+
+	t.Run("deletes entries", func(t *testing.T) {
+		lru := cache.NewLRU(10)
+
+		require.NoError(t, lru.Set(ctx, "a", []byte("1"), 0))
+		require.NoError(t, lru.Delete(ctx, "a", "missing"))
+
+		_, err := lru.Get(ctx, "a")
+		assert.ErrorIs(t, err, cache.ErrMiss)
+	})
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+
+	server := miniredis.RunT(t)
+
+	redis, err := cache.NewRedis("redis://" + server.Addr() + "/1")
+	require.NoError(t, err)
+	t.Cleanup(func() { redis.Close() })
+
+	_, err = redis.Get(ctx, "user")
+	assert.ErrorIs(t, err, cache.ErrMiss)
+
+	payload := []byte("{\"name\":\"faisal\"}\r\nwith a line break")
+	require.NoError(t, redis.Set(ctx, "user", payload, time.Minute))
+	require.NoError(t, redis.Set(ctx, "empty", []byte{}, time.Minute))
+	require.NoError(t, redis.Set(ctx, "short", []byte("1"), 10*time.Millisecond))
+
+	value, err := redis.Get(ctx, "user")
+	require.NoError(t, err)
+	assert.Equal(t, payload, value)
+
+	value, err = redis.Get(ctx, "empty")
+	require.NoError(t, err)
+	assert.Empty(t, value)
+
+	server.FastForward(20 * time.Millisecond)
+	_, err = redis.Get(ctx, "short")
+	assert.ErrorIs(t, err, cache.ErrMiss)
+
+	require.NoError(t, redis.Delete(ctx, "user", "empty"))
+	server.Select(1)
+	assert.Empty(t, server.Keys())
+}
+
+func TestRedis_Unreachable(t *testing.T) {
+	server := miniredis.RunT(t)
+	addr := server.Addr()
+	server.Close()
+
+	_, err := cache.NewRedis(addr)
+	assert.Error(t, err)
+}
+
+func TestNewCache(t *testing.T) {
+	backend, err := cache.NewCache(config.Cache{})
+	require.NoError(t, err)
+	assert.IsType(t, &cache.LRU{}, backend)
+
+	backend, err = cache.NewCache(config.Cache{Driver: cache.DriverNone})
+	require.NoError(t, err)
+	assert.Nil(t, backend)
+
+	_, err = cache.NewCache(config.Cache{Driver: "memcached"})
+	assert.Error(t, err)
+}
+
+func TestCacheMetrics(t *testing.T) {
+	metrics := cache.MetricsFor("test")
+	metrics.Hit()
+	metrics.Miss()
+	metrics.Miss()
+
+	assert.Same(t, metrics, cache.MetricsFor("test"))
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	cache.ServeMetrics(c)
+
+	var vars map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &vars))
+	assert.Len(t, vars, 1, "only the cache counters are served")
+
+	var counters map[string]cache.MetricsSnapshot
+	require.NoError(t, json.Unmarshal(vars["cache"], &counters))
+	assert.Equal(t, cache.MetricsSnapshot{Hits: 1, Misses: 2}, counters["test"])
+}
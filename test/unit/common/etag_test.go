@@ -0,0 +1,50 @@
+package common_test
+
+import (
+	"testing"
+	"user-service/common/util"
+	errConstant "user-service/constants/error"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	t.Run("strong tag", func(t *testing.T) {
+		version, err := util.ParseIfMatch(util.FormatETag(3))
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3), version)
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		version, err := util.ParseIfMatch("*")
+
+		assert.NoError(t, err)
+		assert.Zero(t, version)
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		_, err := util.ParseIfMatch(" ")
+
+		assert.ErrorIs(t, err, errConstant.ErrPreconditionRequired)
+	})
+
+	t.Run("weak tag never matches", func(t *testing.T) {
+		_, err := util.ParseIfMatch(`W/"3"`)
+
+		assert.ErrorIs(t, err, errConstant.ErrConflict)
+	})
+
+	t.Run("strong tag after a weak one", func(t *testing.T) {
+		version, err := util.ParseIfMatch(`W/"2", "3"`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(3), version)
+	})
+
+	t.Run("foreign tag", func(t *testing.T) {
+		_, err := util.ParseIfMatch(`"abc"`)
+
+		assert.ErrorIs(t, err, errConstant.ErrConflict)
+	})
+}
@@ -0,0 +1,169 @@
+package common_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"user-service/common/health"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func up(context.Context) error { return nil }
+
+func down(context.Context) error { return errors.New("connection refused") }
+
+func readyz(t *testing.T, checker *health.Checker) (int, health.Report) {
+	return readyzAs(t, checker, true)
+}
+
+// readyzAs asks as a caller that may see errors or not.
+func readyzAs(t *testing.T, checker *health.Checker, showErrors bool) (int, health.Report) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/readyz", checker.Readiness(func(*gin.Context) bool { return showErrors }))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report health.Report
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	return recorder.Code, report
+}
+
+func TestReadiness(t *testing.T) {
+	t.Run("ready when every component is up", func(t *testing.T) {
+		checker := health.NewChecker(0, nil,
+			health.Check{Name: "database", Critical: true, Check: up},
+			health.Check{Name: "cache", Check: up})
+
+		code, report := readyz(t, checker)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.StatusOK, report.Status)
+		assert.Equal(t, health.StatusUp, report.Components["database"].Status)
+		assert.True(t, report.Components["database"].Critical)
+		assert.Equal(t, health.StatusUp, report.Components["cache"].Status)
+	})
+
+	t.Run("degraded but ready when a non-critical component is down", func(t *testing.T) {
+		checker := health.NewChecker(0, nil,
+			health.Check{Name: "database", Critical: true, Check: up},
+			health.Check{Name: "cache", Check: down})
+
+		code, report := readyz(t, checker)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.StatusDegraded, report.Status)
+		assert.Equal(t, health.StatusDown, report.Components["cache"].Status)
+		assert.Equal(t, "connection refused", report.Components["cache"].Error)
+	})
+
+	t.Run("unavailable when a critical component is down", func(t *testing.T) {
+		checker := health.NewChecker(0, nil,
+			health.Check{Name: "database", Critical: true, Check: down},
+			health.Check{Name: "cache", Check: down})
+
+		code, report := readyz(t, checker)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusUnavailable, report.Status)
+	})
+
+	t.Run("config overrides criticality", func(t *testing.T) {
+		checker := health.NewChecker(0, map[string]bool{"database": false, "cache": true},
+			health.Check{Name: "database", Critical: true, Check: down},
+			health.Check{Name: "cache", Check: up})
+
+		code, report := readyz(t, checker)
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, health.StatusDegraded, report.Status)
+		assert.False(t, report.Components["database"].Critical)
+		assert.True(t, report.Components["cache"].Critical)
+	})
+
+	t.Run("times out slow checks", func(t *testing.T) {
+		checker := health.NewChecker(20*time.Millisecond, nil,
+			health.Check{Name: "broker", Check: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}})
+
+		report := checker.Ready(context.Background())
+
+		assert.Equal(t, health.StatusDown, report.Components["broker"].Status)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Components["broker"].Error)
+	})
+
+	t.Run("not ready once shutting down", func(t *testing.T) {
+		checker := health.NewChecker(0, nil, health.Check{Name: "database", Critical: true, Check: up})
+		checker.ShutDown()
+
+		code, report := readyz(t, checker)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusShuttingDown, report.Status)
+	})
+}
+
+func TestReadiness_Exposure(t *testing.T) {
+	t.Run("hides errors from anonymous callers", func(t *testing.T) {
+		checker := health.NewChecker(0, nil, health.Check{Name: "cache", Check: down})
+
+		_, report := readyzAs(t, checker, false)
+
+		assert.Equal(t, health.StatusDown, report.Components["cache"].Status)
+		assert.Empty(t, report.Components["cache"].Error)
+
+		_, report = readyzAs(t, checker, true)
+		assert.Equal(t, "connection refused", report.Components["cache"].Error)
+	})
+
+	t.Run("answers from a recent report", func(t *testing.T) {
+		var runs atomic.Int32
+		checker := health.NewChecker(0, nil, health.Check{Name: "database", Critical: true,
+			Check: func(context.Context) error {
+				runs.Add(1)
+				return nil
+			}})
+
+		for i := 0; i < 5; i++ {
+			code, _ := readyz(t, checker)
+			assert.Equal(t, http.StatusOK, code)
+		}
+
+		assert.Equal(t, int32(1), runs.Load())
+	})
+
+	t.Run("shutting down shows at once", func(t *testing.T) {
+		checker := health.NewChecker(0, nil, health.Check{Name: "database", Critical: true, Check: up})
+
+		code, _ := readyz(t, checker)
+		require.Equal(t, http.StatusOK, code)
+		checker.ShutDown()
+		code, report := readyz(t, checker)
+
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, health.StatusShuttingDown, report.Status)
+	})
+}
+
+func TestLiveness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/healthz", health.Liveness)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, recorder.Body.String())
+}
@@ -0,0 +1,77 @@
+package common_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"user-service/common/mailer"
+	"user-service/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMailer struct {
+	mu        sync.Mutex
+	sent      []string
+	deadlines []bool
+	release   chan struct{}
+}
+
+func (r *recordingMailer) Send(ctx context.Context, message *mailer.Message) error {
+	if r.release != nil {
+		<-r.release
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := ctx.Deadline()
+	r.sent = append(r.sent, message.Subject)
+	r.deadlines = append(r.deadlines, ok)
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	t.Run("sends every queued mail before closing", func(t *testing.T) {
+		recorder := &recordingMailer{}
+		queue := mailer.NewQueue(recorder, config.Mail{QueueSize: 10, Workers: 2, TimeoutSecond: 5})
+
+		for _, subject := range []string{"one", "two", "three"} {
+			require.NoError(t, queue.Send(context.Background(), &mailer.Message{Subject: subject}))
+		}
+		require.NoError(t, queue.Close(context.Background()))
+
+		assert.ElementsMatch(t, []string{"one", "two", "three"}, recorder.sent)
+		assert.Equal(t, []bool{true, true, true}, recorder.deadlines)
+		assert.ErrorIs(t, queue.Send(context.Background(), &mailer.Message{}), mailer.ErrQueueClosed)
+	})
+
+	t.Run("drops mail when full", func(t *testing.T) {
+		recorder := &recordingMailer{release: make(chan struct{})}
+		queue := mailer.NewQueue(recorder, config.Mail{QueueSize: 1, Workers: 1})
+
+		// the worker holds the first mail, the second fills the queue
+		require.NoError(t, queue.Send(context.Background(), &mailer.Message{Subject: "one"}))
+		require.Eventually(t, func() bool {
+			return queue.Send(context.Background(), &mailer.Message{Subject: "two"}) == nil
+		}, time.Second, time.Millisecond)
+		assert.ErrorIs(t, queue.Send(context.Background(), &mailer.Message{Subject: "three"}), mailer.ErrQueueFull)
+
+		close(recorder.release)
+		require.NoError(t, queue.Close(context.Background()))
+		assert.Equal(t, []string{"one", "two"}, recorder.sent)
+	})
+
+	t.Run("close gives up at the deadline", func(t *testing.T) {
+		recorder := &recordingMailer{release: make(chan struct{})}
+		defer close(recorder.release)
+		queue := mailer.NewQueue(recorder, config.Mail{Workers: 1})
+		require.NoError(t, queue.Send(context.Background(), &mailer.Message{Subject: "stuck"}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, queue.Close(ctx), context.DeadlineExceeded)
+	})
+}
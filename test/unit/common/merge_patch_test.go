@@ -0,0 +1,59 @@
+package common_test
+
+import (
+	"testing"
+	"user-service/common/util"
+	"user-service/domain/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindMergePatch(t *testing.T) {
+	t.Run("only present fields are set", func(t *testing.T) {
+		request := &dto.UpdateRequest{}
+
+		fields, err := util.BindMergePatch([]byte(`{"name":"faisal"}`), request, dto.PatchUserFields)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Name"}, fields)
+		assert.Equal(t, "faisal", *request.Name)
+		assert.Nil(t, request.Username)
+		assert.Nil(t, request.Email)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := util.BindMergePatch([]byte(`{"role":"admin"}`), &dto.UpdateRequest{}, dto.PatchUserFields)
+
+		var patchErr *util.MergePatchError
+		require.ErrorAs(t, err, &patchErr)
+		assert.Equal(t, "role", patchErr.Field)
+	})
+
+	t.Run("field outside the allowed list", func(t *testing.T) {
+		_, err := util.BindMergePatch([]byte(`{"password":"secret"}`), &dto.UpdateRequest{}, dto.PatchUserFields)
+
+		var patchErr *util.MergePatchError
+		require.ErrorAs(t, err, &patchErr)
+		assert.Equal(t, "password", patchErr.Field)
+	})
+
+	t.Run("null removes a required field", func(t *testing.T) {
+		_, err := util.BindMergePatch([]byte(`{"name":null}`), &dto.UpdateRequest{}, dto.PatchUserFields)
+
+		var patchErr *util.MergePatchError
+		require.ErrorAs(t, err, &patchErr)
+		assert.Equal(t, "name", patchErr.Field)
+	})
+
+	t.Run("invalid type", func(t *testing.T) {
+		_, err := util.BindMergePatch([]byte(`{"name":10}`), &dto.UpdateRequest{}, dto.PatchUserFields)
+
+		var patchErr *util.MergePatchError
+		require.ErrorAs(t, err, &patchErr)
+	})
+
+	t.Run("not an object", func(t *testing.T) {
+		_, err := util.BindMergePatch([]byte(`["name"]`), &dto.UpdateRequest{}, dto.PatchUserFields)
+		assert.ErrorIs(t, err, util.ErrInvalidMergePatch)
+	})
+}
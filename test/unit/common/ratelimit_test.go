@@ -0,0 +1,285 @@
+package common_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-service/common/ratelimit"
+	"user-service/config"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rateLimitStores returns a constructor per store, and the Redis server
+// behind the redis one.
+func rateLimitStores(t *testing.T) (map[string]func() ratelimit.IStore, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+
+	return map[string]func() ratelimit.IStore{
+		ratelimit.StoreMemory: func() ratelimit.IStore { return ratelimit.NewMemoryStore() },
+		ratelimit.StoreRedis: func() ratelimit.IStore {
+			store, err := ratelimit.NewRedisStore("redis://" + server.Addr())
+			require.NoError(t, err)
+			t.Cleanup(func() { store.Close() })
+			return store
+		},
+	}, server
+}
+
+func TestRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+
+	stores, server := rateLimitStores(t)
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			key := t.Name()
+
+			count, err := store.Increment(ctx, key+":count", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, int64(1), count)
+			count, err = store.Increment(ctx, key+":count", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, int64(2), count)
+
+			value, err := store.Get(ctx, key+":missing")
+			require.NoError(t, err)
+			assert.Zero(t, value)
+
+			swapped, err := store.CompareAndSwap(ctx, key+":cas", 0, 10, time.Minute)
+			require.NoError(t, err)
+			assert.True(t, swapped)
+
+			swapped, err = store.CompareAndSwap(ctx, key+":cas", 0, 20, time.Minute)
+			require.NoError(t, err)
+			assert.False(t, swapped)
+
+			value, err = store.Get(ctx, key+":cas")
+			require.NoError(t, err)
+			assert.Equal(t, int64(10), value)
+
+			_, err = store.Increment(ctx, key+":short", 10*time.Millisecond)
+			require.NoError(t, err)
+			time.Sleep(20 * time.Millisecond)
+			// miniredis expires keys only when told the time passed
+			server.FastForward(20 * time.Millisecond)
+			value, err = store.Get(ctx, key+":short")
+			require.NoError(t, err)
+			assert.Zero(t, value)
+		})
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	ctx := context.Background()
+
+	stores, _ := rateLimitStores(t)
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			limiter, err := ratelimit.NewLimiter(newStore(), ratelimit.AlgorithmSlidingWindow,
+				ratelimit.Rule{Limit: 3, Window: time.Hour})
+			require.NoError(t, err)
+
+			for remaining := 2; remaining >= 0; remaining-- {
+				result, err := limiter.Allow(ctx, "client")
+				require.NoError(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, 3, result.Limit)
+				assert.Equal(t, remaining, result.Remaining)
+			}
+
+			result, err := limiter.Allow(ctx, "client")
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.Positive(t, result.RetryAfter)
+			assert.LessOrEqual(t, result.RetryAfter, 2*time.Hour)
+
+			result, err = limiter.Allow(ctx, "other")
+			require.NoError(t, err)
+			assert.True(t, result.Allowed)
+		})
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+
+	stores, _ := rateLimitStores(t)
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			limiter, err := ratelimit.NewLimiter(newStore(), ratelimit.AlgorithmTokenBucket,
+				ratelimit.Rule{Limit: 1, Window: time.Minute, Burst: 2})
+			require.NoError(t, err)
+
+			for remaining := 1; remaining >= 0; remaining-- {
+				result, err := limiter.Allow(ctx, "client")
+				require.NoError(t, err)
+				assert.True(t, result.Allowed)
+				assert.Equal(t, 2, result.Limit)
+				assert.Equal(t, remaining, result.Remaining)
+			}
+
+			result, err := limiter.Allow(ctx, "client")
+			require.NoError(t, err)
+			assert.False(t, result.Allowed)
+			assert.InDelta(t, time.Minute.Seconds(), result.RetryAfter.Seconds(), 1)
+		})
+	}
+}
+
+func TestTokenBucket_Refill(t *testing.T) {
+	limiter, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.AlgorithmTokenBucket,
+		ratelimit.Rule{Limit: 1, Window: 50 * time.Millisecond})
+	require.NoError(t, err)
+
+	result, err := limiter.Allow(context.Background(), "client")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = limiter.Allow(context.Background(), "client")
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	time.Sleep(60 * time.Millisecond)
+	result, err = limiter.Allow(context.Background(), "client")
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestRateLimit_SharedAcrossInstances(t *testing.T) {
+	server := miniredis.RunT(t)
+
+	var policies []*ratelimit.Policy
+	for i := 0; i < 3; i++ {
+		store, err := ratelimit.NewStore(config.RateLimit{Store: ratelimit.StoreRedis, URL: "redis://" + server.Addr()})
+		require.NoError(t, err)
+		t.Cleanup(func() { store.Close() })
+
+		policy, err := ratelimit.NewPolicy("default", store, config.RateLimitPolicy{
+			Algorithm:    ratelimit.AlgorithmTokenBucket,
+			Limit:        3,
+			WindowSecond: 60,
+		})
+		require.NoError(t, err)
+		policies = append(policies, policy)
+	}
+
+	allowed := 0
+	for i := 0; i < 9; i++ {
+		result, err := policies[i%3].Allow(context.Background(), ratelimit.Subject{IP: "10.0.0.1"})
+		require.NoError(t, err)
+		if result.Allowed {
+			allowed++
+		}
+	}
+
+	assert.Equal(t, 3, allowed)
+}
+
+func TestPolicy(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+
+	t.Run("keys", func(t *testing.T) {
+		policy, err := ratelimit.NewPolicy("login", store, config.RateLimitPolicy{
+			Limit:        5,
+			WindowSecond: 60,
+			KeyBy:        []string{ratelimit.KeyRoute, ratelimit.KeyUser},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "user-service:ratelimit:login:route=POST /login,user=u-1",
+			policy.Key(ratelimit.Subject{IP: "10.0.0.1", User: "u-1", Route: "POST /login"}))
+		assert.Equal(t, "user-service:ratelimit:login:route=POST /login,ip=10.0.0.1",
+			policy.Key(ratelimit.Subject{IP: "10.0.0.1", Route: "POST /login"}))
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		policy, err := ratelimit.NewPolicy("default", store, config.RateLimitPolicy{Limit: 1, WindowSecond: 60})
+		require.NoError(t, err)
+
+		result, err := policy.Allow(context.Background(), ratelimit.Subject{IP: "10.0.0.2"})
+		require.NoError(t, err)
+		headers := policy.Headers(result)
+		assert.Equal(t, "1", headers["Ratelimit-Limit"])
+		assert.Equal(t, "0", headers["Ratelimit-Remaining"])
+		assert.Equal(t, "1;w=60", headers["Ratelimit-Policy"])
+		assert.NotContains(t, headers, "Retry-After")
+
+		result, err = policy.Allow(context.Background(), ratelimit.Subject{IP: "10.0.0.2"})
+		require.NoError(t, err)
+		assert.Contains(t, policy.Headers(result), "Retry-After")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := ratelimit.NewPolicy("default", store, config.RateLimitPolicy{Limit: 1, WindowSecond: 60, KeyBy: []string{"country"}})
+		assert.Error(t, err)
+
+		_, err = ratelimit.NewPolicy("default", store, config.RateLimitPolicy{Limit: 1, WindowSecond: 60, Algorithm: "leaky"})
+		assert.Error(t, err)
+
+		_, err = ratelimit.NewPolicy("default", store, config.RateLimitPolicy{WindowSecond: 60})
+		assert.Error(t, err)
+	})
+}
+
+func TestPolicy_Exempt(t *testing.T) {
+	policy, err := ratelimit.NewPolicy("login", ratelimit.NewMemoryStore(), config.RateLimitPolicy{
+		Limit:          1,
+		WindowSecond:   60,
+		ExemptServices: []string{"order-service"},
+		ExemptIPs:      []string{"10.1.0.0/16", "127.0.0.1", "::1"},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, policy.Needs(ratelimit.KeyService))
+	assert.False(t, policy.Needs(ratelimit.KeyUser))
+
+	assert.True(t, policy.Exempt(ratelimit.Subject{IP: "8.8.8.8", Service: "order-service"}))
+	assert.True(t, policy.Exempt(ratelimit.Subject{IP: "10.1.2.3"}))
+	assert.True(t, policy.Exempt(ratelimit.Subject{IP: "::ffff:127.0.0.1"}))
+	assert.True(t, policy.Exempt(ratelimit.Subject{IP: "::1"}))
+	assert.False(t, policy.Exempt(ratelimit.Subject{IP: "10.2.0.1", Service: "field-service"}))
+	assert.False(t, policy.Exempt(ratelimit.Subject{IP: "not an ip"}))
+
+	_, err = ratelimit.NewPolicy("login", ratelimit.NewMemoryStore(), config.RateLimitPolicy{
+		Limit:        1,
+		WindowSecond: 60,
+		ExemptIPs:    []string{"10.1.0.0/33"},
+	})
+	assert.Error(t, err)
+}
+
+func TestPolicies_Load(t *testing.T) {
+	policies, err := ratelimit.NewPolicies(ratelimit.NewMemoryStore(), config.RateLimit{
+		Default:  config.RateLimitPolicy{Limit: 10, WindowSecond: 60},
+		Policies: map[string]config.RateLimitPolicy{"login": {Limit: 1, WindowSecond: 60}},
+	})
+	require.NoError(t, err)
+
+	login, ok := policies.Get("login")
+	require.True(t, ok)
+	assert.Equal(t, 1, login.Rule.Limit)
+	_, ok = policies.Get(ratelimit.DefaultPolicy)
+	assert.True(t, ok)
+
+	// an invalid reload keeps what was loaded before
+	err = policies.Load(config.RateLimit{
+		Default:  config.RateLimitPolicy{Limit: 10, WindowSecond: 60},
+		Policies: map[string]config.RateLimitPolicy{"login": {Limit: 2, WindowSecond: 60, KeyBy: []string{"country"}}},
+	})
+	assert.Error(t, err)
+	login, _ = policies.Get("login")
+	assert.Equal(t, 1, login.Rule.Limit)
+
+	err = policies.Load(config.RateLimit{
+		Default:  config.RateLimitPolicy{Limit: 10, WindowSecond: 60},
+		Policies: map[string]config.RateLimitPolicy{ratelimit.DefaultPolicy: {Limit: 2, WindowSecond: 60}},
+	})
+	assert.Error(t, err)
+
+	require.NoError(t, policies.Load(config.RateLimit{Default: config.RateLimitPolicy{Limit: 10, WindowSecond: 60}}))
+	_, ok = policies.Get("login")
+	assert.False(t, ok)
+}
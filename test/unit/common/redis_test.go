@@ -0,0 +1,55 @@
+package common_test
+
+import (
+	"context"
+	"testing"
+	"user-service/common/redis"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisNewClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("bare address", func(t *testing.T) {
+		server := miniredis.RunT(t)
+
+		client, err := redis.NewClient(server.Addr())
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+
+		require.NoError(t, client.Set(ctx, "key", "value", 0).Err())
+		value, err := server.Get("key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+	})
+
+	t.Run("url with password and db", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		server.RequireAuth("secret")
+
+		client, err := redis.NewClient("redis://:secret@" + server.Addr() + "/2")
+		require.NoError(t, err)
+		t.Cleanup(func() { client.Close() })
+
+		require.NoError(t, client.Set(ctx, "key", "value", 0).Err())
+		value, err := server.DB(2).Get("key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		server.RequireAuth("secret")
+
+		_, err := redis.NewClient("redis://:wrong@" + server.Addr())
+		assert.Error(t, err)
+	})
+
+	t.Run("empty address", func(t *testing.T) {
+		_, err := redis.NewClient("")
+		assert.Error(t, err)
+	})
+}
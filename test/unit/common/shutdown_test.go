@@ -0,0 +1,96 @@
+package common_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+	"user-service/common/shutdown"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkers_Stop(t *testing.T) {
+	t.Run("cancels the workers and waits for them", func(t *testing.T) {
+		workers := shutdown.NewWorkers()
+		var finished atomic.Int32
+		for i := 0; i < 3; i++ {
+			workers.Go(func(ctx context.Context) {
+				<-ctx.Done()
+				finished.Add(1)
+			})
+		}
+
+		err := workers.Stop(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), finished.Load())
+	})
+
+	t.Run("gives up at the deadline", func(t *testing.T) {
+		workers := shutdown.NewWorkers()
+		release := make(chan struct{})
+		defer close(release)
+		workers.Go(func(context.Context) { <-release })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := workers.Stop(ctx)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// fakeGRPCServer finishes a graceful stop only once release is closed, and
+// a hard stop releases it.
+type fakeGRPCServer struct {
+	release chan struct{}
+	stopped atomic.Bool
+}
+
+func (s *fakeGRPCServer) GracefulStop() { <-s.release }
+
+func (s *fakeGRPCServer) Stop() {
+	s.stopped.Store(true)
+	close(s.release)
+}
+
+func TestStopGRPC(t *testing.T) {
+	t.Run("lets calls finish", func(t *testing.T) {
+		server := &fakeGRPCServer{release: make(chan struct{})}
+		close(server.release)
+
+		shutdown.StopGRPC(context.Background(), server)
+
+		assert.False(t, server.stopped.Load())
+	})
+
+	t.Run("cuts calls off at the deadline", func(t *testing.T) {
+		server := &fakeGRPCServer{release: make(chan struct{})}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		shutdown.StopGRPC(ctx, server)
+
+		assert.True(t, server.stopped.Load())
+	})
+}
+
+func TestCloseAll(t *testing.T) {
+	var closed []string
+	closer := func(name string, err error) shutdown.Closer {
+		return shutdown.Closer{Name: name, Close: func() error {
+			closed = append(closed, name)
+			return err
+		}}
+	}
+
+	shutdown.CloseAll([]shutdown.Closer{
+		closer("cache", nil),
+		closer("broker", errors.New("connection reset")),
+		closer("database", nil),
+	})
+
+	assert.Equal(t, []string{"cache", "broker", "database"}, closed)
+}
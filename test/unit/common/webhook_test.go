@@ -0,0 +1,86 @@
+package common_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+	"user-service/common/webhook"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"type":"user.registered"}`)
+	signature := webhook.Sign("secret", time.Now(), body)
+
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, webhook.Verify("secret", signature, body, 5*time.Minute))
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		assert.ErrorIs(t, webhook.Verify("other", signature, body, 5*time.Minute), webhook.ErrInvalidSignature)
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		assert.ErrorIs(t, webhook.Verify("secret", signature, []byte(`{}`), 5*time.Minute), webhook.ErrInvalidSignature)
+	})
+
+	t.Run("too old", func(t *testing.T) {
+		old := webhook.Sign("secret", time.Now().Add(-time.Hour), body)
+		assert.ErrorIs(t, webhook.Verify("secret", old, body, 5*time.Minute), webhook.ErrInvalidSignature)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		assert.ErrorIs(t, webhook.Verify("secret", "v1=abc", body, 0), webhook.ErrInvalidSignature)
+	})
+}
+
+func TestAllowedAddr(t *testing.T) {
+	for _, address := range []string{
+		"127.0.0.1", "::1", "10.0.0.8", "172.16.4.1", "192.168.1.1", "169.254.169.254",
+		"fe80::1", "fd00::1", "0.0.0.0", "100.64.0.1", "::ffff:127.0.0.1",
+	} {
+		t.Run(address, func(t *testing.T) {
+			assert.False(t, webhook.AllowedAddr(netip.MustParseAddr(address)))
+		})
+	}
+
+	assert.True(t, webhook.AllowedAddr(netip.MustParseAddr("93.184.216.34")))
+	assert.True(t, webhook.AllowedAddr(netip.MustParseAddr("2606:2800:220:1::248")))
+}
+
+func TestCheckURL(t *testing.T) {
+	t.Run("public address", func(t *testing.T) {
+		assert.NoError(t, webhook.CheckURL(context.Background(), "https://93.184.216.34/hooks"))
+	})
+
+	for _, target := range []string{
+		"http://127.0.0.1:8001/api",
+		"http://[::1]/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"https://10.1.2.3/hooks",
+		"http://localhost/hooks",
+		"ftp://93.184.216.34/hooks",
+		"not a url",
+	} {
+		t.Run(target, func(t *testing.T) {
+			assert.ErrorIs(t, webhook.CheckURL(context.Background(), target), webhook.ErrForbiddenTarget)
+		})
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	_, err := webhook.NewClient(time.Second).Post(server.URL, "application/json", nil)
+
+	assert.ErrorIs(t, err, webhook.ErrForbiddenTarget)
+	assert.False(t, called)
+}
@@ -0,0 +1,83 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-service/constants"
+	controllers "user-service/controllers/user"
+	"user-service/domain/dto"
+	serviceRegistry "user-service/services"
+	services "user-service/services/user"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUserService records the updates that get past validation, every
+// other method is left unimplemented.
+type fakeUserService struct {
+	services.IUserService
+	updates []*dto.UpdateRequest
+}
+
+func (f *fakeUserService) Update(_ context.Context, req *dto.UpdateRequest, uuid string) (*dto.UserResponse, error) {
+	f.updates = append(f.updates, req)
+	return &dto.UserResponse{Version: 2}, nil
+}
+
+type fakeServiceRegistry struct {
+	serviceRegistry.IServiceRegistery
+	user *fakeUserService
+}
+
+func (f *fakeServiceRegistry) GetUser() services.IUserService {
+	return f.user
+}
+
+func TestUserController_Update(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	update := func(method, contentType, body string) (*httptest.ResponseRecorder, *fakeUserService) {
+		fake := &fakeServiceRegistry{user: &fakeUserService{}}
+		controller := controllers.NewUserController(fake)
+		router := gin.New()
+		router.PUT("/users/:uuid", controller.Update)
+		router.PATCH("/users/:uuid", controller.Patch)
+
+		req := httptest.NewRequest(method, "/users/"+uuid.NewString(), strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set(constants.IfMatch, `"1"`)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		return res, fake.user
+	}
+
+	for _, field := range []string{"name", "username", "phoneNumber"} {
+		t.Run("patch with an empty "+field, func(t *testing.T) {
+			res, fake := update(http.MethodPatch, constants.ContentTypeMergePatch, `{"`+field+`":""}`)
+
+			assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+			assert.Empty(t, fake.updates)
+		})
+	}
+
+	t.Run("put with an empty name", func(t *testing.T) {
+		res, fake := update(http.MethodPut, gin.MIMEJSON,
+			`{"name":"","username":"faisalabu","email":"faisal@mail.com","phoneNumber":"0812"}`)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, res.Code)
+		assert.Empty(t, fake.updates)
+	})
+
+	t.Run("patch with a name", func(t *testing.T) {
+		res, fake := update(http.MethodPatch, constants.ContentTypeMergePatch, `{"name":"faisal"}`)
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Len(t, fake.updates, 1)
+	})
+}
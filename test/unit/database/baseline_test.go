@@ -0,0 +1,100 @@
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+	"user-service/database/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// openBaseline returns a connection to a schema of its own on the database
+// TEST_DATABASE_URL names, loaded with the baseline fixture. The schema is
+// dropped when the test ends.
+func openBaseline(t *testing.T) *sql.DB {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	// search_path is set per connection
+	sqlDB.SetMaxOpenConns(1)
+
+	schema := fmt.Sprintf("baseline_%d", time.Now().UnixNano())
+	_, err = sqlDB.Exec(fmt.Sprintf("CREATE SCHEMA %s; SET search_path TO %s", schema, schema))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := sqlDB.Exec(fmt.Sprintf("DROP SCHEMA %s CASCADE", schema))
+		assert.NoError(t, err)
+	})
+
+	fixture, err := os.ReadFile("testdata/baseline_schema.sql")
+	require.NoError(t, err)
+	_, err = sqlDB.Exec(string(fixture))
+	require.NoError(t, err)
+
+	return sqlDB
+}
+
+func TestMigrator_UpFromBaseline(t *testing.T) {
+	sqlDB := openBaseline(t)
+
+	migrator, err := migrations.NewMigrator(sqlDB)
+	require.NoError(t, err)
+
+	// the first release accepted a username longer than the column holds now
+	_, err = migrator.Up(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "users.username has 1 value(s) longer than 20 characters (id 2)")
+
+	pending, err := migrator.Pending(context.Background())
+	require.NoError(t, err)
+	require.NotEmpty(t, pending)
+	assert.Equal(t, "add_user_unique_constraints", pending[0].Name)
+
+	_, err = sqlDB.Exec(`UPDATE users SET username = 'faisal_abu' WHERE id = 2`)
+	require.NoError(t, err)
+
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+
+	pending, err = migrator.Pending(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	var (
+		status    string
+		version   int64
+		deletedAt sql.NullTime
+	)
+	err = sqlDB.QueryRow(`SELECT status, version, deleted_at FROM users WHERE username = 'faisal'`).
+		Scan(&status, &version, &deletedAt)
+	require.NoError(t, err)
+	assert.Equal(t, "active", status)
+	assert.Equal(t, int64(1), version)
+	assert.False(t, deletedAt.Valid)
+
+	columnType := func(column string) string {
+		var dataType string
+		err := sqlDB.QueryRow(`SELECT data_type FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'users' AND column_name = $1`, column).
+			Scan(&dataType)
+		require.NoError(t, err)
+		return dataType
+	}
+	assert.Equal(t, "character varying", columnType("username"))
+	assert.Equal(t, "character varying", columnType("email"))
+}
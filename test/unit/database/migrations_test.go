@@ -0,0 +1,160 @@
+package database_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+	"user-service/database/migrations"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("orders pairs by version", func(t *testing.T) {
+		loaded, err := migrations.Load(fstest.MapFS{
+			"000002_add_index.up.sql":      {Data: []byte("CREATE INDEX")},
+			"000002_add_index.down.sql":    {Data: []byte("DROP INDEX")},
+			"000001_create_users.up.sql":   {Data: []byte("CREATE TABLE")},
+			"000001_create_users.down.sql": {Data: []byte("DROP TABLE")},
+			"README.md":                    {Data: []byte("ignored")},
+		})
+
+		require.NoError(t, err)
+		require.Len(t, loaded, 2)
+		assert.Equal(t, int64(1), loaded[0].Version)
+		assert.Equal(t, "create_users", loaded[0].Name)
+		assert.Equal(t, "DROP INDEX", loaded[1].Down)
+	})
+
+	t.Run("missing down file", func(t *testing.T) {
+		_, err := migrations.Load(fstest.MapFS{
+			"000001_create_users.up.sql": {Data: []byte("CREATE TABLE")},
+		})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("repository migrations are complete", func(t *testing.T) {
+		for i, migration := range repositoryMigrations(t) {
+			assert.Equal(t, int64(i+1), migration.Version, "versions must not have gaps")
+		}
+	})
+}
+
+func repositoryMigrations(t *testing.T) []migrations.Migration {
+	loaded, err := migrations.Load(os.DirFS("../../../database/migrations"))
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+
+	return loaded
+}
+
+func versionsOf(loaded []migrations.Migration) []int64 {
+	versions := make([]int64, 0, len(loaded))
+	for _, migration := range loaded {
+		versions = append(versions, migration.Version)
+	}
+
+	return versions
+}
+
+func expectAppliedVersions(mock sqlmock.Sqlmock, versions ...int64) {
+	mock.ExpectQuery(`SELECT to_regclass\('schema_migrations'\) IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	rows := sqlmock.NewRows([]string{"version", "name", "applied_at"})
+	for _, version := range versions {
+		rows.AddRow(version, "applied", time.Now())
+	}
+	mock.ExpectQuery(`SELECT version, name, applied_at FROM schema_migrations`).WillReturnRows(rows)
+}
+
+func TestMigrator_Up(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	migrator, err := migrations.NewMigrator(db)
+	require.NoError(t, err)
+
+	loaded := repositoryMigrations(t)
+	last := loaded[len(loaded)-1]
+
+	mock.ExpectExec(`SELECT pg_advisory_lock\(hashtext\(\$1\)\)`).
+		WithArgs("user-service.schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS schema_migrations`).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectAppliedVersions(mock, versionsOf(loaded[:len(loaded)-1])...)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(last.Up)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO schema_migrations`).
+		WithArgs(last.Version, last.Name, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(`SELECT pg_advisory_unlock\(hashtext\(\$1\)\)`).
+		WithArgs("user-service.schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, last.Name, applied[0].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Pending(t *testing.T) {
+	t.Run("fresh database", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		migrator, err := migrations.NewMigrator(db)
+		require.NoError(t, err)
+
+		mock.ExpectQuery(`SELECT to_regclass\('schema_migrations'\) IS NOT NULL`).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		pending, err := migrator.Pending(context.Background())
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, pending)
+		assert.Equal(t, int64(1), pending[0].Version)
+	})
+
+	t.Run("up to date", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		migrator, err := migrations.NewMigrator(db)
+		require.NoError(t, err)
+
+		expectAppliedVersions(mock, versionsOf(repositoryMigrations(t))...)
+
+		pending, err := migrator.Pending(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000003_create_users.up.sql"), []byte("CREATE TABLE"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000003_create_users.down.sql"), []byte("DROP TABLE"), 0o644))
+
+	paths, err := migrations.Create(dir, "Add users email index")
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "000004_add_users_email_index.up.sql"),
+		filepath.Join(dir, "000004_add_users_email_index.down.sql"),
+	}, paths)
+}
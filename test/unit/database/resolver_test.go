@@ -0,0 +1,204 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user-service/common/cache"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/database/resolver"
+	"user-service/domain/dto"
+	repositoryRegistry "user-service/repositories"
+	repositories "user-service/repositories/user"
+	services "user-service/services/user"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type resolverFixture struct {
+	db       *gorm.DB
+	primary  sqlmock.Sqlmock
+	replica  sqlmock.Sqlmock
+	resolver resolver.IResolver
+}
+
+func newResolverFixture(t *testing.T, replicaHealthy bool) *resolverFixture {
+	open := func() (*gorm.DB, sqlmock.Sqlmock) {
+		sqlDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		t.Cleanup(func() { sqlDB.Close() })
+
+		db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}),
+			&gorm.Config{DisableAutomaticPing: true})
+		require.NoError(t, err)
+
+		return db, mock
+	}
+
+	primaryDB, primary := open()
+	replicaDB, replica := open()
+
+	ping := replica.ExpectPing()
+	if !replicaHealthy {
+		ping.WillReturnError(errors.New("connection refused"))
+	}
+
+	res, err := resolver.Register(primaryDB, []resolver.Replica{{Name: "replica", DB: replicaDB}}, time.Minute)
+	require.NoError(t, err)
+
+	return &resolverFixture{db: primaryDB, primary: primary, replica: replica, resolver: res}
+}
+
+const selectUserByUUID = `SELECT \* FROM "users" WHERE uuid = \$1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`
+
+func userRows(id string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "uuid", "role_id"}).AddRow(1, id, 1)
+}
+
+func roleRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "USER")
+}
+
+func TestResolver(t *testing.T) {
+	id := uuid.NewString()
+
+	t.Run("reads go to the replica", func(t *testing.T) {
+		fixture := newResolverFixture(t, true)
+
+		fixture.replica.ExpectQuery(selectUserByUUID).WillReturnRows(userRows(id))
+		fixture.replica.ExpectQuery(`SELECT \* FROM "roles"`).WillReturnRows(roleRows())
+
+		_, err := repositories.NewUserRepository(fixture.db).FindByUUID(context.Background(), id)
+
+		require.NoError(t, err)
+		assert.NoError(t, fixture.replica.ExpectationsWereMet())
+		assert.NoError(t, fixture.primary.ExpectationsWereMet())
+	})
+
+	t.Run("forced reads go to the primary", func(t *testing.T) {
+		fixture := newResolverFixture(t, true)
+
+		fixture.primary.ExpectQuery(selectUserByUUID).WillReturnRows(userRows(id))
+		fixture.primary.ExpectQuery(`SELECT \* FROM "roles"`).WillReturnRows(roleRows())
+
+		_, err := repositories.NewUserRepository(fixture.db).FindByUUID(resolver.WithPrimary(context.Background()), id)
+
+		require.NoError(t, err)
+		assert.NoError(t, fixture.primary.ExpectationsWereMet())
+		assert.NoError(t, fixture.replica.ExpectationsWereMet())
+	})
+
+	t.Run("reads after a write stay on the primary", func(t *testing.T) {
+		fixture := newResolverFixture(t, true)
+		name := "faisal"
+
+		fixture.primary.ExpectBegin()
+		fixture.primary.ExpectExec(`UPDATE "users"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		fixture.primary.ExpectCommit()
+		fixture.primary.ExpectQuery(selectUserByUUID).WillReturnRows(userRows(id))
+		fixture.primary.ExpectQuery(`SELECT \* FROM "roles"`).WillReturnRows(roleRows())
+
+		_, err := repositories.NewUserRepository(fixture.db).Update(context.Background(), &dto.UpdateRequest{Name: &name}, id)
+
+		require.NoError(t, err)
+		assert.NoError(t, fixture.primary.ExpectationsWereMet())
+		assert.NoError(t, fixture.replica.ExpectationsWereMet())
+	})
+
+	t.Run("reads in a transaction stay on the primary", func(t *testing.T) {
+		fixture := newResolverFixture(t, true)
+
+		fixture.primary.ExpectBegin()
+		fixture.primary.ExpectQuery(selectUserByUUID).WillReturnRows(userRows(id))
+		fixture.primary.ExpectQuery(`SELECT \* FROM "roles"`).WillReturnRows(roleRows())
+		fixture.primary.ExpectCommit()
+
+		err := fixture.db.Transaction(func(tx *gorm.DB) error {
+			_, err := repositories.NewUserRepository(tx).FindByUUID(context.Background(), id)
+			return err
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, fixture.primary.ExpectationsWereMet())
+	})
+
+	t.Run("unhealthy replica is skipped", func(t *testing.T) {
+		fixture := newResolverFixture(t, false)
+
+		fixture.primary.ExpectQuery(selectUserByUUID).WillReturnRows(userRows(id))
+		fixture.primary.ExpectQuery(`SELECT \* FROM "roles"`).WillReturnRows(roleRows())
+
+		_, err := repositories.NewUserRepository(fixture.db).FindByUUID(context.Background(), id)
+
+		require.NoError(t, err)
+		assert.NoError(t, fixture.primary.ExpectationsWereMet())
+		assert.EqualError(t, fixture.resolver.Err(), "unhealthy replicas: replica")
+
+		// back in rotation once it answers again
+		fixture.replica.ExpectPing()
+		fixture.resolver.CheckHealth(context.Background())
+		assert.NoError(t, fixture.resolver.Err())
+		fixture.replica.ExpectQuery(selectUserByUUID).WillReturnRows(userRows(id))
+		fixture.replica.ExpectQuery(`SELECT \* FROM "roles"`).WillReturnRows(roleRows())
+
+		_, err = repositories.NewUserRepository(fixture.db).FindByUUID(context.Background(), id)
+
+		require.NoError(t, err)
+		assert.NoError(t, fixture.replica.ExpectationsWereMet())
+	})
+	t.Run("closed replicas are out of rotation", func(t *testing.T) {
+		fixture := newResolverFixture(t, true)
+		fixture.replica.ExpectClose()
+
+		require.NoError(t, fixture.resolver.Close())
+
+		fixture.primary.ExpectQuery(selectUserByUUID).WillReturnRows(userRows(id))
+		fixture.primary.ExpectQuery(`SELECT \* FROM "roles"`).WillReturnRows(roleRows())
+
+		_, err := repositories.NewUserRepository(fixture.db).FindByUUID(context.Background(), id)
+
+		require.NoError(t, err)
+		assert.NoError(t, fixture.primary.ExpectationsWereMet())
+		assert.NoError(t, fixture.replica.ExpectationsWereMet())
+	})
+
+	t.Run("version checks read the primary", func(t *testing.T) {
+		fixture := newResolverFixture(t, true)
+		name := "faisal"
+
+		fixture.primary.ExpectQuery(selectUserByUUID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "role_id", "version"}).AddRow(1, id, 1, 3))
+		fixture.primary.ExpectQuery(`SELECT \* FROM "roles"`).WillReturnRows(roleRows())
+
+		ctx := context.WithValue(context.Background(), constants.UserLogin,
+			&dto.UserResponse{UUID: uuid.MustParse(id), Role: "user"})
+		registry := repositoryRegistry.NewRepositoryRegistry(fixture.db, nil)
+		_, err := services.NewUserService(registry, nil).Update(ctx, &dto.UpdateRequest{Name: &name, Version: 2}, id)
+
+		assert.ErrorIs(t, err, errConstant.ErrConflict)
+		assert.NoError(t, fixture.primary.ExpectationsWereMet())
+		assert.NoError(t, fixture.replica.ExpectationsWereMet())
+	})
+
+	t.Run("cache misses are filled from the primary", func(t *testing.T) {
+		fixture := newResolverFixture(t, true)
+
+		fixture.primary.ExpectQuery(selectUserByUUID).WillReturnRows(userRows(id))
+		fixture.primary.ExpectQuery(`SELECT \* FROM "roles"`).WillReturnRows(roleRows())
+
+		userCache := repositories.NewUserCache(cache.NewLRU(10), time.Minute, time.Minute)
+		registry := repositoryRegistry.NewRepositoryRegistry(fixture.db, userCache)
+		_, err := registry.GetUser().FindByUUID(context.Background(), id)
+
+		require.NoError(t, err)
+		assert.NoError(t, fixture.primary.ExpectationsWereMet())
+		assert.NoError(t, fixture.replica.ExpectationsWereMet())
+	})
+}
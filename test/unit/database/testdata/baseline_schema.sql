@@ -0,0 +1,29 @@
+-- The schema AutoMigrate created for the first release, before versioned
+-- migrations, with a user whose username the API would refuse today.
+CREATE TABLE roles (
+    id         bigserial PRIMARY KEY,
+    code       text NOT NULL,
+    name       text NOT NULL,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE users (
+    id           bigserial PRIMARY KEY,
+    uuid         uuid NOT NULL,
+    name         text NOT NULL,
+    username     text NOT NULL,
+    password     text NOT NULL,
+    email        text NOT NULL,
+    phone_number text NOT NULL,
+    role_id      bigint NOT NULL,
+    created_at   timestamptz,
+    updated_at   timestamptz,
+    CONSTRAINT fk_users_role FOREIGN KEY (role_id) REFERENCES roles (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+INSERT INTO roles (code, name, created_at, updated_at) VALUES ('ADMIN', 'Administrator', now(), now());
+
+INSERT INTO users (uuid, name, username, password, email, phone_number, role_id, created_at, updated_at)
+VALUES ('7d0b2c8e-2f6a-4d8e-9a57-1c7f0f4f5b21', 'Faisal', 'faisal', 'hash', 'faisal@mail.com', '08123456789', 1, now(), now()),
+       ('0c3e6a5d-8b41-4f0e-b2f7-93d5a6c1e874', 'Faisal Abu', 'faisal_abu_from_the_first_release', 'hash', 'abu@mail.com', '08123456780', 1, now(), now());
@@ -0,0 +1,106 @@
+package middlewares_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+	"user-service/config"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func apiKey(serviceName, requestAt string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s", serviceName, config.Config.SignatureKey, requestAt)))
+	return hex.EncodeToString(hash[:])
+}
+
+func TestValidateAPIKey(t *testing.T) {
+	config.Config.SignatureKey = "secret"
+	config.Config.APIKeyMaxSkewSecond = 60
+	t.Cleanup(func() {
+		config.Config.SignatureKey = ""
+		config.Config.APIKeyMaxSkewSecond = 0
+	})
+
+	at := func(offset time.Duration) string {
+		return strconv.FormatInt(time.Now().Add(offset).Unix(), 10)
+	}
+
+	t.Run("accepts a fresh key", func(t *testing.T) {
+		requestAt := at(-10 * time.Second)
+
+		err := middlewares.ValidateAPIKey("order-service", requestAt, apiKey("order-service", requestAt))
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("refuses a wrong key", func(t *testing.T) {
+		requestAt := at(0)
+
+		err := middlewares.ValidateAPIKey("order-service", requestAt, apiKey("field-service", requestAt))
+
+		assert.ErrorIs(t, err, errConstant.ErrUnauthorized)
+	})
+
+	t.Run("refuses a replayed key", func(t *testing.T) {
+		requestAt := at(-2 * time.Minute)
+
+		err := middlewares.ValidateAPIKey("order-service", requestAt, apiKey("order-service", requestAt))
+
+		assert.ErrorIs(t, err, errConstant.ErrUnauthorized)
+	})
+
+	t.Run("refuses a key from the future", func(t *testing.T) {
+		requestAt := at(2 * time.Minute)
+
+		err := middlewares.ValidateAPIKey("order-service", requestAt, apiKey("order-service", requestAt))
+
+		assert.ErrorIs(t, err, errConstant.ErrUnauthorized)
+	})
+
+	t.Run("refuses a request time that is not a timestamp", func(t *testing.T) {
+		requestAt := time.Now().Format(time.RFC3339)
+
+		err := middlewares.ValidateAPIKey("order-service", requestAt, apiKey("order-service", requestAt))
+
+		assert.ErrorIs(t, err, errConstant.ErrUnauthorized)
+	})
+}
+
+func TestIsService(t *testing.T) {
+	config.Config.SignatureKey = "secret"
+	t.Cleanup(func() {
+		config.Config.SignatureKey = ""
+		config.Config.InternalServices = nil
+	})
+
+	isService := func(serviceName, key string) bool {
+		requestAt := strconv.FormatInt(time.Now().Unix(), 10)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		c.Request.Header.Set(constants.XServiceName, serviceName)
+		c.Request.Header.Set(constants.XRequestAt, requestAt)
+		if key == "" {
+			key = apiKey(serviceName, requestAt)
+		}
+		c.Request.Header.Set(constants.XApiKey, key)
+		return middlewares.IsService(c)
+	}
+
+	assert.True(t, isService("order-service", ""))
+	assert.False(t, isService("order-service", "forged"))
+	assert.False(t, isService("", ""))
+
+	config.Config.InternalServices = []string{"payment-service"}
+	assert.False(t, isService("order-service", ""))
+	assert.True(t, isService("payment-service", ""))
+}
@@ -0,0 +1,102 @@
+package middlewares_test
+
+import (
+	"context"
+	"testing"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/domain/dto"
+	"user-service/middlewares"
+	serviceRegistry "user-service/services"
+	services "user-service/services/user"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUserService answers the checks the auth middlewares make, every other
+// method is left unimplemented.
+type fakeUserService struct {
+	services.IUserService
+	sessions map[string]error
+	statuses map[string]error
+	users    map[string]*dto.UserResponse
+	checked  []string
+}
+
+func (f *fakeUserService) CheckSession(_ context.Context, sessionID string) error {
+	f.checked = append(f.checked, sessionID)
+	err, ok := f.sessions[sessionID]
+	if !ok {
+		return errConstant.ErrSessionNotFound
+	}
+	return err
+}
+
+func (f *fakeUserService) CheckUserStatus(_ context.Context, uuid string) error {
+	return f.statuses[uuid]
+}
+
+func (f *fakeUserService) GetUserByUUID(_ context.Context, uuid string) (*dto.UserResponse, error) {
+	user, ok := f.users[uuid]
+	if !ok {
+		return nil, errConstant.ErrUserNotFound
+	}
+	return user, nil
+}
+
+type fakeServiceRegistry struct {
+	serviceRegistry.IServiceRegistery
+	user *fakeUserService
+}
+
+func (f *fakeServiceRegistry) GetUser() services.IUserService {
+	return f.user
+}
+
+func newFakeServices() *fakeServiceRegistry {
+	return &fakeServiceRegistry{user: &fakeUserService{
+		sessions: map[string]error{},
+		statuses: map[string]error{},
+		users:    map[string]*dto.UserResponse{},
+	}}
+}
+
+func loginContext(user *dto.UserResponse, sessionID string) context.Context {
+	ctx := context.WithValue(context.Background(), constants.UserLogin, user)
+	return context.WithValue(ctx, constants.SessionID, sessionID)
+}
+
+func TestCheckUserLogin(t *testing.T) {
+	user := &dto.UserResponse{UUID: uuid.New(), Role: constants.UserRole}
+
+	t.Run("live session", func(t *testing.T) {
+		fake := newFakeServices()
+		fake.user.sessions["session"] = nil
+
+		assert.NoError(t, middlewares.CheckUserLogin(loginContext(user, "session"), fake))
+		assert.Equal(t, []string{"session"}, fake.user.checked)
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		fake := newFakeServices()
+		fake.user.sessions["session"] = errConstant.ErrSessionRevoked
+
+		assert.ErrorIs(t, middlewares.CheckUserLogin(loginContext(user, "session"), fake), errConstant.ErrSessionRevoked)
+	})
+
+	t.Run("token from before sessions", func(t *testing.T) {
+		fake := newFakeServices()
+
+		assert.ErrorIs(t, middlewares.CheckUserLogin(loginContext(user, ""), fake), errConstant.ErrSessionNotFound)
+		assert.Equal(t, []string{""}, fake.user.checked)
+	})
+
+	t.Run("inactive user", func(t *testing.T) {
+		fake := newFakeServices()
+		fake.user.sessions["session"] = nil
+		fake.user.statuses[user.UUID.String()] = errConstant.ErrUserBanned
+
+		assert.ErrorIs(t, middlewares.CheckUserLogin(loginContext(user, "session"), fake), errConstant.ErrUserBanned)
+	})
+}
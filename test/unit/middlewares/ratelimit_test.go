@@ -0,0 +1,148 @@
+package middlewares_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+	"user-service/common/ratelimit"
+	"user-service/config"
+	"user-service/constants"
+	"user-service/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRateLimitRouter(t *testing.T, cfg config.RateLimit) (*gin.Engine, *ratelimit.Policies) {
+	gin.SetMode(gin.TestMode)
+
+	policies, err := ratelimit.NewPolicies(ratelimit.NewMemoryStore(), cfg)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(middlewares.RateLimit(policies, ratelimit.DefaultPolicy))
+	router.GET("/users/:uuid", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/login", middlewares.RateLimit(policies, "login"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.POST("/register", middlewares.RateLimit(policies, "register"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	return router, policies
+}
+
+func request(router *gin.Engine, method, path, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = ip + ":1234"
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRateLimit(t *testing.T) {
+	router, _ := newRateLimitRouter(t, config.RateLimit{
+		Default: config.RateLimitPolicy{
+			Limit:        2,
+			WindowSecond: 60,
+			KeyBy:        []string{ratelimit.KeyRoute, ratelimit.KeyIP},
+		},
+	})
+
+	first := request(router, http.MethodGet, "/users/1", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "2", first.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", first.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", first.Header().Get("RateLimit-Policy"))
+
+	// the route is the pattern, so other uuids share the budget
+	assert.Equal(t, http.StatusOK, request(router, http.MethodGet, "/users/2", "10.0.0.1").Code)
+
+	denied := request(router, http.MethodGet, "/users/3", "10.0.0.1")
+	assert.Equal(t, http.StatusTooManyRequests, denied.Code)
+	assert.Equal(t, "0", denied.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, denied.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusOK, request(router, http.MethodGet, "/health", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, request(router, http.MethodGet, "/users/1", "10.0.0.2").Code)
+}
+
+func TestRateLimit_RoutePolicy(t *testing.T) {
+	router, policies := newRateLimitRouter(t, config.RateLimit{
+		Default: config.RateLimitPolicy{Limit: 100, WindowSecond: 60},
+		Policies: map[string]config.RateLimitPolicy{
+			"login": {Limit: 1, WindowSecond: 60, ExemptIPs: []string{"192.168.0.0/16"}},
+		},
+	})
+
+	allowed := request(router, http.MethodPost, "/login", "10.0.0.1")
+	assert.Equal(t, http.StatusOK, allowed.Code)
+	assert.Equal(t, "1;w=60", allowed.Header().Get("RateLimit-Policy"))
+	assert.Equal(t, http.StatusTooManyRequests, request(router, http.MethodPost, "/login", "10.0.0.1").Code)
+
+	// exempt addresses skip the login policy, not the default
+	for i := 0; i < 3; i++ {
+		exempt := request(router, http.MethodPost, "/login", "192.168.1.10")
+		assert.Equal(t, http.StatusOK, exempt.Code)
+		assert.Equal(t, "100;w=60", exempt.Header().Get("RateLimit-Policy"))
+	}
+
+	// a route naming a policy that is not configured keeps the default
+	assert.Equal(t, http.StatusOK, request(router, http.MethodPost, "/register", "10.0.0.1").Code)
+	assert.Equal(t, http.StatusOK, request(router, http.MethodPost, "/register", "10.0.0.1").Code)
+
+	t.Run("reload", func(t *testing.T) {
+		require.NoError(t, policies.Load(config.RateLimit{
+			Default: config.RateLimitPolicy{Limit: 100, WindowSecond: 60},
+			Policies: map[string]config.RateLimitPolicy{
+				"login":    {Limit: 5, WindowSecond: 60},
+				"register": {Limit: 1, WindowSecond: 60},
+			},
+		}))
+
+		assert.Equal(t, "5;w=60", request(router, http.MethodPost, "/login", "10.0.0.3").Header().Get("RateLimit-Policy"))
+		assert.Equal(t, http.StatusOK, request(router, http.MethodPost, "/register", "10.0.0.3").Code)
+		assert.Equal(t, http.StatusTooManyRequests, request(router, http.MethodPost, "/register", "10.0.0.3").Code)
+	})
+}
+
+func TestRateLimit_LogsNoCredentials(t *testing.T) {
+	config.Config.SignatureKey = "secret"
+	var logs bytes.Buffer
+	logrus.SetOutput(&logs)
+	level := logrus.GetLevel()
+	logrus.SetLevel(logrus.DebugLevel)
+	t.Cleanup(func() {
+		config.Config.SignatureKey = ""
+		logrus.SetOutput(os.Stderr)
+		logrus.SetLevel(level)
+	})
+
+	router, _ := newRateLimitRouter(t, config.RateLimit{
+		Default: config.RateLimitPolicy{
+			Limit:        10,
+			WindowSecond: 60,
+			KeyBy:        []string{ratelimit.KeyUser, ratelimit.KeyService, ratelimit.KeyIP},
+		},
+	})
+
+	// a key that matches but is too old is logged, without the key
+	requestAt := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	key := apiKey("order-service", requestAt)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set(constants.Authorization, "Bearer forged.token.value")
+	req.Header.Set(constants.XServiceName, "order-service")
+	req.Header.Set(constants.XRequestAt, requestAt)
+	req.Header.Set(constants.XApiKey, key)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, logs.String(), "order-service")
+	assert.NotContains(t, logs.String(), "forged.token.value")
+	assert.NotContains(t, logs.String(), key)
+}
@@ -0,0 +1,153 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-service/common/auth"
+	"user-service/config"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	controllers "user-service/controllers/user"
+	"user-service/domain/dto"
+	"user-service/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVerifyRouter(fake *fakeServiceRegistry) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/verify", middlewares.ForwardAuth(fake), controllers.NewUserController(fake).Verify)
+
+	return router
+}
+
+func signHS256(t *testing.T, user *dto.UserResponse, sessionID string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
+		User: user,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+
+	signed, err := token.SignedString([]byte(config.Config.JwtSecretKey))
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestVerify(t *testing.T) {
+	config.Config.JwtSecretKey = "secret"
+	t.Cleanup(func() { config.Config.JwtSecretKey = "" })
+
+	user := &dto.UserResponse{UUID: uuid.New(), Role: constants.AdminRole, Email: "faisal@mail.com"}
+
+	// signIn gives the user a live session and a record as stored
+	signIn := func(fake *fakeServiceRegistry, user *dto.UserResponse) string {
+		fake.user.sessions["session"] = nil
+		fake.user.users[user.UUID.String()] = user
+		return signHS256(t, user, "session")
+	}
+
+	verify := func(fake *fakeServiceRegistry, token, requiredRoles string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/verify", nil)
+		if token != "" {
+			req.Header.Set(constants.Authorization, "Bearer "+token)
+		}
+		if requiredRoles != "" {
+			req.Header.Set(constants.XRequiredRoles, requiredRoles)
+		}
+
+		res := httptest.NewRecorder()
+		newVerifyRouter(fake).ServeHTTP(res, req)
+
+		return res
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		fake := newFakeServices()
+		res := verify(fake, signIn(fake, user), "user, admin")
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, user.UUID.String(), res.Header().Get(constants.XUserUUID))
+		assert.Equal(t, user.Role, res.Header().Get(constants.XUserRole))
+		assert.Equal(t, user.Email, res.Header().Get(constants.XUserEmail))
+	})
+
+	t.Run("no required roles", func(t *testing.T) {
+		fake := newFakeServices()
+		res := verify(fake, signIn(fake, user), "")
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, user.UUID.String(), res.Header().Get(constants.XUserUUID))
+	})
+
+	t.Run("identity of the current record", func(t *testing.T) {
+		fake := newFakeServices()
+		token := signIn(fake, user)
+		// demoted and moved to another address after the token was issued
+		fake.user.users[user.UUID.String()] = &dto.UserResponse{UUID: user.UUID, Role: constants.UserRole, Email: "new@mail.com"}
+
+		res := verify(fake, token, "")
+
+		assert.Equal(t, http.StatusOK, res.Code)
+		assert.Equal(t, constants.UserRole, res.Header().Get(constants.XUserRole))
+		assert.Equal(t, "new@mail.com", res.Header().Get(constants.XUserEmail))
+
+		assert.Equal(t, http.StatusForbidden, verify(fake, token, "admin").Code)
+	})
+
+	t.Run("denied a role it does not have", func(t *testing.T) {
+		fake := newFakeServices()
+		member := &dto.UserResponse{UUID: uuid.New(), Role: constants.UserRole}
+
+		res := verify(fake, signIn(fake, member), "admin")
+
+		assert.Equal(t, http.StatusForbidden, res.Code)
+		assert.Empty(t, res.Header().Get(constants.XUserUUID))
+	})
+
+	t.Run("missing role", func(t *testing.T) {
+		fake := newFakeServices()
+		roleless := &dto.UserResponse{UUID: uuid.New()}
+
+		res := verify(fake, signIn(fake, roleless), "admin")
+
+		assert.Equal(t, http.StatusForbidden, res.Code)
+		assert.Empty(t, res.Header().Get(constants.XUserUUID))
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		res := verify(newFakeServices(), "", "admin")
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+		assert.Empty(t, res.Header().Get(constants.XUserUUID))
+	})
+
+	t.Run("inactive user", func(t *testing.T) {
+		fake := newFakeServices()
+		fake.user.sessions["session"] = nil
+		fake.user.statuses[user.UUID.String()] = errConstant.ErrUserBanned
+
+		res := verify(fake, signHS256(t, user, "session"), "admin")
+
+		assert.Equal(t, http.StatusForbidden, res.Code)
+		assert.Empty(t, res.Header().Get(constants.XUserUUID))
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		fake := newFakeServices()
+		fake.user.sessions["session"] = errConstant.ErrSessionRevoked
+
+		res := verify(fake, signHS256(t, user, "session"), "")
+
+		assert.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+}
@@ -0,0 +1,120 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"user-service/common/audit"
+	"user-service/domain/models"
+	repositories "user-service/repositories/audit"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestAuditRepository_Create(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		dialector := postgres.New(postgres.Config{
+			Conn:       sqlDB,
+			DriverName: "postgres",
+		})
+		db, err := gorm.Open(dialector, &gorm.Config{})
+		require.NoError(t, err)
+
+		repo := repositories.NewAuditRepository(db)
+
+		target := uuid.New()
+		log := &models.AuditLog{
+			Event:      "user.registered",
+			TargetUUID: &target,
+			IPAddress:  "127.0.0.1",
+			RequestID:  "request-id",
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).
+			WithArgs("audit_logs:" + target.String()).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT "hash" FROM "audit_logs" WHERE chain = \$1 ORDER BY id DESC LIMIT \$2`).
+			WithArgs(target.String(), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("previous-hash"))
+		mock.ExpectQuery(`INSERT INTO "audit_logs".*RETURNING "id"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		response, err := repo.Create(context.Background(), log)
+		require.NoError(t, err)
+		assert.Equal(t, uint(1), response.ID)
+		assert.NotEqual(t, uuid.Nil, response.UUID)
+		assert.Equal(t, "previous-hash", response.PrevHash)
+		assert.Equal(t, target.String(), response.Chain)
+		assert.Equal(t, audit.ComputeHash("previous-hash", response), response.Hash)
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		dialector := postgres.New(postgres.Config{
+			Conn:       sqlDB,
+			DriverName: "postgres",
+		})
+		db, err := gorm.Open(dialector, &gorm.Config{})
+		require.NoError(t, err)
+
+		repo := repositories.NewAuditRepository(db)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock`).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT "hash" FROM "audit_logs"`).
+			WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+		mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+			WillReturnError(errors.New("database error"))
+		mock.ExpectRollback()
+
+		response, err := repo.Create(context.Background(), &models.AuditLog{Event: "user.registered"})
+		require.Error(t, err)
+		assert.Nil(t, response)
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestAuditRepository_Immutable(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       sqlDB,
+		DriverName: "postgres",
+	})
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	err = db.Model(&models.AuditLog{ID: 1}).Update("event", "tampered").Error
+	require.Error(t, err)
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unfulfilled expectations: %s", err)
+	}
+}
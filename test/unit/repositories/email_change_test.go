@@ -0,0 +1,99 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	errConstant "user-service/constants/error"
+	repositories "user-service/repositories/emailchange"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newEmailChangeRepository(t *testing.T) (repositories.IEmailChangeRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       sqlDB,
+		DriverName: "postgres",
+	})
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return repositories.NewEmailChangeRepository(db), mock
+}
+
+func TestEmailChangeRepository_Confirm(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		repo, mock := newEmailChangeRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "email_changes" SET "confirmed_at"=\$1,"updated_at"=\$2 WHERE id = \$3 AND confirmed_at IS NULL AND cancelled_at IS NULL`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.Confirm(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already resolved", func(t *testing.T) {
+		repo, mock := newEmailChangeRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "email_changes" SET "confirmed_at"=\$1,"updated_at"=\$2 WHERE id = \$3 AND confirmed_at IS NULL AND cancelled_at IS NULL`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.Confirm(context.Background(), 1)
+
+		assert.True(t, errors.Is(err, errConstant.ErrEmailChangeResolved))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEmailChangeRepository_FindByConfirmTokenHash(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		repo, mock := newEmailChangeRepository(t)
+
+		mock.ExpectQuery(`SELECT \* FROM "email_changes" WHERE confirm_token_hash = \$1 ORDER BY "email_changes"."id" LIMIT \$2`).
+			WithArgs("hash", 1).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		change, err := repo.FindByConfirmTokenHash(context.Background(), "hash")
+
+		assert.Nil(t, change)
+		assert.True(t, errors.Is(err, errConstant.ErrEmailChangeNotFound))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEmailChangeRepository_FindByCancelTokenHash(t *testing.T) {
+	t.Run("loads a deleted user", func(t *testing.T) {
+		repo, mock := newEmailChangeRepository(t)
+
+		mock.ExpectQuery(`SELECT \* FROM "email_changes" WHERE cancel_token_hash = \$1 ORDER BY "email_changes"."id" LIMIT \$2`).
+			WithArgs("hash", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 7))
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1$`).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "deleted_at"}).AddRow(7, time.Now()))
+
+		change, err := repo.FindByCancelTokenHash(context.Background(), "hash")
+
+		require.NoError(t, err)
+		assert.Equal(t, uint(7), change.User.ID)
+		assert.True(t, change.User.DeletedAt.Valid)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
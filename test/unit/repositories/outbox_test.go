@@ -0,0 +1,78 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	errConstant "user-service/constants/error"
+	repositories "user-service/repositories/outbox"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newOutboxRepository(t *testing.T) (repositories.IOutboxRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       sqlDB,
+		DriverName: "postgres",
+	})
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return repositories.NewOutboxRepository(db), mock
+}
+
+func TestOutboxRepository_FindPending(t *testing.T) {
+	t.Run("locks due events behind no earlier pending event", func(t *testing.T) {
+		repo, mock := newOutboxRepository(t)
+
+		rows := sqlmock.NewRows([]string{"id", "uuid", "topic", "payload"}).
+			AddRow(1, "1b4e28ba-2fa1-11d2-883f-0016d3cca427", "user.registered", []byte(`{}`))
+		mock.ExpectQuery(`SELECT \* FROM "outbox_events" WHERE \(published_at IS NULL AND next_attempt_at <= \$1\) `+
+			`AND \(NOT EXISTS \(SELECT 1 FROM outbox_events earlier WHERE earlier.aggregate_uuid = outbox_events.aggregate_uuid `+
+			`AND earlier.published_at IS NULL AND earlier.id < outbox_events.id\)\) ORDER BY id LIMIT \$2 FOR UPDATE SKIP LOCKED`).
+			WithArgs(sqlmock.AnyArg(), 10).
+			WillReturnRows(rows)
+
+		events, err := repo.FindPending(context.Background(), 10)
+
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "user.registered", events[0].Topic)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query fails", func(t *testing.T) {
+		repo, mock := newOutboxRepository(t)
+
+		mock.ExpectQuery(`SELECT \* FROM "outbox_events"`).WillReturnError(errors.New("connection reset"))
+
+		_, err := repo.FindPending(context.Background(), 10)
+
+		assert.ErrorIs(t, err, errConstant.ErrSqlError)
+	})
+}
+
+func TestOutboxRepository_MarkFailed(t *testing.T) {
+	repo, mock := newOutboxRepository(t)
+	nextAttemptAt := time.Now().Add(time.Minute)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "outbox_events" SET "attempts"=attempts \+ 1,"last_error"=\$1,"next_attempt_at"=\$2 WHERE id = \$3`).
+		WithArgs("broker down", nextAttemptAt, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.MarkFailed(context.Background(), 1, "broker down", nextAttemptAt)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,125 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user-service/domain/models"
+	"user-service/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newRepositoryRegistry(t *testing.T) (repositories.IRepositoryRegistry, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       sqlDB,
+		DriverName: "postgres",
+	})
+	db, err := gorm.Open(dialector, &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+
+	return repositories.NewRepositoryRegistry(db, nil), mock
+}
+
+func outboxEvent() *models.OutboxEvent {
+	return &models.OutboxEvent{
+		Topic:         "user.registered",
+		AggregateUUID: uuid.New(),
+		Payload:       []byte(`{}`),
+		NextAttemptAt: time.Now(),
+	}
+}
+
+func TestRegistry_WithTransaction(t *testing.T) {
+	t.Run("commits", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "outbox_events"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		err := registry.WithTransaction(context.Background(), func(tx repositories.IRepositoryRegistry) error {
+			return tx.GetOutbox().Create(context.Background(), outboxEvent())
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back on error", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		failure := errors.New("audit failed")
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "outbox_events"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectRollback()
+
+		err := registry.WithTransaction(context.Background(), func(tx repositories.IRepositoryRegistry) error {
+			err := tx.GetOutbox().Create(context.Background(), outboxEvent())
+			if err != nil {
+				return err
+			}
+			return failure
+		})
+
+		assert.ErrorIs(t, err, failure)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back on panic", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		assert.Panics(t, func() {
+			_ = registry.WithTransaction(context.Background(), func(tx repositories.IRepositoryRegistry) error {
+				panic("boom")
+			})
+		})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nests through savepoints", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "outbox_events"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(`SAVEPOINT sp\d+`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`INSERT INTO "outbox_events"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT sp\d+`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := registry.WithTransaction(context.Background(), func(tx repositories.IRepositoryRegistry) error {
+			err := tx.GetOutbox().Create(context.Background(), outboxEvent())
+			if err != nil {
+				return err
+			}
+
+			// the inner failure only undoes the inner write
+			inner := tx.WithTransaction(context.Background(), func(nested repositories.IRepositoryRegistry) error {
+				err := nested.GetOutbox().Create(context.Background(), outboxEvent())
+				if err != nil {
+					return err
+				}
+				return errors.New("optional step failed")
+			})
+			assert.Error(t, inner)
+
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
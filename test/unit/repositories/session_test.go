@@ -0,0 +1,78 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	errConstant "user-service/constants/error"
+	repositories "user-service/repositories/session"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newSessionRepository(t *testing.T) (repositories.ISessionRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       sqlDB,
+		DriverName: "postgres",
+	})
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return repositories.NewSessionRepository(db), mock
+}
+
+func TestSessionRepository_RevokeByUserID(t *testing.T) {
+	t.Run("keeps current session", func(t *testing.T) {
+		repo, mock := newSessionRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "sessions" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE \(user_id = \$3 AND revoked_at IS NULL\) AND uuid <> \$4`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1, "current-session").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := repo.RevokeByUserID(context.Background(), 1, "current-session")
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("revokes all sessions", func(t *testing.T) {
+		repo, mock := newSessionRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "sessions" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE user_id = \$3 AND revoked_at IS NULL`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		err := repo.RevokeByUserID(context.Background(), 1, "")
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSessionRepository_FindByUUID(t *testing.T) {
+	t.Run("not found", func(t *testing.T) {
+		repo, mock := newSessionRepository(t)
+
+		mock.ExpectQuery(`SELECT \* FROM "sessions" WHERE uuid = \$1 ORDER BY "sessions"."id" LIMIT \$2`).
+			WithArgs("missing", 1).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		session, err := repo.FindByUUID(context.Background(), "missing")
+
+		assert.Nil(t, session)
+		assert.True(t, errors.Is(err, errConstant.ErrSessionNotFound))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
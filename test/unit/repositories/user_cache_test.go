@@ -0,0 +1,223 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-service/common/cache"
+	errConstant "user-service/constants/error"
+	"user-service/database/resolver"
+	"user-service/domain/dto"
+	"user-service/repositories"
+	userRepositories "user-service/repositories/user"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const findUserByUUID = `SELECT \* FROM "users" WHERE uuid = \$1`
+
+func newCachedRegistry(t *testing.T) (repositories.IRepositoryRegistry, sqlmock.Sqlmock, *cache.LRU) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       sqlDB,
+		DriverName: "postgres",
+	})
+	db, err := gorm.Open(dialector, &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+
+	lru := cache.NewLRU(100)
+	userCache := userRepositories.NewUserCache(lru, time.Minute, time.Minute)
+
+	return repositories.NewRepositoryRegistry(db, userCache), mock, lru
+}
+
+func expectFindUser(mock sqlmock.Sqlmock, id string, name string) {
+	mock.ExpectQuery(findUserByUUID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "name", "role_id"}).AddRow(1, id, name, 1))
+	mock.ExpectQuery(`SELECT \* FROM "roles"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "USER"))
+}
+
+func TestCachedUserRepository_FindByUUID(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("second lookup is served from the cache", func(t *testing.T) {
+		registry, mock, _ := newCachedRegistry(t)
+		id := uuid.NewString()
+		before := cache.MetricsFor("user").Snapshot()
+
+		expectFindUser(mock, id, "faisal")
+
+		first, err := registry.GetUser().FindByUUID(ctx, id)
+		require.NoError(t, err)
+		second, err := registry.GetUser().FindByUUID(ctx, id)
+		require.NoError(t, err)
+
+		assert.Equal(t, first.UUID, second.UUID)
+		assert.Equal(t, "faisal", second.Name)
+		assert.Equal(t, "USER", second.Role.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		after := cache.MetricsFor("user").Snapshot()
+		assert.Equal(t, uint64(1), after.Misses-before.Misses)
+		assert.Equal(t, uint64(1), after.Hits-before.Hits)
+	})
+
+	t.Run("not found is cached", func(t *testing.T) {
+		registry, mock, _ := newCachedRegistry(t)
+		id := uuid.NewString()
+		before := cache.MetricsFor("user").Snapshot()
+
+		mock.ExpectQuery(findUserByUUID).WillReturnError(gorm.ErrRecordNotFound)
+
+		_, err := registry.GetUser().FindByUUID(ctx, id)
+		assert.ErrorIs(t, err, errConstant.ErrUserNotFound)
+		_, err = registry.GetUser().FindByUUID(ctx, id)
+		assert.ErrorIs(t, err, errConstant.ErrUserNotFound)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, uint64(1), cache.MetricsFor("user").Snapshot().NegativeHits-before.NegativeHits)
+	})
+
+	t.Run("sql errors are not cached", func(t *testing.T) {
+		registry, mock, lru := newCachedRegistry(t)
+
+		mock.ExpectQuery(findUserByUUID).WillReturnError(sqlmock.ErrCancelled)
+
+		_, err := registry.GetUser().FindByUUID(ctx, uuid.NewString())
+
+		assert.ErrorIs(t, err, errConstant.ErrSqlError)
+		assert.Equal(t, 0, lru.Len())
+	})
+
+	t.Run("the password hash is not cached", func(t *testing.T) {
+		registry, mock, lru := newCachedRegistry(t)
+		id := uuid.NewString()
+
+		mock.ExpectQuery(findUserByUUID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "password", "role_id"}).AddRow(1, id, "$2a$10$hash", 1))
+		mock.ExpectQuery(`SELECT \* FROM "roles"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "USER"))
+
+		loaded, err := registry.GetUser().FindByUUID(ctx, id)
+		require.NoError(t, err)
+		cached, err := registry.GetUser().FindByUUID(ctx, id)
+		require.NoError(t, err)
+
+		value, err := lru.Get(ctx, "user-service:user:v2:"+id)
+		require.NoError(t, err)
+		assert.NotContains(t, string(value), "$2a$10$hash")
+		assert.Equal(t, "$2a$10$hash", loaded.Password)
+		assert.Empty(t, cached.Password)
+		assert.Equal(t, loaded.UUID, cached.UUID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("primary reads refresh the entry", func(t *testing.T) {
+		registry, mock, _ := newCachedRegistry(t)
+		id := uuid.NewString()
+
+		expectFindUser(mock, id, "faisal")
+		expectFindUser(mock, id, "abu")
+
+		_, err := registry.GetUser().FindByUUID(ctx, id)
+		require.NoError(t, err)
+		fresh, err := registry.GetUser().FindByUUID(resolver.WithPrimary(ctx), id)
+		require.NoError(t, err)
+		cached, err := registry.GetUser().FindByUUID(ctx, id)
+		require.NoError(t, err)
+
+		assert.Equal(t, "abu", fresh.Name)
+		assert.Equal(t, "abu", cached.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCachedUserRepository_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	name := "abu"
+
+	t.Run("update drops the entry", func(t *testing.T) {
+		registry, mock, _ := newCachedRegistry(t)
+		id := uuid.NewString()
+
+		expectFindUser(mock, id, "faisal")
+		mock.ExpectExec(`UPDATE "users"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectFindUser(mock, id, name)
+		expectFindUser(mock, id, name)
+
+		_, err := registry.GetUser().FindByUUID(ctx, id)
+		require.NoError(t, err)
+		_, err = registry.GetUser().Update(ctx, &dto.UpdateRequest{Name: &name}, id)
+		require.NoError(t, err)
+		user, err := registry.GetUser().FindByUUID(ctx, id)
+		require.NoError(t, err)
+
+		assert.Equal(t, name, user.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("status change drops a negative entry", func(t *testing.T) {
+		registry, mock, _ := newCachedRegistry(t)
+		id := uuid.NewString()
+
+		mock.ExpectQuery(findUserByUUID).WillReturnError(gorm.ErrRecordNotFound)
+		mock.ExpectExec(`UPDATE "users"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectFindUser(mock, id, "faisal")
+		expectFindUser(mock, id, "faisal")
+
+		_, err := registry.GetUser().FindByUUID(ctx, id)
+		require.ErrorIs(t, err, errConstant.ErrUserNotFound)
+		_, err = registry.GetUser().UpdateStatus(ctx, &dto.UpdateStatusRequest{Status: "active"}, id)
+		require.NoError(t, err)
+		_, err = registry.GetUser().FindByUUID(ctx, id)
+		require.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("writes in a transaction bypass the cache and drop the entry after commit", func(t *testing.T) {
+		registry, mock, lru := newCachedRegistry(t)
+		id := uuid.NewString()
+
+		expectFindUser(mock, id, "faisal")
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectFindUser(mock, id, name)
+		expectFindUser(mock, id, name)
+		mock.ExpectCommit()
+
+		_, err := registry.GetUser().FindByUUID(ctx, id)
+		require.NoError(t, err)
+
+		err = registry.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+			_, err := tx.GetUser().Update(ctx, &dto.UpdateRequest{Name: &name}, id)
+			if err != nil {
+				return err
+			}
+
+			// a lookup from another request lands between the write and
+			// the commit and caches the row it can still see
+			require.NoError(t, lru.Set(ctx, "user-service:user:v2:"+id, []byte(`{"name":"faisal"}`), time.Minute))
+
+			user, err := tx.GetUser().FindByUUID(ctx, id)
+			if err != nil {
+				return err
+			}
+			assert.Equal(t, name, user.Name)
+			return nil
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, lru.Len())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"testing"
+	errConstant "user-service/constants/error"
 	"user-service/domain/dto"
 	repositories "user-service/repositories/user"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gorm.io/driver/postgres"
@@ -129,36 +131,45 @@ func TestUserRepository_Update(t *testing.T) {
 
 		repo := repositories.NewUserRepository(db)
 
-		password := "newpassword123"
+		name := "faisalabuupdate"
+		username := "faisalupdate"
+		email := "faisalupdate@mail.com"
+		phoneNumber := "0928318239"
 		req := &dto.UpdateRequest{
-			Username:        "faisalupdate",
-			Name:            "faisalabuupdate",
-			Email:           "faisalupdate@mail.com",
-			Password:        &password,
-			ConfirmPassword: &password,
-			PhoneNumber:     "0928318239",
+			Username:    &username,
+			Name:        &name,
+			Email:       &email,
+			PhoneNumber: &phoneNumber,
+			Version:     3,
 		}
 
 		uuid := uuid.New()
 		mock.ExpectBegin()
-		mock.ExpectExec(`UPDATE "users" SET "name"=\$1,"username"=\$2,"password"=\$3,"phone_number"=\$4,"updated_at"=\$5 WHERE uuid = \$6`).
+		mock.ExpectExec(`UPDATE "users" SET "email"=\$1,"name"=\$2,"phone_number"=\$3,"username"=\$4,"version"=version \+ 1,"updated_at"=\$5 WHERE uuid = \$6 AND version = \$7 AND "users"."deleted_at" IS NULL`).
 			WithArgs(
-				req.Name,
-				req.Username,
-				*req.Password,
-				req.PhoneNumber,
+				email,
+				name,
+				phoneNumber,
+				username,
 				sqlmock.AnyArg(),
 				uuid,
+				3,
 			).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectCommit()
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
+			WithArgs(uuid, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "name", "username", "email", "phone_number", "version"}).
+				AddRow(1, uuid.String(), name, username, email, phoneNumber, 4))
 
 		response, err := repo.Update(context.Background(), req, uuid.String())
 
 		assert.NoError(t, err)
 		assert.NotNil(t, response)
-		assert.Equal(t, req.Name, response.Name)
-		assert.Equal(t, req.Username, response.Username)
+		assert.Equal(t, name, response.Name)
+		assert.Equal(t, username, response.Username)
+		assert.Equal(t, email, response.Email)
+		assert.Equal(t, uint(4), response.Version)
 
 		if err := mock.ExpectationsWereMet(); err != nil {
 			t.Errorf("unfulfilled expectations: %s", err)
@@ -166,6 +177,88 @@ func TestUserRepository_Update(t *testing.T) {
 
 	})
 
+	t.Run("partial update", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		dialector := postgres.New(postgres.Config{
+			Conn:       sqlDB,
+			DriverName: "postgres",
+		})
+
+		db, err := gorm.Open(dialector, &gorm.Config{})
+		require.NoError(t, err)
+
+		repo := repositories.NewUserRepository(db)
+
+		name := "faisalabuupdate"
+		req := &dto.UpdateRequest{
+			Name: &name,
+		}
+
+		uuid := uuid.New()
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users" SET "name"=\$1,"version"=version \+ 1,"updated_at"=\$2 WHERE uuid = \$3 AND "users"."deleted_at" IS NULL`).
+			WithArgs(name, sqlmock.AnyArg(), uuid).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
+			WithArgs(uuid, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "name", "email"}).
+				AddRow(1, uuid.String(), name, "faisal@mail.com"))
+
+		response, err := repo.Update(context.Background(), req, uuid.String())
+
+		assert.NoError(t, err)
+		assert.Equal(t, name, response.Name)
+		assert.Equal(t, "faisal@mail.com", response.Email)
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("version conflict", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		dialector := postgres.New(postgres.Config{
+			Conn:       sqlDB,
+			DriverName: "postgres",
+		})
+
+		db, err := gorm.Open(dialector, &gorm.Config{})
+		require.NoError(t, err)
+
+		repo := repositories.NewUserRepository(db)
+
+		name := "faisalabuupdate"
+		req := &dto.UpdateRequest{
+			Name:    &name,
+			Version: 2,
+		}
+
+		uuid := uuid.New()
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users" SET "name"=\$1,"version"=version \+ 1,"updated_at"=\$2 WHERE uuid = \$3 AND version = \$4`).
+			WithArgs(name, sqlmock.AnyArg(), uuid, 2).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1`).
+			WithArgs(uuid, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "version"}).AddRow(1, uuid.String(), 3))
+
+		response, err := repo.Update(context.Background(), req, uuid.String())
+		assert.ErrorIs(t, err, errConstant.ErrConflict)
+		assert.Nil(t, response)
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %s", err)
+		}
+	})
+
 	t.Run("failed", func(t *testing.T) {
 		sqlDB, mock, err := sqlmock.New()
 		require.NoError(t, err)
@@ -180,25 +273,20 @@ func TestUserRepository_Update(t *testing.T) {
 		require.NoError(t, err)
 
 		repo := repositories.NewUserRepository(db)
-		password := "newpassword123"
+		name := "faisalabuupdate"
+		username := "faisalupdate"
 		req := &dto.UpdateRequest{
-			Username:        "faisalupdate",
-			Name:            "faisalabuupdate",
-			Email:           "faisalupdate@mail.com",
-			Password:        &password,
-			ConfirmPassword: &password,
-			PhoneNumber:     "0928318239",
+			Username: &username,
+			Name:     &name,
 		}
 
 		uuid := uuid.New()
 
 		mock.ExpectBegin()
-		mock.ExpectExec(`UPDATE "users" SET "name"=\$1,"username"=\$2,"password"=\$3,"phone_number"=\$4,"updated_at"=\$5 WHERE uuid = \$6`).
+		mock.ExpectExec(`UPDATE "users" SET "name"=\$1,"username"=\$2,"version"=version \+ 1,"updated_at"=\$3 WHERE uuid = \$4 AND "users"."deleted_at" IS NULL`).
 			WithArgs(
-				req.Name,
-				req.Username,
-				*req.Password,
-				req.PhoneNumber,
+				name,
+				username,
 				sqlmock.AnyArg(),
 				uuid,
 			).WillReturnError(errors.New("database error"))
@@ -232,7 +320,7 @@ func TestUserRepository_FindByEmail(t *testing.T) {
 
 		email := "faisal@mail.com"
 
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE lower\(email\) = lower\(\$1\) AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
 			WithArgs(email, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(1, "faisal", "faisal@mail.com"))
 
@@ -262,7 +350,7 @@ func TestUserRepository_FindByEmail(t *testing.T) {
 
 		email := "faisal@mail.com"
 
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE lower\(email\) = lower\(\$1\) AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
 			WithArgs(email, 1).
 			WillReturnError(errors.New("database error"))
 
@@ -291,7 +379,7 @@ func TestUserRepository_FindByEmail(t *testing.T) {
 
 		email := "faisal@mail.com"
 
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE lower\(email\) = lower\(\$1\) AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
 			WithArgs(email, 1).
 			WillReturnError(errors.New("user not found"))
 
@@ -322,7 +410,7 @@ func TestUserRepository_FindByUsername(t *testing.T) {
 
 		username := "faisalabu"
 
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE username = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE lower\(username\) = lower\(\$1\) AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
 			WithArgs(username, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "username"}).AddRow(1, "faisal", "faisalabu"))
 
@@ -352,7 +440,7 @@ func TestUserRepository_FindByUsername(t *testing.T) {
 
 		username := "faisalabu"
 
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE username = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE lower\(username\) = lower\(\$1\) AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
 			WithArgs(username, 1).
 			WillReturnError(errors.New("database error"))
 
@@ -381,7 +469,7 @@ func TestUserRepository_FindByUsername(t *testing.T) {
 
 		username := "faisalabu"
 
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE username = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE lower\(username\) = lower\(\$1\) AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
 			WithArgs(username, 1).
 			WillReturnError(errors.New("user not found"))
 
@@ -412,7 +500,7 @@ func TestUserRepository_FindByUUID(t *testing.T) {
 
 		uuid := uuid.New()
 
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
 			WithArgs(uuid, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "uuid"}).AddRow(1, "faisal", uuid.String()))
 
@@ -442,7 +530,7 @@ func TestUserRepository_FindByUUID(t *testing.T) {
 
 		uuid := uuid.New()
 
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
 			WithArgs(uuid, 1).
 			WillReturnError(errors.New("database error"))
 
@@ -473,7 +561,7 @@ func TestUserRepository_FindByUUID(t *testing.T) {
 
 		uuid := uuid.New()
 
-		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 ORDER BY "users"."id" LIMIT \$2`).
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT \$2`).
 			WithArgs(uuid, 1).
 			WillReturnError(errors.New("user not found"))
 
@@ -486,3 +574,251 @@ func TestUserRepository_FindByUUID(t *testing.T) {
 		}
 	})
 }
+
+func TestUserRepository_FindByUUIDs(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		dialector := postgres.New(postgres.Config{
+			Conn:       sqlDB,
+			DriverName: "postgres",
+		})
+		db, err := gorm.Open(dialector, &gorm.Config{})
+		require.NoError(t, err)
+
+		repo := repositories.NewUserRepository(db)
+
+		first, second := uuid.New(), uuid.New()
+
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid IN \(\$1,\$2\) AND "users"."deleted_at" IS NULL`).
+			WithArgs(first.String(), second.String()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "uuid"}).AddRow(1, "faisal", first.String()))
+
+		response, err := repo.FindByUUIDs(context.Background(), []string{first.String(), second.String()})
+		require.NoError(t, err)
+		assert.Len(t, response, 1)
+		assert.Equal(t, first, response[0].UUID)
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		dialector := postgres.New(postgres.Config{
+			Conn:       sqlDB,
+			DriverName: "postgres",
+		})
+		db, err := gorm.Open(dialector, &gorm.Config{})
+		require.NoError(t, err)
+
+		repo := repositories.NewUserRepository(db)
+
+		uuid := uuid.New()
+
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid IN \(\$1\) AND "users"."deleted_at" IS NULL`).
+			WithArgs(uuid.String()).
+			WillReturnError(errors.New("database error"))
+
+		response, err := repo.FindByUUIDs(context.Background(), []string{uuid.String()})
+		require.Error(t, err)
+		assert.Nil(t, response)
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestUserRepository_UpdateStatus(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		dialector := postgres.New(postgres.Config{
+			Conn:       sqlDB,
+			DriverName: "postgres",
+		})
+		db, err := gorm.Open(dialector, &gorm.Config{})
+		require.NoError(t, err)
+
+		repo := repositories.NewUserRepository(db)
+
+		uuid := uuid.New()
+		reason := "abusive behaviour"
+		req := &dto.UpdateStatusRequest{
+			Status: "deleted",
+			Reason: &reason,
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users" SET "deleted_at"=\$1,.*"status"=\$2.* WHERE uuid = \$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1 ORDER BY "users"."id" LIMIT \$2`).
+			WithArgs(uuid, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "status", "status_reason", "role_id"}).
+				AddRow(1, uuid.String(), "deleted", reason, 2))
+		mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"."id" = \$1`).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(2, "USER"))
+
+		response, err := repo.UpdateStatus(context.Background(), req, uuid.String())
+		require.NoError(t, err)
+		assert.Equal(t, "deleted", response.Status)
+		assert.Equal(t, reason, *response.StatusReason)
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %s", err)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer sqlDB.Close()
+
+		dialector := postgres.New(postgres.Config{
+			Conn:       sqlDB,
+			DriverName: "postgres",
+		})
+		db, err := gorm.Open(dialector, &gorm.Config{})
+		require.NoError(t, err)
+
+		repo := repositories.NewUserRepository(db)
+
+		uuid := uuid.New()
+		req := &dto.UpdateStatusRequest{
+			Status: "suspended",
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users" SET`).
+			WillReturnError(errors.New("database error"))
+		mock.ExpectRollback()
+
+		response, err := repo.UpdateStatus(context.Background(), req, uuid.String())
+		require.Error(t, err)
+		assert.Nil(t, response)
+
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unfulfilled expectations: %s", err)
+		}
+	})
+}
+
+func TestUserRepository_UniqueViolation(t *testing.T) {
+	newRepo := func(t *testing.T) (repositories.IUserRepository, sqlmock.Sqlmock) {
+		sqlDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { sqlDB.Close() })
+
+		dialector := postgres.New(postgres.Config{
+			Conn:       sqlDB,
+			DriverName: "postgres",
+		})
+		db, err := gorm.Open(dialector, &gorm.Config{})
+		require.NoError(t, err)
+
+		return repositories.NewUserRepository(db), mock
+	}
+
+	t.Run("register with a taken email", func(t *testing.T) {
+		repo, mock := newRepo(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "users"`).
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "idx_users_email_lower"})
+		mock.ExpectRollback()
+
+		_, err := repo.Register(context.Background(), &dto.RegisterRequest{
+			Name:        "faisal",
+			Username:    "faisalabu",
+			Email:       "Faisal@mail.com",
+			PhoneNumber: "082313113",
+			RoleID:      1,
+		})
+
+		assert.ErrorIs(t, err, errConstant.ErrEmailExist)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("update to a taken username", func(t *testing.T) {
+		repo, mock := newRepo(t)
+		username := "FaisalAbu"
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users"`).
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "idx_users_username_lower"})
+		mock.ExpectRollback()
+
+		_, err := repo.Update(context.Background(), &dto.UpdateRequest{Username: &username}, uuid.NewString())
+
+		assert.ErrorIs(t, err, errConstant.ErrUsernameExist)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("restore a user whose email was taken", func(t *testing.T) {
+		repo, mock := newRepo(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users"`).
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "idx_users_email_lower"})
+		mock.ExpectRollback()
+
+		_, err := repo.UpdateStatus(context.Background(), &dto.UpdateStatusRequest{Status: "active"}, uuid.NewString())
+
+		assert.ErrorIs(t, err, errConstant.ErrEmailExist)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("change the role of a user", func(t *testing.T) {
+		repo, mock := newRepo(t)
+
+		mock.ExpectQuery(`SELECT \* FROM "roles" WHERE code = \$1`).
+			WithArgs("ADMIN", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(1, "ADMIN"))
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "users"`).
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "idx_users_username_lower"})
+		mock.ExpectRollback()
+
+		_, err := repo.UpdateRole(context.Background(), "ADMIN", uuid.NewString())
+
+		assert.ErrorIs(t, err, errConstant.ErrUsernameExist)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("register with a taken phone number", func(t *testing.T) {
+		repo, mock := newRepo(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "users"`).
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "idx_users_phone_number"})
+		mock.ExpectRollback()
+
+		_, err := repo.Register(context.Background(), &dto.RegisterRequest{PhoneNumber: "082313113"})
+
+		assert.ErrorIs(t, err, errConstant.ErrPhoneNumberExist)
+	})
+
+	t.Run("other constraint", func(t *testing.T) {
+		repo, mock := newRepo(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "users"`).
+			WillReturnError(&pgconn.PgError{Code: "23503", ConstraintName: "fk_users_role"})
+		mock.ExpectRollback()
+
+		_, err := repo.Register(context.Background(), &dto.RegisterRequest{RoleID: 9})
+
+		assert.ErrorIs(t, err, errConstant.ErrSqlError)
+	})
+}
@@ -0,0 +1,138 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	errConstant "user-service/constants/error"
+	"user-service/domain/models"
+	repositories "user-service/repositories/webhook"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newWebhookRepository(t *testing.T) (repositories.IWebhookRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       sqlDB,
+		DriverName: "postgres",
+	})
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	require.NoError(t, err)
+
+	return repositories.NewWebhookRepository(db), mock
+}
+
+func TestWebhookRepository_FindActiveByEvent(t *testing.T) {
+	repo, mock := newWebhookRepository(t)
+
+	rows := sqlmock.NewRows([]string{"id", "uuid", "url", "events", "active"}).
+		AddRow(1, uuid.New(), "https://pos.example.com/hooks", []byte(`["*"]`), true)
+	mock.ExpectQuery(`SELECT \* FROM "webhook_subscriptions" WHERE \(active AND \(events @> \$1 OR events @> \$2\)\) AND "webhook_subscriptions"."deleted_at" IS NULL ORDER BY id`).
+		WithArgs(`["user.updated"]`, `["*"]`).
+		WillReturnRows(rows)
+
+	subscriptions, err := repo.FindActiveByEvent(context.Background(), "user.updated")
+
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	assert.Equal(t, "https://pos.example.com/hooks", subscriptions[0].URL)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_CreateDeliveries(t *testing.T) {
+	t.Run("ignores existing deliveries", func(t *testing.T) {
+		repo, mock := newWebhookRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "webhook_deliveries" .* ON CONFLICT DO NOTHING RETURNING "id"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		err := repo.CreateDeliveries(context.Background(), []models.WebhookDelivery{{
+			UUID:           uuid.New(),
+			SubscriptionID: 1,
+			EventUUID:      uuid.New(),
+			Event:          "user.updated",
+			Payload:        []byte(`{}`),
+			Status:         "pending",
+		}})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nothing to create", func(t *testing.T) {
+		repo, mock := newWebhookRepository(t)
+
+		err := repo.CreateDeliveries(context.Background(), nil)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookRepository_FindByUUID_NotFound(t *testing.T) {
+	repo, mock := newWebhookRepository(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "webhook_subscriptions" WHERE uuid = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := repo.FindByUUID(context.Background(), uuid.NewString())
+
+	assert.ErrorIs(t, err, errConstant.ErrWebhookNotFound)
+}
+
+func TestWebhookRepository_FindDueDeliveries(t *testing.T) {
+	repo, mock := newWebhookRepository(t)
+
+	mock.ExpectQuery(`SELECT \* FROM "webhook_deliveries" WHERE \(status = \$1 AND next_attempt_at <= \$2\) `+
+		`AND \(leased_until IS NULL OR leased_until <= \$3\) ORDER BY id LIMIT \$4 FOR UPDATE SKIP LOCKED`).
+		WithArgs("pending", sqlmock.AnyArg(), sqlmock.AnyArg(), 20).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	deliveries, err := repo.FindDueDeliveries(context.Background(), 20)
+
+	require.NoError(t, err)
+	assert.Empty(t, deliveries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepository_UpdateLeasedDelivery(t *testing.T) {
+	leaseID := uuid.New()
+
+	t.Run("ends the lease", func(t *testing.T) {
+		repo, mock := newWebhookRepository(t)
+		delivery := &models.WebhookDelivery{ID: 3, Status: "delivered", LeaseID: &leaseID}
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "webhook_deliveries" SET .*"lease_id"=\$\d+,"leased_until"=\$\d+.* WHERE lease_id = \$\d+ AND "id" = \$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := repo.UpdateLeasedDelivery(context.Background(), delivery, leaseID)
+
+		require.NoError(t, err)
+		assert.Nil(t, delivery.LeaseID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("lease taken over", func(t *testing.T) {
+		repo, mock := newWebhookRepository(t)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "webhook_deliveries"`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.UpdateLeasedDelivery(context.Background(), &models.WebhookDelivery{ID: 3}, leaseID)
+
+		assert.ErrorIs(t, err, errConstant.ErrWebhookLeaseLost)
+	})
+}
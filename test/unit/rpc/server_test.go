@@ -0,0 +1,165 @@
+package rpc_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+	"user-service/common/broker"
+	"user-service/common/mailer"
+	"user-service/common/ratelimit"
+	"user-service/config"
+	"user-service/constants"
+	"user-service/proto/pb"
+	"user-service/repositories"
+	"user-service/rpc"
+	"user-service/services"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newConn(t *testing.T, maxRequest int) *grpc.ClientConn {
+	sqlDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB, DriverName: "postgres"}), &gorm.Config{})
+	require.NoError(t, err)
+
+	service := services.NewServiceRegistry(repositories.NewRepositoryRegistry(db, nil), mailer.NewMailer(config.Mail{}), broker.NewMemoryBroker())
+	policies, err := ratelimit.NewPolicies(ratelimit.NewMemoryStore(), config.RateLimit{
+		Default: config.RateLimitPolicy{Limit: maxRequest, WindowSecond: 60},
+		Policies: map[string]config.RateLimitPolicy{
+			"register": {Limit: 1, WindowSecond: 60},
+		},
+	})
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := rpc.NewServer(service, policies)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func TestServer_Health(t *testing.T) {
+	conn := newConn(t, 100)
+
+	response, err := healthpb.NewHealthClient(conn).Check(context.Background(),
+		&healthpb.HealthCheckRequest{Service: pb.UserService_ServiceDesc.ServiceName})
+
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.GetStatus())
+}
+
+func TestServer_Authenticate(t *testing.T) {
+	t.Run("service method without api key", func(t *testing.T) {
+		conn := newConn(t, 100)
+
+		_, err := pb.NewUserServiceClient(conn).BatchGetUsers(context.Background(),
+			&pb.BatchGetUsersRequest{Uuids: []string{"8d6c1bd0-7e4b-4c0a-a3a5-7f3f1a3cfd1e"}})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("service method from a service off the allow list", func(t *testing.T) {
+		config.Config.SignatureKey = "secret"
+		config.Config.InternalServices = []string{"order-service"}
+		t.Cleanup(func() {
+			config.Config.SignatureKey = ""
+			config.Config.InternalServices = nil
+		})
+		conn := newConn(t, 100)
+
+		batchGetUsers := func(serviceName string) error {
+			requestAt := strconv.FormatInt(time.Now().Unix(), 10)
+			hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%s:%s", serviceName, config.Config.SignatureKey, requestAt)))
+			ctx := metadata.AppendToOutgoingContext(context.Background(),
+				constants.XServiceName, serviceName,
+				constants.XRequestAt, requestAt,
+				constants.XApiKey, hex.EncodeToString(hash[:]))
+
+			_, err := pb.NewUserServiceClient(conn).BatchGetUsers(ctx,
+				&pb.BatchGetUsersRequest{Uuids: []string{"8d6c1bd0-7e4b-4c0a-a3a5-7f3f1a3cfd1e"}})
+			return err
+		}
+
+		assert.Equal(t, codes.PermissionDenied, status.Code(batchGetUsers("field-service")))
+
+		code := status.Code(batchGetUsers("order-service"))
+		assert.NotEqual(t, codes.PermissionDenied, code)
+		assert.NotEqual(t, codes.Unauthenticated, code)
+	})
+
+	t.Run("user method without token", func(t *testing.T) {
+		conn := newConn(t, 100)
+
+		_, err := pb.NewUserServiceClient(conn).GetUser(context.Background(),
+			&pb.GetUserRequest{Uuid: "8d6c1bd0-7e4b-4c0a-a3a5-7f3f1a3cfd1e"})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("public method still validates input", func(t *testing.T) {
+		conn := newConn(t, 100)
+
+		_, err := pb.NewUserServiceClient(conn).Login(context.Background(), &pb.LoginRequest{Username: "faisal"})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), "Password is required")
+	})
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	conn := newConn(t, 1)
+	client := pb.NewUserServiceClient(conn)
+
+	var header metadata.MD
+	_, err := client.Login(context.Background(), &pb.LoginRequest{}, grpc.Header(&header))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, []string{"0"}, header.Get("ratelimit-remaining"))
+
+	_, err = client.Login(context.Background(), &pb.LoginRequest{}, grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NotEmpty(t, header.Get("retry-after"))
+}
+
+func TestServer_RateLimitPolicy(t *testing.T) {
+	conn := newConn(t, 100)
+	client := pb.NewUserServiceClient(conn)
+
+	_, err := client.Register(context.Background(), &pb.RegisterRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Register(context.Background(), &pb.RegisterRequest{})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// other methods are only held to the default
+	_, err = client.Login(context.Background(), &pb.LoginRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
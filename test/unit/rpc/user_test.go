@@ -0,0 +1,81 @@
+package rpc_test
+
+import (
+	"context"
+	"testing"
+	"user-service/domain/dto"
+	"user-service/proto/pb"
+	"user-service/rpc"
+	serviceRegistry "user-service/services"
+	services "user-service/services/user"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// introspectingUserService answers Introspect and fails the test on any
+// other lookup.
+type introspectingUserService struct {
+	services.IUserService
+	t      *testing.T
+	result *dto.IntrospectResponse
+}
+
+func (f *introspectingUserService) Introspect(context.Context, *dto.IntrospectRequest) (*dto.IntrospectResponse, error) {
+	return f.result, nil
+}
+
+func (f *introspectingUserService) GetUserByUUID(context.Context, string) (*dto.UserResponse, error) {
+	f.t.Error("the user was looked up again")
+	return nil, nil
+}
+
+type introspectingServices struct {
+	serviceRegistry.IServiceRegistery
+	user services.IUserService
+}
+
+func (f *introspectingServices) GetUser() services.IUserService {
+	return f.user
+}
+
+func TestUserServer_VerifyToken(t *testing.T) {
+	verify := func(result *dto.IntrospectResponse) (*pb.VerifyTokenResponse, error) {
+		server := rpc.NewUserServer(&introspectingServices{
+			user: &introspectingUserService{t: t, result: result},
+		})
+		return server.VerifyToken(context.Background(), &pb.VerifyTokenRequest{Token: "token"})
+	}
+
+	t.Run("answers with the introspected user", func(t *testing.T) {
+		user := &dto.UserResponse{UUID: uuid.New(), Username: "faisal", Email: "faisal@mail.com", Role: "user"}
+		impersonator := uuid.New()
+
+		response, err := verify(&dto.IntrospectResponse{
+			Active:  true,
+			Sub:     user.UUID.String(),
+			Jti:     "session",
+			Exp:     42,
+			Scope:   "profile:read",
+			User:    user,
+			Session: &dto.IntrospectSession{Impersonator: &impersonator},
+		})
+
+		require.NoError(t, err)
+		assert.True(t, response.GetActive())
+		assert.Equal(t, user.UUID.String(), response.GetUser().GetUuid())
+		assert.Equal(t, user.Email, response.GetUser().GetEmail())
+		assert.Equal(t, "session", response.GetSessionId())
+		assert.Equal(t, int64(42), response.GetExpiresAt())
+		assert.Equal(t, impersonator.String(), response.GetImpersonatorUuid())
+	})
+
+	t.Run("inactive token", func(t *testing.T) {
+		response, err := verify(&dto.IntrospectResponse{Active: false})
+
+		require.NoError(t, err)
+		assert.False(t, response.GetActive())
+		assert.Nil(t, response.GetUser())
+	})
+}
@@ -0,0 +1,166 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user-service/common/audit"
+	"user-service/domain/models"
+	"user-service/repositories"
+	auditServices "user-service/services/audit"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newRepositoryRegistry(t *testing.T) (repositories.IRepositoryRegistry, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	dialector := postgres.New(postgres.Config{
+		Conn:       sqlDB,
+		DriverName: "postgres",
+	})
+	db, err := gorm.Open(dialector, &gorm.Config{SkipDefaultTransaction: true})
+	require.NoError(t, err)
+
+	return repositories.NewRepositoryRegistry(db, nil), mock
+}
+
+// expectAuditInsert expects one audit entry written inside a transaction
+// that is already open.
+func expectAuditInsert(mock sqlmock.Sqlmock, event string) {
+	mock.ExpectExec(`SAVEPOINT sp\d+`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT "hash" FROM "audit_logs"`).WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(sqlmock.AnyArg(), event, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
+func TestRecordAdminAction(t *testing.T) {
+	t.Run("writes in the transaction of the change", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		target := uuid.New()
+		ctx := auditServices.WithAdminAction(context.Background(), &target, map[string]interface{}{"method": "DELETE"})
+
+		mock.ExpectBegin()
+		expectAuditInsert(mock, "admin.action")
+		mock.ExpectCommit()
+
+		err := registry.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+			err := auditServices.RecordAdminAction(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			// a second write in the same request is not audited again
+			return auditServices.RecordAdminAction(ctx, tx)
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, auditServices.AdminActionRecorded(ctx))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails the change when the entry fails", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		ctx := auditServices.WithAdminAction(context.Background(), nil, nil)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT sp\d+`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock`).WillReturnError(errors.New("connection reset"))
+		mock.ExpectExec(`ROLLBACK TO SAVEPOINT sp\d+`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := registry.WithTransaction(ctx, func(tx repositories.IRepositoryRegistry) error {
+			return auditServices.RecordAdminAction(ctx, tx)
+		})
+
+		assert.Error(t, err)
+		assert.False(t, auditServices.AdminActionRecorded(ctx))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("does nothing outside an admin request", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+
+		err := auditServices.RecordAdminAction(context.Background(), registry)
+
+		assert.NoError(t, err)
+		assert.False(t, auditServices.AdminActionRecorded(context.Background()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// chainedLogs links logs the way the repository writes them.
+func chainedLogs(logs ...*models.AuditLog) *sqlmock.Rows {
+	heads := map[string]string{}
+	rows := sqlmock.NewRows([]string{"id", "uuid", "event", "target_uuid", "chain", "prev_hash", "hash", "created_at"})
+	for i, log := range logs {
+		createdAt := audit.Timestamp(time.Now())
+		log.ID = uint(i + 1)
+		log.UUID = uuid.New()
+		log.CreatedAt = &createdAt
+		log.Chain = audit.ChainOf(log)
+		log.PrevHash = heads[log.Chain]
+		log.Hash = audit.ComputeHash(log.PrevHash, log)
+		heads[log.Chain] = log.Hash
+
+		rows.AddRow(log.ID, log.UUID, log.Event, log.TargetUUID, log.Chain, log.PrevHash, log.Hash, createdAt)
+	}
+
+	return rows
+}
+
+func TestAuditService_Verify(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+
+	t.Run("interleaved chains", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		mock.ExpectQuery(`SELECT \* FROM "audit_logs"`).WillReturnRows(chainedLogs(
+			&models.AuditLog{Event: "user.registered", TargetUUID: &first},
+			&models.AuditLog{Event: "user.registered", TargetUUID: &second},
+			&models.AuditLog{Event: "auth.login_failed"},
+			&models.AuditLog{Event: "user.profile_updated", TargetUUID: &first},
+		))
+
+		result, err := auditServices.NewAuditService(registry).Verify(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Equal(t, int64(4), result.Checked)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("entry missing from a chain", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		logs := []*models.AuditLog{
+			{Event: "user.registered", TargetUUID: &first},
+			{Event: "user.profile_updated", TargetUUID: &first},
+			{Event: "user.role_changed", TargetUUID: &first},
+		}
+		chainedLogs(logs...)
+
+		rows := sqlmock.NewRows([]string{"id", "uuid", "event", "target_uuid", "chain", "prev_hash", "hash", "created_at"})
+		for _, log := range []*models.AuditLog{logs[0], logs[2]} {
+			rows.AddRow(log.ID, log.UUID, log.Event, log.TargetUUID, log.Chain, log.PrevHash, log.Hash, *log.CreatedAt)
+		}
+		mock.ExpectQuery(`SELECT \* FROM "audit_logs"`).WillReturnRows(rows)
+
+		result, err := auditServices.NewAuditService(registry).Verify(context.Background())
+
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(3), result.BrokenAtID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
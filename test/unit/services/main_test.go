@@ -0,0 +1,58 @@
+package services_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"testing"
+	"user-service/config"
+)
+
+const (
+	testKeyID         = "test-key"
+	testPreviousKeyID = "test-previous-key"
+)
+
+var (
+	testSigningKey  *rsa.PrivateKey
+	testPreviousKey *rsa.PrivateKey
+)
+
+// TestMain configures the signing keys before any service loads them.
+func TestMain(m *testing.M) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	testPreviousKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	testSigningKey = key
+	config.Config.JwtPrivateKey = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+	config.Config.JwtKeyID = testKeyID
+	config.Config.JwtPreviousPublicKey = string(pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: must(x509.MarshalPKIXPublicKey(&testPreviousKey.PublicKey)),
+	}))
+	config.Config.JwtPreviousKeyID = testPreviousKeyID
+	config.Config.JwtSecretKey = "secret"
+	config.Config.JwtExpirationTime = 60
+
+	os.Exit(m.Run())
+}
+
+func must(data []byte, err error) []byte {
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
@@ -0,0 +1,59 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"user-service/common/broker"
+	services "user-service/services/outbox"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cancellingBroker accepts a message and then cancels the relay, like a
+// shutdown arriving while a batch is published.
+type cancellingBroker struct {
+	cancel    context.CancelFunc
+	published []string
+}
+
+func (b *cancellingBroker) Publish(_ context.Context, message *broker.Message) error {
+	b.published = append(b.published, message.ID)
+	b.cancel()
+	return nil
+}
+
+func (b *cancellingBroker) Close() error {
+	return nil
+}
+
+func TestOutboxService_PublishPending(t *testing.T) {
+	t.Run("stops at shutdown and keeps what was published", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		eventBroker := &cancellingBroker{cancel: cancel}
+		first, second := uuid.New(), uuid.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "outbox_events" WHERE .* FOR UPDATE SKIP LOCKED`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "topic", "aggregate_uuid", "payload", "next_attempt_at"}).
+				AddRow(1, first, "user.updated", uuid.New(), []byte(`{}`), time.Now()).
+				AddRow(2, second, "user.updated", uuid.New(), []byte(`{}`), time.Now()))
+		mock.ExpectQuery(`SELECT \* FROM "webhook_subscriptions" WHERE \(active`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec(`UPDATE "outbox_events" SET .* WHERE id = \$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		published, err := services.NewOutboxService(registry, eventBroker).PublishPending(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, published)
+		assert.Equal(t, []string{first.String()}, eventBroker.published)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
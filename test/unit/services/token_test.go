@@ -0,0 +1,65 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+	"user-service/common/auth"
+	"user-service/domain/dto"
+	services "user-service/services/user"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClaims() *auth.Claims {
+	return &auth.Claims{
+		User: &dto.UserResponse{UUID: uuid.New()},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+}
+
+func TestUserService_JWKS(t *testing.T) {
+	jwks := services.NewUserService(nil, nil).JWKS()
+
+	kids := make([]string, 0, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		kids = append(kids, jwk.Kid)
+	}
+	assert.Equal(t, []string{testKeyID, testPreviousKeyID}, kids)
+}
+
+func TestKeyfunc(t *testing.T) {
+	parse := func(method jwt.SigningMethod, kid string, key interface{}) error {
+		token := jwt.NewWithClaims(method, newClaims())
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+
+		_, err = jwt.ParseWithClaims(signed, &auth.Claims{}, services.Keyfunc)
+		return err
+	}
+
+	t.Run("accepts the current key", func(t *testing.T) {
+		assert.NoError(t, parse(jwt.SigningMethodRS256, testKeyID, testSigningKey))
+	})
+
+	t.Run("accepts the previous key", func(t *testing.T) {
+		assert.NoError(t, parse(jwt.SigningMethodRS256, testPreviousKeyID, testPreviousKey))
+	})
+
+	t.Run("refuses a key under another key id", func(t *testing.T) {
+		assert.Error(t, parse(jwt.SigningMethodRS256, testKeyID, testPreviousKey))
+	})
+
+	t.Run("refuses an unknown key id", func(t *testing.T) {
+		assert.Error(t, parse(jwt.SigningMethodRS256, "unknown", testSigningKey))
+	})
+
+	t.Run("refuses hs256 once an rsa key is configured", func(t *testing.T) {
+		assert.Error(t, parse(jwt.SigningMethodHS256, "", []byte("secret")))
+	})
+}
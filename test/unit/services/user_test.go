@@ -0,0 +1,434 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"user-service/common/auth"
+	"user-service/common/mailer"
+	"user-service/common/util"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/domain/dto"
+	"user-service/domain/models"
+	services "user-service/services/user"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newUser(role, status string) *models.User {
+	return &models.User{
+		ID:       1,
+		UUID:     uuid.New(),
+		Name:     "faisal",
+		Username: "faisalabu",
+		Email:    "faisal@mail.com",
+		Status:   status,
+		Role:     models.Role{Code: role},
+	}
+}
+
+func userLogin(user *models.User) *dto.UserResponse {
+	return &dto.UserResponse{UUID: user.UUID, Username: user.Username, Role: user.Role.Code}
+}
+
+// expectFindUser expects the lookup of user by uuid with its role.
+func expectFindUser(mock sqlmock.Sqlmock, user *models.User) {
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE uuid = \$1`).
+		WithArgs(user.UUID.String(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "name", "username", "email", "status", "role_id", "version"}).
+			AddRow(user.ID, user.UUID, user.Name, user.Username, user.Email, user.Status, 2, user.Version))
+	mock.ExpectQuery(`SELECT \* FROM "roles" WHERE "roles"."id" = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(2, user.Role.Code))
+}
+
+func expectFindSession(mock sqlmock.Sqlmock, session *models.Session) {
+	mock.ExpectQuery(`SELECT \* FROM "sessions" WHERE uuid = \$1`).
+		WithArgs(session.UUID.String(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "user_id", "impersonator_uuid", "expires_at", "revoked_at"}).
+			AddRow(1, session.UUID, session.UserID, session.ImpersonatorUUID, session.ExpiresAt, session.RevokedAt))
+}
+
+func signToken(t *testing.T, claims *auth.Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKeyID
+
+	signed, err := token.SignedString(testSigningKey)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func TestUserService_Impersonate(t *testing.T) {
+	admin := newUser(constants.AdminRole, constants.UserStatusActive)
+
+	t.Run("starts an impersonation", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		target := newUser(constants.UserRole, constants.UserStatusActive)
+		ctx := context.WithValue(context.Background(), constants.UserLogin, userLogin(admin))
+
+		expectFindUser(mock, target)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "sessions"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		expectAuditInsert(mock, constants.AuditEventImpersonation)
+		mock.ExpectCommit()
+
+		result, err := services.NewUserService(registry, nil).Impersonate(ctx, target.UUID.String())
+
+		require.NoError(t, err)
+		assert.Equal(t, target.UUID, result.User.UUID)
+		assert.Equal(t, admin.UUID, result.Impersonator.UUID)
+
+		claims := &auth.Claims{}
+		_, err = jwt.ParseWithClaims(result.Token, claims, services.Keyfunc)
+		require.NoError(t, err)
+		assert.Equal(t, target.UUID, claims.User.UUID)
+		assert.Equal(t, admin.UUID, claims.Impersonator.UUID)
+		assert.NotEmpty(t, claims.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses to nest", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		ctx := context.WithValue(context.Background(), constants.UserLogin, userLogin(admin))
+		ctx = context.WithValue(ctx, constants.Impersonator, userLogin(newUser(constants.AdminRole, constants.UserStatusActive)))
+
+		result, err := services.NewUserService(registry, nil).Impersonate(ctx, uuid.NewString())
+
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, errConstant.ErrImpersonationNotAllowed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses a non admin", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		caller := newUser(constants.UserRole, constants.UserStatusActive)
+		ctx := context.WithValue(context.Background(), constants.UserLogin, userLogin(caller))
+
+		result, err := services.NewUserService(registry, nil).Impersonate(ctx, uuid.NewString())
+
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, errConstant.ErrForbiden)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses another admin without the permission", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		target := newUser(constants.AdminRole, constants.UserStatusActive)
+		ctx := context.WithValue(context.Background(), constants.UserLogin, userLogin(admin))
+
+		expectFindUser(mock, target)
+
+		result, err := services.NewUserService(registry, nil).Impersonate(ctx, target.UUID.String())
+
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, errConstant.ErrImpersonateAdmin)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserService_StopImpersonation(t *testing.T) {
+	admin := newUser(constants.AdminRole, constants.UserStatusActive)
+	target := newUser(constants.UserRole, constants.UserStatusActive)
+
+	t.Run("revokes the impersonation session", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		sessionID := uuid.NewString()
+		ctx := context.WithValue(context.Background(), constants.UserLogin, userLogin(target))
+		ctx = context.WithValue(ctx, constants.Impersonator, userLogin(admin))
+		ctx = context.WithValue(ctx, constants.SessionID, sessionID)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "sessions" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE uuid = \$3 AND revoked_at IS NULL`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sessionID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAuditInsert(mock, constants.AuditEventImpersonationStop)
+		mock.ExpectCommit()
+
+		err := services.NewUserService(registry, nil).StopImpersonation(ctx)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses a regular token", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		ctx := context.WithValue(context.Background(), constants.UserLogin, userLogin(target))
+
+		err := services.NewUserService(registry, nil).StopImpersonation(ctx)
+
+		assert.ErrorIs(t, err, errConstant.ErrNotImpersonating)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserService_UpdateRole(t *testing.T) {
+	t.Run("signs the demoted user out", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		admin := newUser(constants.AdminRole, constants.UserStatusActive)
+		target := newUser(constants.AdminRole, constants.UserStatusActive)
+		target.ID = 2
+		demoted := *target
+		demoted.Role = models.Role{Code: constants.UserRole}
+		session := &models.Session{UUID: uuid.New(), UserID: target.ID, ExpiresAt: time.Now().Add(time.Hour)}
+		ctx := context.WithValue(context.Background(), constants.UserLogin, userLogin(admin))
+
+		expectFindUser(mock, target)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT \* FROM "roles" WHERE code = \$1`).
+			WithArgs("USER", 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(2, "USER"))
+		mock.ExpectExec(`UPDATE "users"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectFindUser(mock, &demoted)
+		mock.ExpectExec(`UPDATE "sessions" SET "revoked_at"=\$1,"updated_at"=\$2 WHERE user_id = \$3 AND revoked_at IS NULL`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), target.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAuditInsert(mock, constants.AuditEventRoleChange)
+		mock.ExpectQuery(`INSERT INTO "outbox_events"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		service := services.NewUserService(registry, nil)
+		result, err := service.UpdateRole(ctx, &dto.UpdateRoleRequest{Role: "user"}, target.UUID.String())
+
+		require.NoError(t, err)
+		assert.Equal(t, "user", result.Role)
+
+		// the token issued while they were an admin is refused from now on
+		revokedAt := time.Now()
+		session.RevokedAt = &revokedAt
+		expectFindSession(mock, session)
+
+		assert.ErrorIs(t, service.CheckSession(context.Background(), session.UUID.String()), errConstant.ErrSessionRevoked)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUserService_Introspect(t *testing.T) {
+	newClaims := func(user, impersonator *models.User, session *models.Session) *auth.Claims {
+		claims := &auth.Claims{
+			User: userLogin(user),
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        session.UUID.String(),
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
+			},
+		}
+		if impersonator != nil {
+			claims.Impersonator = userLogin(impersonator)
+		}
+
+		return claims
+	}
+	newSession := func(user *models.User) *models.Session {
+		return &models.Session{UUID: uuid.New(), UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	}
+
+	t.Run("active", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		user := newUser(constants.UserRole, constants.UserStatusActive)
+		session := newSession(user)
+
+		expectFindSession(mock, session)
+		expectFindUser(mock, user)
+
+		result, err := services.NewUserService(registry, nil).Introspect(context.Background(),
+			&dto.IntrospectRequest{Token: signToken(t, newClaims(user, nil, session))})
+
+		require.NoError(t, err)
+		assert.True(t, result.Active)
+		assert.Equal(t, user.UUID.String(), result.Sub)
+		assert.Equal(t, session.UUID.String(), result.Jti)
+		assert.Equal(t, constants.UserRole, result.Role)
+		assert.Equal(t, "Bearer", result.TokenType)
+		assert.Contains(t, result.Scope, constants.ScopeProfileRead)
+		assert.Equal(t, session.UUID, result.Session.ID)
+		assert.Equal(t, user.UUID, result.User.UUID)
+		assert.Equal(t, user.Email, result.User.Email)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("revoked session", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		user := newUser(constants.UserRole, constants.UserStatusActive)
+		session := newSession(user)
+		revokedAt := time.Now().Add(-time.Minute)
+		session.RevokedAt = &revokedAt
+
+		expectFindSession(mock, session)
+
+		result, err := services.NewUserService(registry, nil).Introspect(context.Background(),
+			&dto.IntrospectRequest{Token: signToken(t, newClaims(user, nil, session))})
+
+		require.NoError(t, err)
+		assert.Equal(t, &dto.IntrospectResponse{Active: false}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("inactive user", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		user := newUser(constants.UserRole, constants.UserStatusSuspended)
+		session := newSession(user)
+
+		expectFindSession(mock, session)
+		expectFindUser(mock, user)
+
+		result, err := services.NewUserService(registry, nil).Introspect(context.Background(),
+			&dto.IntrospectRequest{Token: signToken(t, newClaims(user, nil, session))})
+
+		require.NoError(t, err)
+		assert.False(t, result.Active)
+		assert.Empty(t, result.Sub)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("inactive impersonator", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		user := newUser(constants.UserRole, constants.UserStatusActive)
+		admin := newUser(constants.AdminRole, constants.UserStatusBanned)
+		session := newSession(user)
+		session.ImpersonatorUUID = &admin.UUID
+
+		expectFindSession(mock, session)
+		expectFindUser(mock, user)
+		expectFindUser(mock, admin)
+
+		result, err := services.NewUserService(registry, nil).Introspect(context.Background(),
+			&dto.IntrospectRequest{Token: signToken(t, newClaims(user, admin, session))})
+
+		require.NoError(t, err)
+		assert.False(t, result.Active)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("token from before sessions", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		user := newUser(constants.UserRole, constants.UserStatusActive)
+		claims := newClaims(user, nil, newSession(user))
+		claims.ID = ""
+
+		result, err := services.NewUserService(registry, nil).Introspect(context.Background(),
+			&dto.IntrospectRequest{Token: signToken(t, claims)})
+
+		require.NoError(t, err)
+		assert.False(t, result.Active)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+
+		result, err := services.NewUserService(registry, nil).Introspect(context.Background(),
+			&dto.IntrospectRequest{Token: "not-a-token"})
+
+		require.NoError(t, err)
+		assert.False(t, result.Active)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database failure", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		user := newUser(constants.UserRole, constants.UserStatusActive)
+		session := newSession(user)
+
+		mock.ExpectQuery(`SELECT \* FROM "sessions"`).WillReturnError(errors.New("connection reset"))
+
+		result, err := services.NewUserService(registry, nil).Introspect(context.Background(),
+			&dto.IntrospectRequest{Token: signToken(t, newClaims(user, nil, session))})
+
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, errConstant.ErrSqlError)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+type fakeMailer struct {
+	sent []*mailer.Message
+}
+
+func (m *fakeMailer) Send(_ context.Context, message *mailer.Message) error {
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+// expectFindEmailChange expects the lookup of a confirmed change by its
+// cancel token together with its user, deleted or not.
+func expectFindEmailChange(mock sqlmock.Sqlmock, user *models.User, deletedAt *time.Time) {
+	mock.ExpectQuery(`SELECT \* FROM "email_changes" WHERE cancel_token_hash = \$1`).
+		WithArgs(util.HashToken("token"), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "user_id", "old_email", "new_email", "expires_at", "confirmed_at"}).
+			AddRow(1, uuid.New(), user.ID, "old@mail.com", "new@mail.com", time.Now(), time.Now().Add(-time.Hour)))
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE "users"."id" = \$1$`).
+		WithArgs(user.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "name", "email", "version", "deleted_at"}).
+			AddRow(user.ID, user.UUID, user.Name, "new@mail.com", 2, deletedAt))
+}
+
+func TestUserService_CancelEmailChange(t *testing.T) {
+	req := &dto.EmailChangeTokenRequest{Token: "token"}
+
+	t.Run("reverts a confirmed change", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		user := newUser(constants.UserRole, constants.UserStatusActive)
+		user.Email = "new@mail.com"
+		user.Version = 2
+		mails := &fakeMailer{}
+
+		expectFindEmailChange(mock, user, nil)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "email_changes" SET "cancelled_at"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectFindUser(mock, user)
+		mock.ExpectExec(`UPDATE "users" SET .* WHERE uuid = \$\d+ AND version = \$\d+`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		user.Email = "old@mail.com"
+		expectFindUser(mock, user)
+		mock.ExpectQuery(`INSERT INTO "outbox_events"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(`UPDATE "sessions" SET "revoked_at"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAuditInsert(mock, constants.AuditEventEmailChangeCancel)
+		mock.ExpectCommit()
+
+		err := services.NewUserService(registry, mails).CancelEmailChange(context.Background(), req)
+
+		require.NoError(t, err)
+		require.Len(t, mails.sent, 1)
+		assert.Equal(t, []string{"new@mail.com"}, mails.sent[0].To)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("leaves a later change alone", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		user := newUser(constants.UserRole, constants.UserStatusActive)
+		user.Email = "later@mail.com"
+		mails := &fakeMailer{}
+
+		expectFindEmailChange(mock, user, nil)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "email_changes" SET "cancelled_at"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectFindUser(mock, user)
+		expectAuditInsert(mock, constants.AuditEventEmailChangeCancel)
+		mock.ExpectCommit()
+
+		err := services.NewUserService(registry, mails).CancelEmailChange(context.Background(), req)
+
+		require.NoError(t, err)
+		assert.Empty(t, mails.sent)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses a deleted user", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		user := newUser(constants.UserRole, constants.UserStatusDeleted)
+		deletedAt := time.Now()
+
+		expectFindEmailChange(mock, user, &deletedAt)
+
+		err := services.NewUserService(registry, nil).CancelEmailChange(context.Background(), req)
+
+		assert.ErrorIs(t, err, errConstant.ErrUserNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
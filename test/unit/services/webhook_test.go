@@ -0,0 +1,104 @@
+package services_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-service/config"
+	"user-service/constants"
+	errConstant "user-service/constants/error"
+	"user-service/domain/dto"
+	services "user-service/services/webhook"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectLeaseDelivery expects a batch of one due delivery to url to be
+// leased in a transaction of its own.
+func expectLeaseDelivery(mock sqlmock.Sqlmock, url string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "webhook_deliveries" WHERE .* FOR UPDATE SKIP LOCKED`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "subscription_id", "event", "payload", "status", "next_attempt_at"}).
+			AddRow(1, uuid.New(), 1, constants.EventUserUpdated, []byte(`{}`), constants.WebhookDeliveryPending, time.Now()))
+	mock.ExpectQuery(`SELECT \* FROM "webhook_subscriptions" WHERE "webhook_subscriptions"."id" = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uuid", "url", "secret", "active"}).
+			AddRow(1, uuid.New(), url, "secret", true))
+	mock.ExpectExec(`UPDATE "webhook_deliveries" SET "lease_id"=\$1,"leased_until"=\$2,"updated_at"=\$3 WHERE id IN \(\$4\)`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestWebhookService_DeliverPending(t *testing.T) {
+	config.Config.Webhook.AllowPrivateTargets = true
+	t.Cleanup(func() { config.Config.Webhook.AllowPrivateTargets = false })
+
+	t.Run("sends outside the transactions", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.NotEmpty(t, r.Header.Get(constants.XWebhookSignature))
+		}))
+		defer server.Close()
+
+		expectLeaseDelivery(mock, server.URL)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "webhook_deliveries" SET "status"=\$1,"attempts"=\$2,.* WHERE lease_id = \$\d+ AND "id" = \$\d+`).
+			WithArgs(constants.WebhookDeliveryDelivered, 1, 200, nil, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil,
+				sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		delivered, err := services.NewWebhookService(registry).DeliverPending(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, delivered)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("hands back a delivery cut off by shutdown", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cancel()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		defer server.Close()
+
+		expectLeaseDelivery(mock, server.URL)
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "webhook_deliveries" SET "status"=\$1,"attempts"=\$2,.* WHERE lease_id = \$\d+ AND "id" = \$\d+`).
+			WithArgs(constants.WebhookDeliveryPending, 0, nil, nil, sqlmock.AnyArg(), nil, nil, nil,
+				sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		delivered, err := services.NewWebhookService(registry).DeliverPending(ctx)
+
+		require.NoError(t, err)
+		assert.Zero(t, delivered)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWebhookService_Create(t *testing.T) {
+	t.Run("refuses an internal address", func(t *testing.T) {
+		registry, mock := newRepositoryRegistry(t)
+
+		result, err := services.NewWebhookService(registry).Create(context.Background(), &dto.WebhookRequest{
+			URL:    "http://169.254.169.254/latest/meta-data",
+			Events: []string{"*"},
+		})
+
+		assert.Nil(t, result)
+		assert.ErrorIs(t, err, errConstant.ErrWebhookURLNotAllowed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}